@@ -0,0 +1,139 @@
+package captcha
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	// 图片尺寸
+	Width  = 120
+	Height = 40
+	// 数字字形为 5x7 的点阵, 每个点放大为 scale x scale 像素
+	glyphWidth  = 5
+	glyphHeight = 7
+	scale       = 3
+	// 干扰点和干扰线的数量
+	noiseDots  = 120
+	noiseLines = 3
+)
+
+// digits 0-9 的 5x7 点阵字形, 每行 5 位, 1 表示需要绘制
+var digits = [10][glyphHeight]uint8{
+	{0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	{0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	{0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	{0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	{0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	{0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	{0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	{0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	{0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	{0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+}
+
+// RandomDigits 生成 n 位随机数字作为验证码的答案
+func RandomDigits(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte('0' + rand.IntN(10)))
+	}
+	return b.String()
+}
+
+// Draw 将数字绘制为带干扰的图片, 每个数字的位置和颜色随机偏移
+func Draw(answer string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	background := color.RGBA{R: 240, G: 240, B: 236, A: 255}
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			img.Set(x, y, background)
+		}
+	}
+
+	cell := Width / max(len(answer), 1)
+	for i, ch := range answer {
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		x := i*cell + rand.IntN(max(cell-glyphWidth*scale, 1))
+		y := rand.IntN(Height - glyphHeight*scale)
+		drawGlyph(img, digits[ch-'0'], x, y, randomColor())
+	}
+
+	for i := 0; i < noiseLines; i++ {
+		drawLine(img, rand.IntN(Width), rand.IntN(Height), rand.IntN(Width), rand.IntN(Height), randomColor())
+	}
+	for i := 0; i < noiseDots; i++ {
+		img.Set(rand.IntN(Width), rand.IntN(Height), randomColor())
+	}
+	return img
+}
+
+// EncodePNG 生成验证码图片并编码为 PNG
+func EncodePNG(answer string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Draw(answer)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func drawGlyph(img *image.RGBA, glyph [glyphHeight]uint8, x0, y0 int, c color.RGBA) {
+	for row, bits := range glyph {
+		for col := 0; col < glyphWidth; col++ {
+			if bits&(1<<(glyphWidth-1-col)) == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.Set(x0+col*scale+dx, y0+row*scale+dy, c)
+				}
+			}
+		}
+	}
+}
+
+// drawLine 用 Bresenham 算法画线
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// randomColor 深色, 与浅色背景区分
+func randomColor() color.RGBA {
+	return color.RGBA{R: uint8(rand.IntN(150)), G: uint8(rand.IntN(150)), B: uint8(rand.IntN(150)), A: 255}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
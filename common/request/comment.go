@@ -0,0 +1,25 @@
+package request
+
+import (
+	"inkgo/model"
+	"strings"
+)
+
+// GuestCommentRequest 游客发表评论, captcha_id 和 captcha 为获取的验证码及其答案
+type GuestCommentRequest struct {
+	Name      string `json:"name" binding:"required,max=64"`
+	Email     string `json:"email" binding:"required,email,max=128"`
+	Website   string `json:"website" binding:"omitempty,url,max=256"`
+	Content   string `json:"content" binding:"required,max=1024"`
+	CaptchaID string `json:"captcha_id" binding:"required"`
+	Captcha   string `json:"captcha" binding:"required"`
+}
+
+func (r *GuestCommentRequest) GetComment() *model.Comment {
+	return &model.Comment{
+		Content:      r.Content,
+		GuestName:    strings.TrimSpace(r.Name),
+		GuestEmail:   strings.ToLower(strings.TrimSpace(r.Email)),
+		GuestWebsite: r.Website,
+	}
+}
@@ -16,6 +16,17 @@ type Config struct {
 	Logger       LoggerConfig           `yaml:"logger"`
 	JWTConfig    JWTConfig              `yaml:"jwt"`
 	OAuthConfigs map[string]OAuthConfig `yaml:"oauth"` // 支持多种 OAuth 配置
+	Ranking      RankingConfig          `yaml:"ranking"`
+	Related      RelatedConfig          `yaml:"related"`
+	Share        ShareConfig            `yaml:"share"`
+	Export       ExportConfig           `yaml:"export"`
+	Import       ImportConfig           `yaml:"import"`
+	Moderation   ModerationConfig       `yaml:"moderation"`
+	Trash        TrashConfig            `yaml:"trash"`
+	Spam         SpamConfig             `yaml:"spam"`
+	Comment      CommentConfig          `yaml:"comment"`
+	Mail         MailConfig             `yaml:"mail"`
+	Timeline     TimelineConfig         `yaml:"timeline"`
 }
 
 type ServerConfig struct {
@@ -69,6 +80,80 @@ type LoggerConfig struct {
 	MaxBackups int    `yaml:"maxbackups" json:"maxbackups"`
 }
 
+// RankingConfig 热门文章排行的权重配置
+// score = (view*ViewWeight + like*LikeWeight + comment*CommentWeight + favorite*FavoriteWeight) / (小时数 + 2) ^ Gravity
+type RankingConfig struct {
+	ViewWeight     float64 `yaml:"viewWeight"`
+	LikeWeight     float64 `yaml:"likeWeight"`
+	CommentWeight  float64 `yaml:"commentWeight"`
+	FavoriteWeight float64 `yaml:"favoriteWeight"`
+	Gravity        float64 `yaml:"gravity"`  // 时间衰减因子, 越大旧文章衰减越快
+	Interval       int     `yaml:"interval"` // 重新计算热榜的间隔, 单位为秒
+	Size           int     `yaml:"size"`     // 热榜保留的文章数
+}
+
+// RelatedConfig 相关文章推荐配置
+// score = 标签的 Jaccard 系数*TagWeight + 分类的 Jaccard 系数*CategoryWeight + 正文 TF-IDF 余弦相似度*TextWeight
+type RelatedConfig struct {
+	TagWeight      float64 `yaml:"tagWeight"`
+	CategoryWeight float64 `yaml:"categoryWeight"`
+	TextWeight     float64 `yaml:"textWeight"`
+	MinScore       float64 `yaml:"minScore"` // 低于该相似度的文章不推荐
+	Size           int     `yaml:"size"`     // 每篇文章缓存的相关文章数
+	Interval       int     `yaml:"interval"` // 全量重新计算的间隔, 单位为秒
+}
+
+// ShareConfig 分享短链接配置
+type ShareConfig struct {
+	BaseURL   string `yaml:"baseURL"`   // 短链接的访问前缀, 例如 https://inkgo.io/s
+	TargetURL string `yaml:"targetURL"` // 短链接跳转的文章地址, {id} 会被替换为文章ID
+}
+
+// ExportConfig 文章导出配置
+type ExportConfig struct {
+	Dir        string   `yaml:"dir"`        // 批量导出文件的保存目录
+	FontPath   string   `yaml:"fontPath"`   // PDF 使用的 TrueType 字体, 导出中文内容必须配置
+	ImageHosts []string `yaml:"imageHosts"` // 允许下载并嵌入图片的域名, 其它图片以链接代替
+	Retention  int      `yaml:"retention"`  // 批量导出文件的保留时间, 单位为小时
+	Interval   int      `yaml:"interval"`   // 检查新导出任务的间隔, 单位为秒
+}
+
+// ImportConfig 文章导入配置
+type ImportConfig struct {
+	MaxUploadSize   int64 `yaml:"maxUploadSize"`   // 一次上传的文件总大小上限, 单位为 MB
+	MaxUnpackedSize int64 `yaml:"maxUnpackedSize"` // 一次上传的压缩包解压后的总大小上限, 单位为 MB
+}
+
+// ModerationConfig 敏感词过滤配置
+type ModerationConfig struct {
+	WordsFile     string `yaml:"wordsFile"`     // 敏感词列表文件, 每行一个词, 可用 "词|block" 指定处理方式
+	DefaultAction string `yaml:"defaultAction"` // 未指定处理方式的词如何处理: mask, review, block
+	Interval      int    `yaml:"interval"`      // 检查敏感词文件是否修改的间隔, 单位为秒
+}
+
+// TrashConfig 回收站配置
+type TrashConfig struct {
+	Retention int `yaml:"retention"` // 删除的内容在回收站中保留的天数, 之后彻底删除
+	Interval  int `yaml:"interval"`  // 检查过期内容的间隔, 单位为秒
+}
+
+// SpamConfig 垃圾评论分类器配置
+type SpamConfig struct {
+	Enabled         bool    `yaml:"enabled"`         // 是否自动分类新评论, 关闭时仍然可以标记评论以积累训练样本
+	HideThreshold   float64 `yaml:"hideThreshold"`   // 垃圾评论概率不低于该值时直接标记为垃圾评论
+	ReviewThreshold float64 `yaml:"reviewThreshold"` // 垃圾评论概率不低于该值时进入人工审核
+	MinSamples      int64   `yaml:"minSamples"`      // 垃圾评论和正常评论各至少有多少条样本后才开始自动分类
+	Interval        int     `yaml:"interval"`        // 重新加载模型的间隔, 单位为秒, 用于同步其它实例的训练结果
+}
+
+// CommentConfig 评论配置
+type CommentConfig struct {
+	EditWindow        int  `yaml:"editWindow"`        // 发表后多长时间内作者可以编辑评论, 单位为分钟; 管理员不受限制
+	Guest             bool `yaml:"guest"`             // 是否允许游客填写昵称和邮箱后免登录评论, 游客评论需要验证码且总是先进入人工审核
+	CaptchaExpiration int  `yaml:"captchaExpiration"` // 验证码的有效期, 单位为秒
+	ClaimExpiration   int  `yaml:"claimExpiration"`   // 认领游客评论的确认链接的有效期, 单位为分钟
+}
+
 type OAuthConfig struct {
 	AuthType     string `yaml:"authType"`
 	ClientID     string `yaml:"clientID"`
@@ -88,3 +173,24 @@ func LoadConfig(appconfig string) (*Config, error) {
 	}
 	return config, nil
 }
+
+// MailConfig 回复通知邮件配置
+type MailConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	From           string `yaml:"from"`           // 发件邮箱
+	AuthCode       string `yaml:"authCode"`       // 发件邮箱的 SMTP 授权码
+	Host           string `yaml:"host"`           // SMTP 服务器, 为空时根据发件邮箱的域名查找
+	Port           int    `yaml:"port"`           // SMTP 服务器端口
+	Secret         string `yaml:"secret"`         // 退订和认领链接的签名密钥, 为空时使用 JWT 密钥
+	UnsubscribeURL string `yaml:"unsubscribeURL"` // 退订接口的地址, 例如 https://inkgo.io/api/v1/unsubscribe
+	PostURL        string `yaml:"postURL"`        // 邮件中文章的链接, {id} 会被替换为文章ID
+	ClaimURL       string `yaml:"claimURL"`       // 认领游客评论的确认页面, 邮件中的链接会附带 token 参数
+	Interval       int    `yaml:"interval"`       // 发送回复通知的间隔, 单位为秒, 期间的回复合并为一封邮件
+}
+
+// TimelineConfig 首页时间线配置
+type TimelineConfig struct {
+	Size             int   `yaml:"size"`             // 每个用户的时间线在 Redis 中保留的文章数
+	PopularFollowers int64 `yaml:"popularFollowers"` // 粉丝数不低于该值的作者发文时不推送给粉丝, 由粉丝读取时间线时拉取
+	Expiration       int   `yaml:"expiration"`       // 时间线的过期时间, 单位为小时, 过期后下次读取时重建
+}
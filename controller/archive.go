@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type ArchiveController struct {
+	archiveService service.ArchiveService
+}
+
+func NewArchiveController(archiveService service.ArchiveService) Controller {
+	return &ArchiveController{
+		archiveService: archiveService,
+	}
+}
+
+// Summary 按年、月统计已发布的文章数, 可按 author、tag、category 筛选
+func (a *ArchiveController) Summary(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	filter, err := archiveFilter(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	years, err := a.archiveService.Summary(user, filter)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, years)
+}
+
+// ListPosts 列出某年或某月发布的文章
+func (a *ArchiveController) ListPosts(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	filter, err := archiveFilter(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	posts, total, err := a.archiveService.ListPosts(user, filter, c.Param("year"), c.Param("month"), page, pageSize)
+	if err != nil {
+		utils.Error(c, archiveErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, posts, total, page, pageSize)
+}
+
+// Calendar 写作日历, year 为空时返回最近一年每天发布的文章数
+func (a *ArchiveController) Calendar(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	filter, err := archiveFilter(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	calendar, err := a.archiveService.Calendar(user, filter, c.Query("year"))
+	if err != nil {
+		utils.Error(c, archiveErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, calendar)
+}
+
+// UserCalendar 指定作者的写作日历
+func (a *ArchiveController) UserCalendar(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	filter, err := archiveFilter(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	filter.AuthorID = uint(uid)
+	calendar, err := a.archiveService.Calendar(user, filter, c.Query("year"))
+	if err != nil {
+		utils.Error(c, archiveErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, calendar)
+}
+
+// archiveFilter 从 author、tag、category 参数中读取筛选条件
+func archiveFilter(c *gin.Context) (model.ArchiveFilter, error) {
+	var filter model.ArchiveFilter
+	for _, param := range []struct {
+		name  string
+		value *uint
+	}{
+		{"author", &filter.AuthorID},
+		{"tag", &filter.TagID},
+		{"category", &filter.CategoryID},
+	} {
+		if v := c.Query(param.name); v != "" {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return filter, errors.New("参数 " + param.name + " 必须是ID")
+			}
+			*param.value = uint(id)
+		}
+	}
+	return filter, nil
+}
+
+func archiveErrorStatus(err error) int {
+	if errors.Is(err, service.ErrArchiveDate) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (a *ArchiveController) Name() string {
+	return "archives"
+}
+
+func (a *ArchiveController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/archives", a.Summary)
+	api.GET("/archives/calendar", a.Calendar)
+	api.GET("/archives/:year", a.ListPosts)
+	api.GET("/archives/:year/:month", a.ListPosts)
+	api.GET("/user/:id/calendar", a.UserCalendar)
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type BlockController struct {
+	blockService service.BlockService
+}
+
+func NewBlockController(blockService service.BlockService) Controller {
+	return &BlockController{
+		blockService: blockService,
+	}
+}
+
+// Block 拉黑用户, 被拉黑的用户 @ 当前用户时不会生效
+func (b *BlockController) Block(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := b.blockService.Block(user, c.Param("id")); err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Unblock 取消拉黑
+func (b *BlockController) Unblock(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := b.blockService.Unblock(user, c.Param("id")); err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// List 当前用户拉黑的用户
+func (b *BlockController) List(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	blocks, total, err := b.blockService.List(user, page, pageSize)
+	if err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, blocks, total, page, pageSize)
+}
+
+// Mute 屏蔽用户, 被屏蔽的用户的文章不出现在首页时间线中
+func (b *BlockController) Mute(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := b.blockService.Mute(user, c.Param("id")); err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Unmute 取消屏蔽
+func (b *BlockController) Unmute(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := b.blockService.Unmute(user, c.Param("id")); err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListMutes 当前用户屏蔽的用户
+func (b *BlockController) ListMutes(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	mutes, total, err := b.blockService.ListMutes(user, page, pageSize)
+	if err != nil {
+		utils.Error(c, blockErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, mutes, total, page, pageSize)
+}
+
+func blockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrBlockSelf), errors.Is(err, service.ErrMuteSelf):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (b *BlockController) Name() string {
+	return "blocks"
+}
+
+func (b *BlockController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/user/blocks", b.List)
+	api.POST("/user/:id/block", b.Block)
+	api.DELETE("/user/:id/block", b.Unblock)
+	api.GET("/user/mutes", b.ListMutes)
+	api.POST("/user/:id/mute", b.Mute)
+	api.DELETE("/user/:id/mute", b.Unmute)
+}
@@ -1,67 +1,324 @@
 package controller
 
 import (
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"inkgo/common"
+	"inkgo/common/request"
+	"inkgo/database"
 	"inkgo/model"
 	"inkgo/service"
 	"inkgo/utils"
 	"net/http"
+	"strconv"
 )
 
 type CommentController struct {
 	commentService service.CommentService
+	captchaService service.CaptchaService
 }
 
-func NewCommentController(commentService service.CommentService) Controller {
+func NewCommentController(commentService service.CommentService, captchaService service.CaptchaService) Controller {
 	return &CommentController{
 		commentService: commentService,
+		captchaService: captchaService,
 	}
 }
 
+// Add 发表顶层评论. 兼容旧的 /comment 接口, 此时从请求体的 postId 读取文章ID
 func (p *CommentController) Add(c *gin.Context) {
 	currentUser, ok := utils.UserFromContext(c)
-
 	if !ok || currentUser == nil {
-		utils.Error(c, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
 		return
 	}
 
+	comment := new(model.Comment)
+	if err := c.ShouldBindJSON(&comment); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
 	id := c.Param("id")
+	if id == "" {
+		id = strconv.Itoa(int(comment.PostID))
+	}
+
+	comment, err := p.commentService.Add(currentUser, id, postGrant(c), comment)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, comment)
+}
+
+// Captcha 获取游客评论用的图片验证码
+func (p *CommentController) Captcha(c *gin.Context) {
+	captcha, err := p.captchaService.New()
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, captcha)
+}
+
+// AddGuest 游客发表顶层评论, 评论审核通过后才会公开
+func (p *CommentController) AddGuest(c *gin.Context) {
+	var req request.GuestCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	comment, err := p.commentService.AddGuest(c.Param("id"), postGrant(c), &req)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, comment)
+}
+
+// RequestClaim 向当前用户的邮箱发送认领游客评论的确认链接
+func (p *CommentController) RequestClaim(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := p.commentService.RequestClaim(currentUser); err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Claim 凭邮件中的确认链接认领注册前用相同邮箱发表的游客评论
+func (p *CommentController) Claim(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	claimed, err := p.commentService.ClaimGuestComments(currentUser, req.Token)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"claimed": claimed})
+}
 
+// Reply 回复评论
+func (p *CommentController) Reply(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	comment := new(model.Comment)
-	if err := c.BindJSON(&comment); err != nil {
-		common.ResponseFailed(c, http.StatusBadRequest, err)
+	if err := c.ShouldBindJSON(&comment); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	comment, err := p.commentService.Reply(currentUser, c.Param("id"), postGrant(c), comment)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
 	}
+	utils.Success(c, comment)
+}
 
-	comment, err := p.commentService.Add(comment, id, currentUser)
+// Edit 编辑评论, 请求体为 {"content": "..."}
+func (p *CommentController) Edit(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	comment, err := p.commentService.Edit(currentUser, c.Param("id"), req.Content)
 	if err != nil {
-		common.ResponseFailed(c, http.StatusInternalServerError, err)
+		utils.Error(c, commentErrorStatus(err), err)
+		return
 	}
-	common.ResponseSuccess(c, comment)
+	utils.Success(c, comment)
+}
+
+// ListRevisions 评论的编辑历史, 仅管理员可以查看
+func (p *CommentController) ListRevisions(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	revisions, err := p.commentService.ListRevisions(currentUser, c.Param("id"))
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, revisions)
 }
 
 func (p *CommentController) Delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := p.commentService.Delete(id); err != nil {
 		common.ResponseFailed(c, http.StatusInternalServerError, err)
+		return
 	}
 	common.ResponseSuccess(c, nil)
 }
 
-//
-//func (c *CommentController) List() {
-//
-//}
+// ListThreads 分页获取文章的顶层评论, sort 为 newest、oldest 或 likes, replies 为每个楼层预先返回的回复数
+func (p *CommentController) ListThreads(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	sort := model.CommentSort(c.DefaultQuery("sort", string(model.CommentNewest)))
+	switch sort {
+	case model.CommentNewest, model.CommentOldest, model.CommentMostLiked:
+	default:
+		utils.Error(c, http.StatusBadRequest, errors.New("sort 只能是 newest、oldest 或 likes"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+	replies, err := strconv.Atoi(c.DefaultQuery("replies", "-1"))
+	if err != nil {
+		replies = -1
+	}
+	comments, total, err := p.commentService.ListThreads(currentUser, c.Param("id"), postGrant(c), sort, page, pageSize, replies)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, comments, total, page, pageSize)
+}
+
+// ListReplies 加载楼层中更多的回复, cursor 为顶层评论返回的 replies_cursor
+func (p *CommentController) ListReplies(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if !ok {
+		limit = 10
+	}
+	replies, next, err := p.commentService.ListReplies(currentUser, c.Param("id"), postGrant(c), cursor, limit)
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithCursor(c, replies, next)
+}
+
+// Count 文章的评论数, 包括回复
+func (p *CommentController) Count(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	count, err := p.commentService.Count(currentUser, c.Param("id"), postGrant(c))
+	if err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"count": count})
+}
+
+func (p *CommentController) Like(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := p.commentService.Like(currentUser, c.Param("id"), postGrant(c)); err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func (p *CommentController) Unlike(c *gin.Context) {
+	currentUser, ok := utils.UserFromContext(c)
+	if !ok || currentUser == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := p.commentService.Unlike(currentUser, c.Param("id")); err != nil {
+		utils.Error(c, commentErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func commentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrContentBlocked):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrCommentDepth), errors.Is(err, service.ErrCaptcha),
+		errors.Is(err, service.ErrClaimEmail), errors.Is(err, service.ErrClaimToken):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrMailDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrCommentNotOwner), errors.Is(err, service.ErrCommentEditExpired),
+		errors.Is(err, service.ErrModerationForbidden), errors.Is(err, service.ErrGuestComment):
+		return http.StatusForbidden
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.RedisDisableError):
+		return http.StatusServiceUnavailable
+	default:
+		return postErrorStatus(err)
+	}
+}
 
 func (c *CommentController) Name() string {
 	return "comments"
 }
 
 func (c *CommentController) RegisterRoute(api *gin.RouterGroup) {
-	//api.GET("/comments", c.List)
-	//api.GET("/tag/:id", t.Get)
+	api.GET("/post/:id/comments", c.ListThreads)
+	api.GET("/post/:id/comments/count", c.Count)
+	api.POST("/post/:id/comments", c.Add)
 	api.POST("/comment", c.Add)
+	api.POST("/comment/:id/reply", c.Reply)
+	api.GET("/comment/:id/replies", c.ListReplies)
+	api.POST("/comment/:id/like", c.Like)
+	api.DELETE("/comment/:id/like", c.Unlike)
+	api.PUT("/comment/:id", c.Edit)
+	api.GET("/comment/:id/revisions", c.ListRevisions)
 	api.DELETE("/comment/:id", c.Delete)
+	api.POST("/comments/claim/email", c.RequestClaim)
+	api.POST("/comments/claim", c.Claim)
+}
+
+func (c *CommentController) RegisterPublicRoute(public *gin.RouterGroup) {
+	public.GET("/captcha", c.Captcha)
+	public.POST("/post/:id/guest-comments", c.AddGuest)
 }
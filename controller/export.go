@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/exporter"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"net/url"
+)
+
+type ExportController struct {
+	exportService service.ExportService
+}
+
+func NewExportController(exportService service.ExportService) Controller {
+	return &ExportController{
+		exportService: exportService,
+	}
+}
+
+// ExportPost 下载单篇文章, format 可选 docx、pdf、epub
+func (e *ExportController) ExportPost(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	file, err := e.exportService.ExportPost(user, c.Param("id"), postGrant(c), c.DefaultQuery("format", string(exporter.FormatPDF)))
+	if err != nil {
+		utils.Error(c, exportErrorStatus(err), err)
+		return
+	}
+	c.Header("Content-Disposition", attachment(file.Name))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// ExportAll 创建导出当前用户全部文章的后台任务
+func (e *ExportController) ExportAll(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	task, err := e.exportService.ExportAll(user, c.DefaultQuery("format", string(exporter.FormatEPUB)))
+	if err != nil {
+		utils.Error(c, exportErrorStatus(err), err)
+		return
+	}
+	utils.JSON(c, http.StatusAccepted, "导出任务已创建", task, nil, nil, nil)
+}
+
+// ListTasks 列出当前用户的导出任务
+func (e *ExportController) ListTasks(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	tasks, err := e.exportService.ListTasks(user)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, tasks)
+}
+
+// GetTask 查询导出任务的状态
+func (e *ExportController) GetTask(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	task, err := e.exportService.GetTask(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, exportErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, task)
+}
+
+// Download 下载已完成的导出文件
+func (e *ExportController) Download(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	task, err := e.exportService.TaskFile(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, exportErrorStatus(err), err)
+		return
+	}
+	c.Header("Content-Disposition", attachment(task.FileName))
+	c.File(task.FilePath)
+}
+
+// attachment 生成下载文件的 Content-Disposition, 文件名可以包含中文
+func attachment(name string) string {
+	return fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name))
+}
+
+func exportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, exporter.ErrUnsupportedFormat):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrExportForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrExportNotReady), errors.Is(err, service.ErrExportRunning):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return postErrorStatus(err)
+	}
+}
+
+func (e *ExportController) Name() string {
+	return "exports"
+}
+
+func (e *ExportController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/post/:id/export", e.ExportPost)
+	api.POST("/posts/export", e.ExportAll)
+	api.GET("/exports", e.ListTasks)
+	api.GET("/export/:id", e.GetTask)
+	api.GET("/export/:id/download", e.Download)
+}
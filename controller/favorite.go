@@ -1,9 +1,9 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
-	"inkgo/common"
 	"inkgo/model"
 	"inkgo/service"
 	"inkgo/utils"
@@ -26,10 +26,27 @@ func (f *FavoriteController) RegisterRoute(api *gin.RouterGroup) {
 	api.PUT("/favorites/:id", f.UpdateFavorite)
 	api.DELETE("/favorites/:id", f.DeleteFavorite)
 	api.POST("/favorite/:id/post", f.AddPostToFavorite)
+	api.PUT("/favorite/:id/post/:post_id", f.UpdateNote)
 	api.DELETE("/favorite/:id/post/:post_id", f.RemovePostFromFavorite)
 	api.GET("/favorite/:id/posts", f.ListPostsInFavorite)
+	api.POST("/favorite/:id/posts", f.BatchAddPosts)
+	api.DELETE("/favorite/:id/posts", f.Clear)
+	api.PUT("/favorite/:id/order", f.Reorder)
+	api.GET("/favorite/:id/items", f.ListItems)
 
 	api.GET("/favorite/:id/posts/count", f.CountPostsInFavorite)
+	api.GET("/post/:id/favorites", f.ListFavoritesOfPost)
+
+	api.POST("/favorite/:id/follow", f.Follow)
+	api.DELETE("/favorite/:id/follow", f.Unfollow)
+	api.GET("/favorites/following", f.ListFollowed)
+	api.GET("/favorites/following/activity", f.ListFollowedActivity)
+}
+
+// RegisterPublicRoute 公开的收藏夹通过链接免登录访问
+func (f *FavoriteController) RegisterPublicRoute(public *gin.RouterGroup) {
+	public.GET("/shared/favorites/:id", f.GetShared)
+	public.GET("/shared/favorites/:id/items", f.ListSharedItems)
 }
 
 func NewFavoriteController(favoriteService service.FavoriteService) Controller {
@@ -40,10 +57,25 @@ func NewFavoriteController(favoriteService service.FavoriteService) Controller {
 
 // ListFavorites 用于列出用户的收藏夹
 func (f *FavoriteController) ListFavorites(c *gin.Context) {
-	user := model.User{
-		Model: gorm.Model{
-			ID: 1, // This should be replaced with actual user retrieval logic
-		},
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		favorites, next, err := f.favoriteService.GetUserFavoritesByCursor(strconv.Itoa(int(user.ID)), cursor, limit)
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		utils.SuccessWithCursor(c, favorites, next)
+		return
 	}
 
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -58,34 +90,44 @@ func (f *FavoriteController) ListFavorites(c *gin.Context) {
 	utils.SuccessWithPage(c, favorites, total, page, pageSize)
 }
 
-// GetFavorite 用于获取特定收藏夹的详细信息
+// GetFavorite 用于获取特定收藏夹的详细信息, 他人的收藏夹公开时也可以查看
 func (f *FavoriteController) GetFavorite(c *gin.Context) {
-	uid := common.GetUserID(c)
-	fid := c.Param("id")
-	favorite, err := f.favoriteService.GetFavoriteByID(strconv.Itoa(int(uid)), fid)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	favorite, err := f.favoriteService.GetFavoriteByID(strconv.Itoa(int(user.ID)), c.Param("id"))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, favorite)
+}
+
+// GetShared 免登录查看公开的收藏夹
+func (f *FavoriteController) GetShared(c *gin.Context) {
+	favorite, err := f.favoriteService.GetFavoriteByID("0", c.Param("id"))
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, favorite)
 }
 
 func (f *FavoriteController) CreateFavorite(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	favorite := &model.Favorite{}
 	if err := c.ShouldBindJSON(favorite); err != nil {
 		utils.Error(c, http.StatusBadRequest, err)
 		return
 	}
 
-	user := model.User{
-		Model: gorm.Model{
-			ID: 1, // This should be replaced with actual user retrieval logic
-		},
-	}
-	favorite.UserID = user.ID
-	uid := common.GetUserID(c)
-
-	newFavorite, err := f.favoriteService.CreateFavorite(strconv.Itoa(int(uid)), favorite)
+	newFavorite, err := f.favoriteService.CreateFavorite(strconv.Itoa(int(user.ID)), favorite)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -94,6 +136,11 @@ func (f *FavoriteController) CreateFavorite(c *gin.Context) {
 }
 
 func (f *FavoriteController) UpdateFavorite(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	fid := c.Param("id")
 	favorite := &model.Favorite{}
 	if err := c.ShouldBindJSON(favorite); err != nil {
@@ -103,8 +150,7 @@ func (f *FavoriteController) UpdateFavorite(c *gin.Context) {
 
 	favoritID, _ := strconv.ParseUint(fid, 10, 64)
 	favorite.ID = uint(favoritID)
-	uid := common.GetUserID(c)
-	updatedFavorite, err := f.favoriteService.UpdateFavorite(strconv.Itoa(int(uid)), favorite)
+	updatedFavorite, err := f.favoriteService.UpdateFavorite(strconv.Itoa(int(user.ID)), favorite)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -115,77 +161,283 @@ func (f *FavoriteController) UpdateFavorite(c *gin.Context) {
 
 // DeleteFavorite 用于删除特定收藏夹
 func (f *FavoriteController) DeleteFavorite(c *gin.Context) {
-	fid := c.Param("id")
-	uid := common.GetUserID(c)
-	err := f.favoriteService.DeleteFavorite(strconv.Itoa(int(uid)), fid)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	err := f.favoriteService.DeleteFavorite(strconv.Itoa(int(user.ID)), c.Param("id"))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, nil)
 }
 
+// ListPostsInFavorite 收藏夹中的全部文章, 按手动排序的顺序
 func (f *FavoriteController) ListPostsInFavorite(c *gin.Context) {
-	fid := c.Param("id")
-	uid := common.GetUserID(c)
-	favorite, err := f.favoriteService.GetFavoriteByID(strconv.Itoa(int(uid)), fid)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	posts, err := f.favoriteService.ListPostsInFavorite(strconv.Itoa(int(user.ID)), c.Param("id"))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
+	utils.Success(c, posts)
+}
 
-	if favorite == nil {
-		utils.Error(c, http.StatusNotFound, err)
+// ListItems 分页列出收藏夹中的文章, 包含备注和收藏时间
+func (f *FavoriteController) ListItems(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
 		return
 	}
+	f.listItems(c, user)
+}
 
-	utils.Success(c, favorite.Posts)
+// ListSharedItems 免登录分页查看公开收藏夹中的文章
+func (f *FavoriteController) ListSharedItems(c *gin.Context) {
+	f.listItems(c, &model.User{})
 }
 
+func (f *FavoriteController) listItems(c *gin.Context, user *model.User) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	items, total, err := f.favoriteService.ListItems(user, c.Param("id"), page, pageSize)
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, items, total, page, pageSize)
+}
+
+// AddPostToFavorite 收藏文章到收藏夹末尾, 可以附带备注
 func (f *FavoriteController) AddPostToFavorite(c *gin.Context) {
-	fid := c.Param("id")
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	var request AddPostRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		utils.Error(c, http.StatusBadRequest, err)
 		return
 	}
-	uid := common.GetUserID(c)
 	pid := strconv.Itoa(int(request.PostID))
-	err := f.favoriteService.AddPostToFavorite(strconv.Itoa(int(uid)), pid, fid)
+	err := f.favoriteService.AddPostToFavorite(strconv.Itoa(int(user.ID)), pid, c.Param("id"), request.Note)
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, nil)
 }
 
 type AddPostRequest struct {
-	PostID uint `json:"post_id" binding:"required"`
+	PostID uint   `json:"post_id" binding:"required"`
+	Note   string `json:"note" binding:"max=512"`
 }
 
-func (f *FavoriteController) RemovePostFromFavorite(c *gin.Context) {
-	fid := c.Param("id")
-	var request AddPostRequest
+// FavoritePostsRequest 批量收藏或排序的文章ID
+type FavoritePostsRequest struct {
+	PostIDs []uint `json:"post_ids" binding:"required"`
+}
+
+// FavoriteNoteRequest 修改收藏的备注
+type FavoriteNoteRequest struct {
+	Note string `json:"note" binding:"max=512"`
+}
+
+// BatchAddPosts 批量收藏文章, 返回新加入的数量
+func (f *FavoriteController) BatchAddPosts(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request FavoritePostsRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		utils.Error(c, http.StatusBadRequest, err)
 		return
 	}
-	uid := common.GetUserID(c)
-	err := f.favoriteService.RemovePostFromFavorite(strconv.Itoa(int(uid)), strconv.Itoa(int(request.PostID)), fid)
+	added, err := f.favoriteService.BatchAddPosts(user, c.Param("id"), request.PostIDs)
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"added": added})
+}
+
+// UpdateNote 修改收藏的备注
+func (f *FavoriteController) UpdateNote(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request FavoriteNoteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := f.favoriteService.UpdateNote(user, c.Param("id"), c.Param("post_id"), request.Note); err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func (f *FavoriteController) RemovePostFromFavorite(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	err := f.favoriteService.RemovePostFromFavorite(strconv.Itoa(int(user.ID)), c.Param("post_id"), c.Param("id"))
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Clear 清空收藏夹, 返回移除的数量
+func (f *FavoriteController) Clear(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	removed, err := f.favoriteService.Clear(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"removed": removed})
+}
+
+// Reorder 按 post_ids 的顺序排列收藏夹, 未列出的文章排在后面
+func (f *FavoriteController) Reorder(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request FavoritePostsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := f.favoriteService.Reorder(user, c.Param("id"), request.PostIDs); err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, nil)
 }
 
 func (f *FavoriteController) CountPostsInFavorite(c *gin.Context) {
-	fid := c.Param("id")
-	uid := common.GetUserID(c)
-	count, err := f.favoriteService.CountPostsInFavorite(strconv.Itoa(int(uid)), fid)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	count, err := f.favoriteService.CountPostsInFavorite(strconv.Itoa(int(user.ID)), c.Param("id"))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, favoriteErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, gin.H{"count": count})
 }
+
+// ListFavoritesOfPost 收藏了该文章的收藏夹, 包括自己的和他人公开的
+func (f *FavoriteController) ListFavoritesOfPost(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	favorites, err := f.favoriteService.ListFavoritesOfPost(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, favorites)
+}
+
+// Follow 关注他人的公开收藏夹
+func (f *FavoriteController) Follow(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := f.favoriteService.Follow(user, c.Param("id")); err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Unfollow 取消关注收藏夹
+func (f *FavoriteController) Unfollow(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := f.favoriteService.Unfollow(user, c.Param("id")); err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListFollowed 当前用户关注的收藏夹
+func (f *FavoriteController) ListFollowed(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	follows, total, err := f.favoriteService.ListFollowed(user, page, pageSize)
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, follows, total, page, pageSize)
+}
+
+// ListFollowedActivity 关注的收藏夹中新加入的文章, 最近加入的在前
+func (f *FavoriteController) ListFollowedActivity(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	activities, total, err := f.favoriteService.ListFollowedActivity(user, page, pageSize)
+	if err != nil {
+		utils.Error(c, favoriteErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, activities, total, page, pageSize)
+}
+
+func favoriteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrFavoriteBatch), errors.Is(err, service.ErrFavoriteFollow):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrFavoriteNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPostForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type FollowController struct {
+	followService service.FollowService
+}
+
+func NewFollowController(followService service.FollowService) Controller {
+	return &FollowController{
+		followService: followService,
+	}
+}
+
+// Follow 关注用户
+func (f *FollowController) Follow(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := f.followService.Follow(user, c.Param("id")); err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Unfollow 取消关注
+func (f *FollowController) Unfollow(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := f.followService.Unfollow(user, c.Param("id")); err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// ListFollowing 用户关注的人, mutual 表示是否互相关注
+func (f *FollowController) ListFollowing(c *gin.Context) {
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		users, next, err := f.followService.ListFollowingByCursor(c.Param("id"), cursor, limit)
+		if err != nil {
+			utils.Error(c, followErrorStatus(err), err)
+			return
+		}
+		utils.SuccessWithCursor(c, users, next)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	users, total, err := f.followService.ListFollowing(c.Param("id"), page, pageSize)
+	if err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, users, total, page, pageSize)
+}
+
+// ListFollowers 用户的粉丝, mutual 表示是否互相关注
+func (f *FollowController) ListFollowers(c *gin.Context) {
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		users, next, err := f.followService.ListFollowersByCursor(c.Param("id"), cursor, limit)
+		if err != nil {
+			utils.Error(c, followErrorStatus(err), err)
+			return
+		}
+		utils.SuccessWithCursor(c, users, next)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	users, total, err := f.followService.ListFollowers(c.Param("id"), page, pageSize)
+	if err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, users, total, page, pageSize)
+}
+
+// Suggest 推荐关注, 来自关注的人所关注的用户和点赞过的文章的作者
+func (f *FollowController) Suggest(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	suggestions, err := f.followService.Suggest(user, limit)
+	if err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, suggestions)
+}
+
+// FollowTopic 关注标签或分类, 路由 /tag/:id/follow 和 /category/:id/follow
+func (f *FollowController) FollowTopic(target model.FollowTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		if err := f.followService.FollowTopic(user, target, c.Param("id")); err != nil {
+			utils.Error(c, followErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, nil)
+	}
+}
+
+// UnfollowTopic 取消关注标签或分类
+func (f *FollowController) UnfollowTopic(target model.FollowTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		if err := f.followService.UnfollowTopic(user, target, c.Param("id")); err != nil {
+			utils.Error(c, followErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, nil)
+	}
+}
+
+// ListTopics 当前用户关注的标签和分类
+func (f *FollowController) ListTopics(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	topics, err := f.followService.ListTopics(user)
+	if err != nil {
+		utils.Error(c, followErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, topics)
+}
+
+func followErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrFollowSelf), errors.Is(err, service.ErrFollowTarget):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrFollowBlocked):
+		return http.StatusForbidden
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (f *FollowController) Name() string {
+	return "follows"
+}
+
+func (f *FollowController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/user/suggestions", f.Suggest)
+	api.POST("/user/:id/follow", f.Follow)
+	api.DELETE("/user/:id/follow", f.Unfollow)
+	api.GET("/user/:id/following", f.ListFollowing)
+	api.GET("/user/:id/followers", f.ListFollowers)
+	api.GET("/user/topics", f.ListTopics)
+	api.POST("/tag/:id/follow", f.FollowTopic(model.FollowTag))
+	api.DELETE("/tag/:id/follow", f.UnfollowTopic(model.FollowTag))
+	api.POST("/category/:id/follow", f.FollowTopic(model.FollowCategory))
+	api.DELETE("/category/:id/follow", f.UnfollowTopic(model.FollowCategory))
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"inkgo/importer"
+	"inkgo/service"
+	"inkgo/utils"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type ImportController struct {
+	importService service.ImportService
+}
+
+func NewImportController(importService service.ImportService) Controller {
+	return &ImportController{
+		importService: importService,
+	}
+}
+
+// Import 管理员上传文件批量导入文章
+// 表单字段 file 可上传多个 .md/.zip/.xml 文件, dry_run 默认为 true, 只返回差异报告
+func (i *ImportController) Import(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if !utils.IsAdmin(user) {
+		utils.Error(c, http.StatusForbidden, service.ErrImportForbidden)
+		return
+	}
+	// 限制请求体大小, 必须在解析表单之前设置
+	limit := i.importService.MaxUploadSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, fmt.Errorf("上传的文件不能超过 %d MB", limit>>20))
+			return
+		}
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", c.DefaultQuery("dry_run", "true")))
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		utils.Error(c, http.StatusBadRequest, errors.New("请上传要导入的文件"))
+		return
+	}
+
+	var docs []*importer.Document
+	unpack := i.importService.UnpackLimit()
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			utils.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		parsed, err := importer.ParseFile(fileHeader.Filename, data, unpack)
+		if err != nil {
+			if errors.Is(err, importer.ErrTooLarge) {
+				utils.Error(c, http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			utils.Error(c, http.StatusBadRequest, err)
+			return
+		}
+		docs = append(docs, parsed...)
+	}
+
+	report, err := i.importService.Import(user, docs, dryRun)
+	if err != nil {
+		if errors.Is(err, service.ErrImportForbidden) {
+			utils.Error(c, http.StatusForbidden, err)
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, report)
+}
+
+func (i *ImportController) Name() string {
+	return "imports"
+}
+
+func (i *ImportController) RegisterRoute(api *gin.RouterGroup) {
+	api.POST("/import", i.Import)
+}
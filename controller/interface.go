@@ -6,3 +6,8 @@ type Controller interface {
 	Name() string
 	RegisterRoute(*gin.RouterGroup)
 }
+
+// PublicController 除了需要登录的路由, 还有免登录访问的路由
+type PublicController interface {
+	RegisterPublicRoute(*gin.RouterGroup)
+}
@@ -1,9 +1,9 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
-	"inkgo/model"
 	"inkgo/service"
 	"inkgo/utils"
 	"net/http"
@@ -37,20 +37,28 @@ func NewlikeController(likeService service.LikeService) Controller {
 
 func (l *LikeController) LikePost(c *gin.Context) {
 
-	user := &model.User{Model: gorm.Model{ID: 2}}
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	pid := c.Param("id")
-	if err := l.likeService.LikePost(user, pid); err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+	if err := l.likeService.LikePost(user, pid, postGrant(c)); err != nil {
+		utils.Error(c, likeErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, nil)
 }
 
 func (p *LikeController) UnLikePost(c *gin.Context) {
-	user := &model.User{Model: gorm.Model{ID: 2}}
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	pid := c.Param("id")
-	if err := p.likeService.UnLikePost(user, pid); err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+	if err := p.likeService.UnLikePost(user, pid, postGrant(c)); err != nil {
+		utils.Error(c, likeErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, nil)
@@ -66,3 +74,10 @@ func (l *LikeController) CountLikes(c *gin.Context) {
 	}
 	utils.Success(c, gin.H{"count": count})
 }
+
+func likeErrorStatus(err error) int {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return postErrorStatus(err)
+}
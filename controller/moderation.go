@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type ModerationController struct {
+	moderationService service.ModerationService
+}
+
+func NewModerationController(moderationService service.ModerationService) Controller {
+	return &ModerationController{
+		moderationService: moderationService,
+	}
+}
+
+// ListReviews 审核队列, status 默认为 pending
+func (m *ModerationController) ListReviews(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	reviews, total, err := m.moderationService.ListReviews(user, c.DefaultQuery("status", "pending"), page, pageSize)
+	if err != nil {
+		utils.Error(c, moderationErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, reviews, total, page, pageSize)
+}
+
+// Approve 审核通过并发布内容
+func (m *ModerationController) Approve(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	review, err := m.moderationService.Approve(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, moderationErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, review)
+}
+
+type RejectRequest struct {
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// Reject 审核不通过
+func (m *ModerationController) Reject(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request RejectRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.Error(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	review, err := m.moderationService.Reject(user, c.Param("id"), request.Reason)
+	if err != nil {
+		utils.Error(c, moderationErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, review)
+}
+
+// ReloadWords 立即重新加载敏感词列表
+func (m *ModerationController) ReloadWords(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if !utils.IsAdmin(user) {
+		utils.Error(c, http.StatusForbidden, service.ErrModerationForbidden)
+		return
+	}
+	count, err := m.moderationService.Reload()
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, gin.H{"words": count})
+}
+
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrModerationForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrReviewResolved):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (m *ModerationController) Name() string {
+	return "moderation"
+}
+
+func (m *ModerationController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/moderation/reviews", m.ListReviews)
+	api.POST("/moderation/review/:id/approve", m.Approve)
+	api.POST("/moderation/review/:id/reject", m.Reject)
+	api.POST("/moderation/words/reload", m.ReloadWords)
+}
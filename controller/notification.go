@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type NotificationController struct {
+	notificationService service.NotificationService
+}
+
+func NewNotificationController(notificationService service.NotificationService) Controller {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// List 分页获取当前用户的通知, unread=true 时只返回未读通知
+func (n *NotificationController) List(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	unread := c.Query("unread") == "true"
+	notifications, total, err := n.notificationService.List(user, unread, page, pageSize)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.SuccessWithPage(c, notifications, total, page, pageSize)
+}
+
+// CountUnread 未读通知数
+func (n *NotificationController) CountUnread(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	count, err := n.notificationService.CountUnread(user)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, gin.H{"count": count})
+}
+
+// MarkRead 标记为已读, 请求体为 {"ids": [1, 2]}, 不传 ids 时标记全部
+func (n *NotificationController) MarkRead(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var req struct {
+		IDs []uint `json:"ids"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.Error(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if err := n.notificationService.MarkRead(user, req.IDs); err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func (n *NotificationController) Name() string {
+	return "notifications"
+}
+
+func (n *NotificationController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/notifications", n.List)
+	api.GET("/notifications/unread/count", n.CountUnread)
+	api.POST("/notifications/read", n.MarkRead)
+}
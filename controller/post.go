@@ -2,12 +2,16 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"inkgo/model"
 	"inkgo/service"
 	"inkgo/utils"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type PostController struct {
@@ -20,17 +24,100 @@ func NewPostController(PostService service.PostService) Controller {
 	}
 }
 
-// GetPostByID 获取文章详情
+// GetPostByID 获取文章详情, 加密文章需要在 X-Post-Grant 请求头中携带解锁得到的授权
 func (p *PostController) GetPostByID(c *gin.Context) {
-	pid := c.Param("id")
-	post, err := p.postService.GetPostByID(pid)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	post, err := p.postService.GetPostByID(user, c.Param("id"), postGrant(c))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, postErrorStatus(err), err)
 		return
 	}
+	setETag(c, post.Version)
 	utils.Success(c, post)
 }
 
+// GetShared 免登录查看分享的文章
+func (p *PostController) GetShared(c *gin.Context) {
+	post, err := p.postService.GetSharedPost(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, err)
+			return
+		}
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, post)
+}
+
+// postGrant 获取加密文章的访问授权, 优先使用请求头
+func postGrant(c *gin.Context) string {
+	if grant := c.GetHeader("X-Post-Grant"); grant != "" {
+		return grant
+	}
+	return c.Query("grant")
+}
+
+// ListRelated 获取相关文章
+func (p *PostController) ListRelated(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	posts, err := p.postService.ListRelated(user, c.Param("id"), postGrant(c), limit)
+	if err != nil {
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, posts)
+}
+
+type UnlockRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// Unlock 输入密码解锁加密文章
+func (p *PostController) Unlock(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request UnlockRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	grant, expiresAt, err := p.postService.Unlock(user, c.Param("id"), request.Password)
+	if err != nil {
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"grant": grant, "expires_at": expiresAt})
+}
+
+func postErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPostForbidden), errors.Is(err, service.ErrPostLocked), errors.Is(err, service.ErrPostPassword),
+		errors.Is(err, service.ErrPostNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPostConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrContentBlocked):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrBulkRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Get 获取单个文章
 //func (p *PostController) GetPostByID(c *gin.Context) {
 //	pid := c.Param("id")
@@ -46,20 +133,44 @@ func (p *PostController) GetPostByID(c *gin.Context) {
 
 // GetPostByName 获取文章详情
 func (p *PostController) GetPostByName(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	name := c.Param("name")
-	post, err := p.postService.GetPostByName(name)
+	post, err := p.postService.GetPostByName(user, name)
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, postErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, post)
 }
 
-// List 已发布的文章列表
+// List 已发布的文章列表, 支持 page/page_size 和 cursor/limit 两种分页方式
 func (p *PostController) ListHasPublished(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		posts, next, err := p.postService.HasPublishedByCursor(user, cursor, limit)
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		utils.SuccessWithCursor(c, posts, next)
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "2"))
-	posts, total, err := p.postService.HasPublished(page, pageSize)
+	posts, total, err := p.postService.HasPublished(user, page, pageSize)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -67,7 +178,130 @@ func (p *PostController) ListHasPublished(c *gin.Context) {
 	utils.SuccessWithPage(c, posts, total, page, pageSize)
 }
 
+// Query 按条件筛选和排序文章, 例如 /posts?tag_name=go&category_name=backend&author=7&from=2025-05-01&to=2025-05-31&sort=-like_count
+func (p *PostController) Query(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	query, err := postQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	posts, total, err := p.postService.Query(user, query, page, pageSize)
+	if err != nil {
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, posts, total, page, pageSize)
+}
+
+// postQuery 解析文章列表的筛选参数. tag 和 category 为ID, tag_name 和 category_name 为名称, 都可以重复或用逗号分隔;
+// from 和 to 为日期(包含当天), sort 为逗号分隔的排序字段, 字段前加 - 表示降序
+func postQuery(c *gin.Context) (*model.PostQuery, error) {
+	query := &model.PostQuery{
+		TagNames:      queryList(c, "tag_name"),
+		CategoryNames: queryList(c, "category_name"),
+		State:         model.PostState(c.Query("state")),
+	}
+	var err error
+	if query.TagIDs, err = queryIDs(c, "tag"); err != nil {
+		return nil, err
+	}
+	if query.CategoryIDs, err = queryIDs(c, "category"); err != nil {
+		return nil, err
+	}
+	if v := c.Query("author"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("参数 author 必须是ID")
+		}
+		query.AuthorID = uint(id)
+	}
+	switch query.State {
+	case "", model.PostDraft, model.PostPublished, model.PostArchived, model.PostPending:
+	default:
+		return nil, fmt.Errorf("无效的文章状态: %s", query.State)
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			return nil, errors.New("参数 from 的格式应为 YYYY-MM-DD")
+		}
+		query.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			return nil, errors.New("参数 to 的格式应为 YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+		query.To = &to
+	}
+	if v := c.Query("original"); v != "" {
+		original, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("参数 original 应为 true 或 false")
+		}
+		query.Original = &original
+	}
+	if query.Sort, err = model.ParsePostSort(c.Query("sort")); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// queryList 读取可以重复或用逗号分隔的参数
+func queryList(c *gin.Context, name string) []string {
+	values := make([]string, 0)
+	for _, v := range c.QueryArray(name) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func queryIDs(c *gin.Context, name string) ([]uint, error) {
+	values := queryList(c, name)
+	ids := make([]uint, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("参数 " + name + " 必须是ID")
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
 func (a *PostController) ListDrafts(c *gin.Context) {
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		posts, next, err := a.postService.ListDraftsByCursor(cursor, limit)
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		utils.SuccessWithCursor(c, posts, next)
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "2"))
 	posts, total, err := a.postService.ListDrafts(page, pageSize)
@@ -80,16 +314,21 @@ func (a *PostController) ListDrafts(c *gin.Context) {
 
 // Create 创建文章
 func (p *PostController) Create(c *gin.Context) {
-	user := new(model.User)
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	post := new(model.Post)
 	if err := c.ShouldBindJSON(&post); err != nil {
 		utils.Error(c, http.StatusBadRequest, err)
 		return
 	}
+	post.AuthorID = user.ID
 
 	post, err := p.postService.Create(user, post)
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, postErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, post)
@@ -105,21 +344,85 @@ func (p *PostController) Delete(c *gin.Context) {
 	utils.Success(c, nil)
 }
 
+// Update 更新文章, 需要通过 If-Match 请求头或 version 字段携带读取时的版本号
 func (p *PostController) Update(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	id := c.Param("id")
 	post := &model.Post{}
 	if err := c.ShouldBindJSON(&post); err != nil {
 		utils.Error(c, http.StatusBadRequest, err)
 		return
 	}
-	post, err := p.postService.Update(id, post)
+	version, ok := requestVersion(c, post.Version)
+	if !ok {
+		utils.Error(c, http.StatusPreconditionRequired, errors.New("缺少 If-Match 请求头或 version 字段"))
+		return
+	}
+	post, err := p.postService.Update(user, id, post, version)
+	p.versionedResponse(c, post, err)
+}
+
+// Autosave 自动保存草稿, 版本规则与 Update 相同
+func (p *PostController) Autosave(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	post := &model.Post{}
+	if err := c.ShouldBindJSON(&post); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	version, ok := requestVersion(c, post.Version)
+	if !ok {
+		utils.Error(c, http.StatusPreconditionRequired, errors.New("缺少 If-Match 请求头或 version 字段"))
+		return
+	}
+	post, err := p.postService.Autosave(user, c.Param("id"), post, version)
+	p.versionedResponse(c, post, err)
+}
+
+// versionedResponse 写入带 ETag 的响应, 版本冲突时返回 409 和服务端当前的文章
+func (p *PostController) versionedResponse(c *gin.Context, post *model.Post, err error) {
+	if post != nil {
+		setETag(c, post.Version)
+	}
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		if errors.Is(err, service.ErrPostConflict) {
+			utils.JSON(c, http.StatusConflict, err.Error(), post, nil, nil, nil)
+			return
+		}
+		utils.Error(c, postErrorStatus(err), err)
 		return
 	}
 	utils.Success(c, post)
 }
 
+// requestVersion 优先从 If-Match 请求头读取版本号, 否则使用请求体中的 version
+func requestVersion(c *gin.Context, bodyVersion uint) (uint, bool) {
+	if match := c.GetHeader("If-Match"); match != "" {
+		match = strings.Trim(strings.TrimPrefix(match, "W/"), `"`)
+		version, err := strconv.ParseUint(match, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return uint(version), true
+	}
+	if bodyVersion > 0 {
+		return bodyVersion, true
+	}
+	return 0, false
+}
+
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", fmt.Sprintf(`"%d"`, version))
+}
+
 func (p *PostController) UpdateStatus(c *gin.Context) {
 	id := c.Param("id")
 	state := c.Query("state")
@@ -130,17 +433,43 @@ func (p *PostController) UpdateStatus(c *gin.Context) {
 
 	post, err := p.postService.UpdateStatus(id, model.PostState(state))
 	if err != nil {
-		utils.Error(c, http.StatusInternalServerError, err)
+		utils.Error(c, postErrorStatus(err), err)
 		return
 	}
+	setETag(c, post.Version)
 	utils.Success(c, post)
 }
 
+// Bulk 批量操作文章, 返回每篇文章的处理结果
+func (p *PostController) Bulk(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request model.BulkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	report, err := p.postService.Bulk(user, &request)
+	if err != nil {
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, report)
+}
+
 // SortByViewCountDesc 按照阅读数降序排列文章
 func (a *PostController) SortByViewCountDesc(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "2"))
-	posts, total, err := a.postService.SortByViewCountDesc(page, pageSize)
+	posts, total, err := a.postService.SortByViewCountDesc(user, page, pageSize)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -150,8 +479,13 @@ func (a *PostController) SortByViewCountDesc(c *gin.Context) {
 
 // ListHotPosts 获取热门文章
 func (a *PostController) ListHotPosts(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
-	posts, err := a.postService.ListHotPosts(limit)
+	posts, err := a.postService.ListHotPosts(user, limit)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -161,8 +495,13 @@ func (a *PostController) ListHotPosts(c *gin.Context) {
 
 // ListRecentPosts 获取最近的文章
 func (a *PostController) ListRecentPosts(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
-	posts, err := a.postService.ListRecentPosts(limit)
+	posts, err := a.postService.ListRecentPosts(user, limit)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
 		return
@@ -175,15 +514,25 @@ func (a *PostController) Name() string {
 }
 
 func (a *PostController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/posts", a.Query)
 	api.GET("/posts/published", a.ListHasPublished)
 	api.GET("/posts/drafts", a.ListDrafts)
 	api.GET("/posts/hot", a.ListHotPosts)
 	api.GET("/posts/recent", a.ListRecentPosts)
 	api.GET("/posts/sort", a.SortByViewCountDesc)
+	api.POST("/posts/bulk", a.Bulk)
 	api.GET("/post/:id", a.GetPostByID)
+	api.GET("/post/:id/related", a.ListRelated)
+	api.POST("/post/:id/unlock", a.Unlock)
 	api.GET("/post/name/:name", a.GetPostByName)
 	api.POST("/post", a.Create)
 	api.DELETE("/post/:id", a.Delete)
 	api.PUT("/post/:id", a.Update)
+	api.PUT("/post/:id/autosave", a.Autosave)
 	api.PUT("/post/:id/status", a.UpdateStatus)
 }
+
+// RegisterPublicRoute 分享短链接跳转到免登录访问的文章地址
+func (a *PostController) RegisterPublicRoute(public *gin.RouterGroup) {
+	public.GET("/shared/posts/:id", a.GetShared)
+}
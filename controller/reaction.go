@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+)
+
+type ReactionController struct {
+	reactionService service.ReactionService
+}
+
+func NewReactionController(reactionService service.ReactionService) Controller {
+	return &ReactionController{
+		reactionService: reactionService,
+	}
+}
+
+// React 添加表情, kind 为 like、love、laugh、celebrate 或对应的表情字符
+func (r *ReactionController) React(target model.ReactionTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		counts, err := r.reactionService.React(user, target, c.Param("id"), c.Param("kind"), postGrant(c))
+		if err != nil {
+			utils.Error(c, reactionErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, counts)
+	}
+}
+
+// Unreact 取消表情
+func (r *ReactionController) Unreact(target model.ReactionTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		counts, err := r.reactionService.Unreact(user, target, c.Param("id"), c.Param("kind"), postGrant(c))
+		if err != nil {
+			utils.Error(c, reactionErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, counts)
+	}
+}
+
+// List 每种表情的数量, reacted 表示当前用户是否添加过
+func (r *ReactionController) List(target model.ReactionTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		counts, err := r.reactionService.List(user, target, c.Param("id"), postGrant(c))
+		if err != nil {
+			utils.Error(c, reactionErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, counts)
+	}
+}
+
+func reactionErrorStatus(err error) int {
+	if errors.Is(err, service.ErrReactionKind) {
+		return http.StatusBadRequest
+	}
+	return commentErrorStatus(err)
+}
+
+func (r *ReactionController) Name() string {
+	return "reactions"
+}
+
+func (r *ReactionController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/post/:id/reactions", r.List(model.ReactionPost))
+	api.POST("/post/:id/reactions/:kind", r.React(model.ReactionPost))
+	api.DELETE("/post/:id/reactions/:kind", r.Unreact(model.ReactionPost))
+	api.GET("/comment/:id/reactions", r.List(model.ReactionComment))
+	api.POST("/comment/:id/reactions/:kind", r.React(model.ReactionComment))
+	api.DELETE("/comment/:id/reactions/:kind", r.Unreact(model.ReactionComment))
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type RepostController struct {
+	repostService service.RepostService
+}
+
+func NewRepostController(repostService service.RepostService) Controller {
+	return &RepostController{
+		repostService: repostService,
+	}
+}
+
+type RepostRequest struct {
+	Title string `json:"title"` // 转载后的标题, 为空时沿用原文标题
+}
+
+type SourceRequest struct {
+	Url          string `json:"url" binding:"required,url"`
+	SourceTitle  string `json:"source_title"`
+	SourceAuthor string `json:"source_author" binding:"required"`
+}
+
+// Repost 转载站内文章
+func (r *RepostController) Repost(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request RepostRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	post, err := r.repostService.Repost(user, c.Param("id"), &model.Post{Title: request.Title})
+	if err != nil {
+		utils.Error(c, repostErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, post)
+}
+
+// SetSource 为自己的文章登记站外来源
+func (r *RepostController) SetSource(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	var request SourceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+
+	repost, err := r.repostService.SetSource(user, c.Param("id"), &model.Repost{
+		Url:          request.Url,
+		SourceTitle:  request.SourceTitle,
+		SourceAuthor: request.SourceAuthor,
+	})
+	if err != nil {
+		utils.Error(c, repostErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, repost)
+}
+
+// ListRequests 列出收到的转载授权申请, 默认只看待处理的
+func (r *RepostController) ListRequests(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	status := c.DefaultQuery("status", string(model.RepostPending))
+
+	reposts, total, err := r.repostService.ListRequests(user, status, page, pageSize)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.SuccessWithPage(c, reposts, total, page, pageSize)
+}
+
+// Approve 同意转载
+func (r *RepostController) Approve(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	repost, err := r.repostService.Approve(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, repostErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, repost)
+}
+
+// Reject 拒绝转载
+func (r *RepostController) Reject(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	repost, err := r.repostService.Reject(user, c.Param("id"))
+	if err != nil {
+		utils.Error(c, repostErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, repost)
+}
+
+func repostErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrRepostForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrRepostUnpublished):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (r *RepostController) Name() string {
+	return "reposts"
+}
+
+func (r *RepostController) RegisterRoute(api *gin.RouterGroup) {
+	api.POST("/post/:id/repost", r.Repost)
+	api.PUT("/post/:id/source", r.SetSource)
+	api.GET("/reposts/requests", r.ListRequests)
+	api.PUT("/repost/:id/approve", r.Approve)
+	api.PUT("/repost/:id/reject", r.Reject)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type ShareController struct {
+	shareService service.ShareService
+}
+
+func NewShareController(shareService service.ShareService) Controller {
+	return &ShareController{
+		shareService: shareService,
+	}
+}
+
+// Share 生成文章的分享短链接
+func (s *ShareController) Share(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	share, err := s.shareService.Share(user, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, err)
+			return
+		}
+		utils.Error(c, postErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, share)
+}
+
+// Redirect 短链接跳转, 免登录访问
+func (s *ShareController) Redirect(c *gin.Context) {
+	click := &model.ShareClick{
+		Referrer:  c.Request.Referer(),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	target, err := s.shareService.Resolve(c.Param("code"), click)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.Error(c, http.StatusNotFound, errors.New("短链接不存在"))
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// Stats 文章的分享统计, 仅作者可见
+func (s *ShareController) Stats(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	stats, err := s.shareService.Stats(user, c.Param("id"), days)
+	if err != nil {
+		if errors.Is(err, service.ErrShareForbidden) {
+			utils.Error(c, http.StatusForbidden, err)
+			return
+		}
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, stats)
+}
+
+func (s *ShareController) Name() string {
+	return "shares"
+}
+
+func (s *ShareController) RegisterRoute(api *gin.RouterGroup) {
+	api.POST("/post/:id/share", s.Share)
+	api.GET("/post/:id/share/stats", s.Stats)
+}
+
+func (s *ShareController) RegisterPublicRoute(public *gin.RouterGroup) {
+	public.GET("/s/:code", s.Redirect)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type SpamController struct {
+	spamService service.SpamService
+}
+
+func NewSpamController(spamService service.SpamService) Controller {
+	return &SpamController{
+		spamService: spamService,
+	}
+}
+
+// ListSpam 垃圾评论列表, 管理员可以从中找出误判的评论
+func (s *SpamController) ListSpam(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	comments, total, err := s.spamService.ListSpam(user, page, pageSize)
+	if err != nil {
+		utils.Error(c, moderationErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, comments, total, page, pageSize)
+}
+
+// MarkSpam 标记为垃圾评论
+func (s *SpamController) MarkSpam(c *gin.Context) {
+	s.mark(c, true)
+}
+
+// MarkHam 标记为正常评论, 评论会重新显示
+func (s *SpamController) MarkHam(c *gin.Context) {
+	s.mark(c, false)
+}
+
+func (s *SpamController) mark(c *gin.Context, isSpam bool) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	comment, err := s.spamService.Mark(user, c.Param("id"), isSpam)
+	if err != nil {
+		utils.Error(c, moderationErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, comment)
+}
+
+func (s *SpamController) Name() string {
+	return "spam"
+}
+
+func (s *SpamController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/comments/spam", s.ListSpam)
+	api.POST("/comment/:id/spam", s.MarkSpam)
+	api.POST("/comment/:id/not-spam", s.MarkHam)
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"html"
+	"inkgo/model"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"net/url"
+)
+
+type SubscriptionController struct {
+	subscriptionService service.SubscriptionService
+}
+
+func NewSubscriptionController(subscriptionService service.SubscriptionService) Controller {
+	return &SubscriptionController{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// Subscribe 订阅文章下的新评论, 或自己评论收到的回复, 新回复会合并后发送邮件通知
+func (s *SubscriptionController) Subscribe(target model.SubscriptionTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		if err := s.subscriptionService.Subscribe(user, target, c.Param("id"), postGrant(c)); err != nil {
+			utils.Error(c, subscriptionErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, nil)
+	}
+}
+
+// Unsubscribe 取消订阅
+func (s *SubscriptionController) Unsubscribe(target model.SubscriptionTarget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := utils.UserFromContext(c)
+		if !ok || user == nil {
+			utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+			return
+		}
+		if err := s.subscriptionService.Unsubscribe(user, target, c.Param("id")); err != nil {
+			utils.Error(c, subscriptionErrorStatus(err), err)
+			return
+		}
+		utils.Success(c, nil)
+	}
+}
+
+// List 当前用户的订阅
+func (s *SubscriptionController) List(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	subscriptions, err := s.subscriptionService.List(user)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.Success(c, subscriptions)
+}
+
+// ConfirmUnsubscribe 打开邮件中的退订链接时只显示确认页面, 避免邮件客户端预取链接时误退订
+func (s *SubscriptionController) ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if err := s.subscriptionService.VerifyUnsubscribeToken(token); err != nil {
+		utils.Error(c, subscriptionErrorStatus(err), err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(fmt.Sprintf(
+		`<html>
+	<head>
+		<title>退订回复通知</title>
+	</head>
+	<body>
+		<form method="post" action="?token=%s">
+			<p>确认不再接收这些回复的邮件通知?</p>
+			<button type="submit">确认退订</button>
+		</form>
+	</body>
+</html>`, html.EscapeString(url.QueryEscape(token)))))
+}
+
+// UnsubscribeByToken 确认页面提交的退订, 同时支持 List-Unsubscribe-Post 的一键退订
+func (s *SubscriptionController) UnsubscribeByToken(c *gin.Context) {
+	if err := s.subscriptionService.UnsubscribeByToken(c.Query("token")); err != nil {
+		utils.Error(c, subscriptionErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, gin.H{"message": "已退订"})
+}
+
+func subscriptionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrSubscriptionTarget), errors.Is(err, service.ErrUnsubscribeToken):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrSubscriptionNotOwner):
+		return http.StatusForbidden
+	default:
+		return commentErrorStatus(err)
+	}
+}
+
+func (s *SubscriptionController) Name() string {
+	return "subscriptions"
+}
+
+func (s *SubscriptionController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/subscriptions", s.List)
+	api.POST("/post/:id/subscription", s.Subscribe(model.SubscriptionPost))
+	api.DELETE("/post/:id/subscription", s.Unsubscribe(model.SubscriptionPost))
+	api.POST("/comment/:id/subscription", s.Subscribe(model.SubscriptionComment))
+	api.DELETE("/comment/:id/subscription", s.Unsubscribe(model.SubscriptionComment))
+}
+
+func (s *SubscriptionController) RegisterPublicRoute(public *gin.RouterGroup) {
+	public.GET("/unsubscribe", s.ConfirmUnsubscribe)
+	public.POST("/unsubscribe", s.UnsubscribeByToken)
+}
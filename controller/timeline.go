@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+)
+
+// 没有传 cursor 和 limit 时第一页的文章数
+const defaultTimelineLimit = 10
+
+type TimelineController struct {
+	timelineService service.TimelineService
+}
+
+func NewTimelineController(timelineService service.TimelineService) Controller {
+	return &TimelineController{
+		timelineService: timelineService,
+	}
+}
+
+// Timeline 首页时间线, 包含关注的作者、标签和分类下最近发布的文章, 只支持 cursor/limit 分页
+func (t *TimelineController) Timeline(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if !ok {
+		limit = defaultTimelineLimit
+	}
+	posts, next, err := t.timelineService.Timeline(user, cursor, limit)
+	if err != nil {
+		utils.Error(c, http.StatusInternalServerError, err)
+		return
+	}
+	utils.SuccessWithCursor(c, posts, next)
+}
+
+func (t *TimelineController) Name() string {
+	return "timeline"
+}
+
+func (t *TimelineController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/timeline", t.Timeline)
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"inkgo/service"
+	"inkgo/utils"
+	"net/http"
+	"strconv"
+)
+
+type TrashController struct {
+	trashService service.TrashService
+}
+
+func NewTrashController(trashService service.TrashService) Controller {
+	return &TrashController{
+		trashService: trashService,
+	}
+}
+
+// List 回收站列表, type 可选 post, comment, favorite; 管理员传入 all=true 查看所有人删除的内容
+func (t *TrashController) List(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	all, _ := strconv.ParseBool(c.DefaultQuery("all", "false"))
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	items, total, err := t.trashService.List(user, c.Query("type"), all, page, pageSize)
+	if err != nil {
+		utils.Error(c, trashErrorStatus(err), err)
+		return
+	}
+	utils.SuccessWithPage(c, items, total, page, pageSize)
+}
+
+// Restore 恢复已删除的内容
+func (t *TrashController) Restore(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := t.trashService.Restore(user, c.Param("type"), c.Param("id")); err != nil {
+		utils.Error(c, trashErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+// Purge 彻底删除, 不可恢复
+func (t *TrashController) Purge(c *gin.Context) {
+	user, ok := utils.UserFromContext(c)
+	if !ok || user == nil {
+		utils.Error(c, http.StatusUnauthorized, errors.New("未登录或 token 无效"))
+		return
+	}
+	if err := t.trashService.Purge(user, c.Param("type"), c.Param("id")); err != nil {
+		utils.Error(c, trashErrorStatus(err), err)
+		return
+	}
+	utils.Success(c, nil)
+}
+
+func trashErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTrashForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrTrashType), errors.Is(err, strconv.ErrSyntax):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrTrashConflict):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (t *TrashController) Name() string {
+	return "trash"
+}
+
+func (t *TrashController) RegisterRoute(api *gin.RouterGroup) {
+	api.GET("/trash", t.List)
+	api.POST("/trash/:type/:id/restore", t.Restore)
+	api.DELETE("/trash/:type/:id", t.Purge)
+}
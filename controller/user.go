@@ -48,6 +48,21 @@ func (u *UserController) List(c *gin.Context) {
 		return
 	}
 
+	cursor, limit, ok, err := utils.CursorQuery(c)
+	if err != nil {
+		utils.Error(c, http.StatusBadRequest, err)
+		return
+	}
+	if ok {
+		users, next, err := u.userService.ListByCursor(cursor, limit)
+		if err != nil {
+			utils.Error(c, http.StatusInternalServerError, err)
+			return
+		}
+		utils.SuccessWithCursor(c, users, next)
+		return
+	}
+
 	users, total, err := u.userService.List(pageSize, page)
 	if err != nil {
 		utils.Error(c, http.StatusInternalServerError, err)
@@ -29,7 +29,8 @@ func NewMysql(conf *config.DBConfig) (db *gorm.DB, err error) {
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true, // 使用单数表名
 		},
-		Logger: newLogger,
+		Logger:         newLogger,
+		TranslateError: true, // 将唯一索引冲突等错误转换为 gorm.ErrDuplicatedKey
 	})
 
 	//adapter := gormadapter.NewAdapterByDB(db)
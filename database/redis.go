@@ -90,3 +90,15 @@ func (r *RedisDB) Del(ctx context.Context, key string) error {
 	}
 	return r.Client.Del(ctx, key).Err()
 }
+
+// GetDel 获取并删除 key, key 不存在时返回空字符串
+func (r *RedisDB) GetDel(ctx context.Context, key string) (string, error) {
+	if !r.enable {
+		return "", RedisDisableError
+	}
+	val, err := r.Client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
@@ -0,0 +1,166 @@
+// Package exporter 将文章导出为 DOCX、PDF、EPUB 等离线格式
+package exporter
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatDOCX Format = "docx"
+	FormatPDF  Format = "pdf"
+	FormatEPUB Format = "epub"
+)
+
+var ErrUnsupportedFormat = errors.New("不支持的导出格式, 可选 docx、pdf、epub")
+
+// ParseFormat 校验导出格式
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatDOCX, FormatPDF, FormatEPUB:
+		return f, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// ContentType 导出文件的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatDOCX:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatEPUB:
+		return "application/epub+zip"
+	}
+	return "application/octet-stream"
+}
+
+// Book 一次导出的内容, 单篇文章导出时只有一篇 Article
+type Book struct {
+	Title    string
+	Author   string
+	Articles []*Article
+}
+
+// Article 待导出的一篇文章
+type Article struct {
+	Title      string
+	Author     string
+	Date       time.Time
+	Updated    time.Time
+	Tags       []string
+	Categories []string
+	Source     string // 转载文章的原文链接
+	Blocks     []Block
+}
+
+// BlockKind 正文块的类型
+type BlockKind int
+
+const (
+	BlockParagraph BlockKind = iota
+	BlockHeading
+	BlockCode
+	BlockImage
+	BlockQuote
+	BlockListItem
+	BlockRule
+)
+
+// Block 正文中的一个块级元素
+type Block struct {
+	Kind    BlockKind
+	Level   int    // 标题级别, 或列表的嵌套层级(从 0 开始)
+	Runs    []Run  // 段落、标题、引用、列表项的行内文本
+	Code    string // 代码块内容
+	Lang    string // 代码块语言
+	Image   *Image
+	Ordered bool // 有序列表
+	Index   int  // 有序列表的序号
+}
+
+// Run 一段样式相同的行内文本
+type Run struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Code   bool
+	Link   string
+}
+
+// Image 正文中的图片, Data 为空时以替代文字和链接代替
+type Image struct {
+	Src       string
+	Alt       string
+	Data      []byte
+	MediaType string // image/png, image/jpeg, image/gif
+	Width     int    // 像素
+	Height    int
+}
+
+// Options 导出选项
+type Options struct {
+	FontPath string // PDF 使用的 TrueType 字体, 中文内容必须配置
+}
+
+// Write 按 format 将 book 写入 w
+func Write(w io.Writer, format Format, book *Book, opts Options) error {
+	switch format {
+	case FormatDOCX:
+		return WriteDOCX(w, book)
+	case FormatPDF:
+		return WritePDF(w, book, opts)
+	case FormatEPUB:
+		return WriteEPUB(w, book)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// plainText 拼接行内文本
+func plainText(runs []Run) string {
+	var b strings.Builder
+	for _, r := range runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+// metaLine 文章标题下方的元信息
+func metaLine(a *Article) string {
+	line := ""
+	add := func(s string) {
+		if s == "" {
+			return
+		}
+		if line != "" {
+			line += "  ·  "
+		}
+		line += s
+	}
+	add(a.Author)
+	if !a.Date.IsZero() {
+		add(a.Date.Format("2006-01-02 15:04"))
+	}
+	if len(a.Categories) > 0 {
+		add("分类: " + strings.Join(a.Categories, ", "))
+	}
+	if len(a.Tags) > 0 {
+		add("标签: " + strings.Join(a.Tags, ", "))
+	}
+	return line
+}
+
+// xmlEscape 转义 XML 文本, 非法字符会被替换
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
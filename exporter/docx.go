@@ -0,0 +1,299 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// 每像素对应的 EMU (Office 的长度单位), 按 96 DPI 计算
+const emuPerPixel = 9525
+
+// 正文区域宽度, 约 6 英寸
+const docxMaxImageWidth = 576
+
+// WriteDOCX 生成 Word 文档, 每篇文章从新的一页开始.
+// 直接按 OOXML 写出 zip 包, 没有使用 unioffice: 它的 v1.21 起保存文档需要商业授权, 未授权时 Save 返回错误
+func WriteDOCX(w io.Writer, book *Book) error {
+	d := &docxWriter{}
+	for i, article := range book.Articles {
+		d.article(article, i > 0)
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"docProps/core.xml", docxCore(book)},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", docxDocumentHeader + d.body.String() + docxDocumentFooter},
+		{"word/_rels/document.xml.rels", d.rels()},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	for i, img := range d.images {
+		fw, err := zw.Create(fmt.Sprintf("word/media/image%d.%s", i+1, img.ext()))
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(img.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+type docxWriter struct {
+	body   strings.Builder
+	images []*Image
+	links  []string
+}
+
+func (d *docxWriter) article(a *Article, pageBreak bool) {
+	if pageBreak {
+		d.body.WriteString(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`)
+	}
+	d.paragraph("Title", "", []Run{{Text: a.Title}})
+	if line := metaLine(a); line != "" {
+		d.paragraph("Subtitle", "", []Run{{Text: line}})
+	}
+	if a.Source != "" {
+		d.paragraph("Subtitle", "", []Run{{Text: "原文: "}, {Text: a.Source, Link: a.Source}})
+	}
+	for _, b := range a.Blocks {
+		switch b.Kind {
+		case BlockHeading:
+			level := b.Level
+			if level > 6 {
+				level = 6
+			}
+			d.paragraph(fmt.Sprintf("Heading%d", level), "", b.Runs)
+		case BlockCode:
+			d.code(b.Code)
+		case BlockImage:
+			d.image(b.Image)
+		case BlockQuote:
+			d.paragraph("Quote", "", b.Runs)
+		case BlockListItem:
+			bullet := "• "
+			if b.Ordered {
+				bullet = fmt.Sprintf("%d. ", b.Index)
+			}
+			d.paragraph("ListParagraph", indent(b.Level+1), append([]Run{{Text: bullet}}, b.Runs...))
+		case BlockRule:
+			d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="auto"/></w:pBdr></w:pPr></w:p>`)
+		default:
+			d.paragraph("", indent(b.Level), b.Runs)
+		}
+	}
+}
+
+func indent(level int) string {
+	if level == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`<w:ind w:left="%d"/>`, level*420)
+}
+
+func (d *docxWriter) paragraph(style, ppr string, runs []Run) {
+	d.body.WriteString("<w:p>")
+	if style != "" || ppr != "" {
+		d.body.WriteString("<w:pPr>")
+		if style != "" {
+			fmt.Fprintf(&d.body, `<w:pStyle w:val="%s"/>`, style)
+		}
+		d.body.WriteString(ppr)
+		d.body.WriteString("</w:pPr>")
+	}
+	for _, r := range runs {
+		if r.Link != "" {
+			d.links = append(d.links, r.Link)
+			fmt.Fprintf(&d.body, `<w:hyperlink r:id="rIdLink%d">`, len(d.links))
+			d.run(r)
+			d.body.WriteString("</w:hyperlink>")
+			continue
+		}
+		d.run(r)
+	}
+	d.body.WriteString("</w:p>")
+}
+
+func (d *docxWriter) run(r Run) {
+	d.body.WriteString("<w:r>")
+	if r.Bold || r.Italic || r.Code || r.Link != "" {
+		d.body.WriteString("<w:rPr>")
+		if r.Link != "" {
+			d.body.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		} else if r.Code {
+			d.body.WriteString(`<w:rStyle w:val="CodeChar"/>`)
+		}
+		if r.Bold {
+			d.body.WriteString("<w:b/>")
+		}
+		if r.Italic {
+			d.body.WriteString("<w:i/>")
+		}
+		d.body.WriteString("</w:rPr>")
+	}
+	d.text(r.Text)
+	d.body.WriteString("</w:r>")
+}
+
+// text 写入文本, 换行转换为 <w:br/>
+func (d *docxWriter) text(s string) {
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			d.body.WriteString("<w:br/>")
+		}
+		d.body.WriteString(`<w:t xml:space="preserve">`)
+		d.body.WriteString(xmlEscape(line))
+		d.body.WriteString("</w:t>")
+	}
+}
+
+func (d *docxWriter) code(code string) {
+	d.body.WriteString(`<w:p><w:pPr><w:pStyle w:val="Code"/></w:pPr><w:r>`)
+	d.text(strings.ReplaceAll(code, "\t", "    "))
+	d.body.WriteString("</w:r></w:p>")
+}
+
+func (d *docxWriter) image(img *Image) {
+	if img.Data == nil {
+		alt := img.Alt
+		if alt == "" {
+			alt = "图片"
+		}
+		d.paragraph("", "", []Run{{Text: "[" + alt + "] "}, {Text: img.Src, Link: img.Src}})
+		return
+	}
+	d.images = append(d.images, img)
+	id := len(d.images)
+	w, h := img.fit(docxMaxImageWidth)
+	cx, cy := int64(w*emuPerPixel), int64(h*emuPerPixel)
+	fmt.Fprintf(&d.body, `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:drawing>`+
+		`<wp:inline distT="0" distB="0" distL="0" distR="0"><wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="Picture %[3]d" descr="%[4]s"/>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:nvPicPr><pic:cNvPr id="%[3]d" name="image%[3]d"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="rIdImage%[3]d"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr></pic:pic>`+
+		`</a:graphicData></a:graphic></wp:inline></w:drawing></w:r></w:p>`, cx, cy, id, xmlEscape(img.Alt))
+	if img.Alt != "" {
+		d.paragraph("Caption", "", []Run{{Text: img.Alt}})
+	}
+}
+
+func (d *docxWriter) rels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	b.WriteString(`<Relationship Id="rIdStyles" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	for i, img := range d.images {
+		fmt.Fprintf(&b, `<Relationship Id="rIdImage%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image%d.%s"/>`,
+			i+1, i+1, img.ext())
+	}
+	for i, link := range d.links {
+		fmt.Fprintf(&b, `<Relationship Id="rIdLink%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="%s" TargetMode="External"/>`,
+			i+1, xmlEscape(link))
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+var docxCoreTemplate = template.Must(template.New("core").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(xml.Header +
+	`<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" ` +
+	`xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+	`<dc:title>{{xml .Title}}</dc:title><dc:creator>{{xml .Author}}</dc:creator><cp:keywords>{{xml .Keywords}}</cp:keywords>` +
+	`<dcterms:created xsi:type="dcterms:W3CDTF">{{.Created}}</dcterms:created><dcterms:modified xsi:type="dcterms:W3CDTF">{{.Modified}}</dcterms:modified>` +
+	`</cp:coreProperties>`))
+
+func docxCore(book *Book) string {
+	created, modified := time.Now(), time.Now()
+	var keywords []string
+	if len(book.Articles) == 1 {
+		a := book.Articles[0]
+		if !a.Date.IsZero() {
+			created = a.Date
+		}
+		if !a.Updated.IsZero() {
+			modified = a.Updated
+		}
+		keywords = append(append(keywords, a.Categories...), a.Tags...)
+	}
+	var b strings.Builder
+	docxCoreTemplate.Execute(&b, map[string]string{
+		"Title":    book.Title,
+		"Author":   book.Author,
+		"Keywords": strings.Join(keywords, ", "),
+		"Created":  created.UTC().Format(time.RFC3339),
+		"Modified": modified.UTC().Format(time.RFC3339),
+	})
+	return b.String()
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/><Default Extension="jpg" ContentType="image/jpeg"/><Default Extension="gif" ContentType="image/gif"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>` +
+	`</Types>`
+
+const docxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>` +
+	`</Relationships>`
+
+const docxDocumentHeader = xml.Header + `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" ` +
+	`xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"><w:body>`
+
+const docxDocumentFooter = `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+	`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="851" w:footer="992" w:gutter="0"/></w:sectPr></w:body></w:document>`
+
+// 标题、代码、引用等样式, 中文使用宋体/黑体, 代码使用 Consolas
+const docxStyles = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="SimSun"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="300" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:spacing w:after="80"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:rFonts w:eastAsia="SimHei"/><w:b/><w:sz w:val="40"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Subtitle"><w:name w:val="Subtitle"/><w:basedOn w:val="Normal"/><w:rPr><w:color w:val="808080"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="360"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:rFonts w:eastAsia="SimHei"/><w:b/><w:sz w:val="32"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="280"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:rFonts w:eastAsia="SimHei"/><w:b/><w:sz w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:rFonts w:eastAsia="SimHei"/><w:b/><w:sz w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:outlineLvl w:val="4"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading5"><w:name w:val="heading 5"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:outlineLvl w:val="5"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading6"><w:name w:val="heading 6"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:outlineLvl w:val="6"/></w:pPr><w:rPr><w:b/><w:i/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F5F5F5"/><w:spacing w:after="120" w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="CodeChar"><w:name w:val="Code Char"/><w:rPr><w:rFonts w:ascii="Consolas" w:hAnsi="Consolas" w:cs="Consolas"/>` +
+	`<w:shd w:val="clear" w:color="auto" w:fill="F5F5F5"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="CCCCCC"/></w:pBdr><w:ind w:left="360"/></w:pPr><w:rPr><w:color w:val="595959"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="60"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Caption"><w:name w:val="caption"/><w:basedOn w:val="Normal"/><w:pPr><w:jc w:val="center"/></w:pPr>` +
+	`<w:rPr><w:color w:val="808080"/><w:sz w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`</w:styles>`
@@ -0,0 +1,276 @@
+package exporter
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteEPUB 生成 EPUB 3 电子书, 每篇文章为一章, 同时提供 EPUB 2 的 toc.ncx 以兼容旧阅读器
+func WriteEPUB(w io.Writer, book *Book) error {
+	e := &epubWriter{images: make(map[*Image]string)}
+	chapters := make([]string, len(book.Articles))
+	for i, article := range book.Articles {
+		chapters[i] = e.chapter(article)
+	}
+
+	zw := zip.NewWriter(w)
+	// mimetype 必须是第一个文件且不压缩
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mw, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", e.opf(book)},
+		{"OEBPS/nav.xhtml", e.nav(book)},
+		{"OEBPS/toc.ncx", e.ncx(book)},
+		{"OEBPS/style.css", epubStyle},
+	}
+	for i, chapter := range chapters {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("OEBPS/chapter-%d.xhtml", i+1), chapter})
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	for _, img := range e.order {
+		fw, err := zw.Create("OEBPS/" + e.images[img])
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(img.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+type epubWriter struct {
+	images map[*Image]string // 图片在 OEBPS 中的路径
+	order  []*Image
+}
+
+func (e *epubWriter) chapter(a *Article) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE html><html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="zh-CN">`)
+	fmt.Fprintf(&b, `<head><meta charset="utf-8"/><title>%s</title><link rel="stylesheet" type="text/css" href="style.css"/></head><body>`, xmlEscape(a.Title))
+	fmt.Fprintf(&b, `<h1 class="title">%s</h1>`, xmlEscape(a.Title))
+	if line := metaLine(a); line != "" {
+		fmt.Fprintf(&b, `<p class="meta">%s</p>`, xmlEscape(line))
+	}
+	if a.Source != "" {
+		fmt.Fprintf(&b, `<p class="meta">原文: <a href="%[1]s">%[1]s</a></p>`, xmlEscape(a.Source))
+	}
+
+	// 列表项按层级拼成嵌套的 <ul>/<ol>
+	var lists []string
+	closeLists := func(depth int) {
+		for len(lists) > depth {
+			fmt.Fprintf(&b, "</%s>", lists[len(lists)-1])
+			lists = lists[:len(lists)-1]
+		}
+	}
+	for _, block := range a.Blocks {
+		if block.Kind != BlockListItem {
+			closeLists(0)
+		}
+		switch block.Kind {
+		case BlockHeading:
+			// 文章标题占用 h1, 正文标题依次下移一级
+			level := block.Level + 1
+			if level > 6 {
+				level = 6
+			}
+			fmt.Fprintf(&b, "<h%d>%s</h%d>", level, epubRuns(block.Runs), level)
+		case BlockCode:
+			class := ""
+			if block.Lang != "" {
+				class = fmt.Sprintf(` class="language-%s"`, xmlEscape(block.Lang))
+			}
+			fmt.Fprintf(&b, "<pre><code%s>%s</code></pre>", class, xmlEscape(block.Code))
+		case BlockImage:
+			b.WriteString(e.image(block.Image))
+		case BlockQuote:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>", epubRuns(block.Runs))
+		case BlockListItem:
+			tag := "ul"
+			if block.Ordered {
+				tag = "ol"
+			}
+			closeLists(block.Level + 1)
+			for len(lists) <= block.Level {
+				lists = append(lists, tag)
+				if tag == "ol" {
+					fmt.Fprintf(&b, `<ol start="%d">`, block.Index)
+				} else {
+					b.WriteString("<ul>")
+				}
+			}
+			fmt.Fprintf(&b, "<li>%s</li>", epubRuns(block.Runs))
+		case BlockRule:
+			b.WriteString("<hr/>")
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>", epubRuns(block.Runs))
+		}
+	}
+	closeLists(0)
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func epubRuns(runs []Run) string {
+	var b strings.Builder
+	for _, r := range runs {
+		s := strings.ReplaceAll(xmlEscape(r.Text), "\n", "<br/>")
+		if r.Code {
+			s = "<code>" + s + "</code>"
+		}
+		if r.Italic {
+			s = "<em>" + s + "</em>"
+		}
+		if r.Bold {
+			s = "<strong>" + s + "</strong>"
+		}
+		if r.Link != "" {
+			s = fmt.Sprintf(`<a href="%s">%s</a>`, xmlEscape(r.Link), s)
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func (e *epubWriter) image(img *Image) string {
+	if img.Data == nil {
+		alt := img.Alt
+		if alt == "" {
+			alt = "图片"
+		}
+		return fmt.Sprintf(`<p>[%s] <a href="%[2]s">%[2]s</a></p>`, xmlEscape(alt), xmlEscape(img.Src))
+	}
+	name, ok := e.images[img]
+	if !ok {
+		name = fmt.Sprintf("images/image-%d.%s", len(e.order)+1, img.ext())
+		e.images[img] = name
+		e.order = append(e.order, img)
+	}
+	caption := ""
+	if img.Alt != "" {
+		caption = fmt.Sprintf("<figcaption>%s</figcaption>", xmlEscape(img.Alt))
+	}
+	return fmt.Sprintf(`<figure><img src="%s" alt="%s"/>%s</figure>`, name, xmlEscape(img.Alt), caption)
+}
+
+// identifier 根据书名和文章生成稳定的书籍标识
+func (e *epubWriter) identifier(book *Book) string {
+	h := sha1.New()
+	io.WriteString(h, book.Title+"\x00"+book.Author)
+	for _, a := range book.Articles {
+		io.WriteString(h, "\x00"+a.Title)
+	}
+	sum := h.Sum(nil)
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func (e *epubWriter) opf(book *Book) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="zh-CN">`)
+	b.WriteString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">`)
+	fmt.Fprintf(&b, `<dc:identifier id="book-id">%s</dc:identifier>`, e.identifier(book))
+	fmt.Fprintf(&b, `<dc:title>%s</dc:title><dc:creator>%s</dc:creator><dc:language>zh-CN</dc:language>`,
+		xmlEscape(book.Title), xmlEscape(book.Author))
+	modified := time.Now()
+	if len(book.Articles) == 1 {
+		a := book.Articles[0]
+		if !a.Date.IsZero() {
+			fmt.Fprintf(&b, `<dc:date>%s</dc:date>`, a.Date.UTC().Format(time.RFC3339))
+		}
+		if !a.Updated.IsZero() {
+			modified = a.Updated
+		}
+		for _, subject := range append(append([]string{}, a.Categories...), a.Tags...) {
+			fmt.Fprintf(&b, `<dc:subject>%s</dc:subject>`, xmlEscape(subject))
+		}
+		if a.Source != "" {
+			fmt.Fprintf(&b, `<dc:source>%s</dc:source>`, xmlEscape(a.Source))
+		}
+	}
+	fmt.Fprintf(&b, `<meta property="dcterms:modified">%s</meta>`, modified.UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString(`</metadata><manifest>`)
+	b.WriteString(`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`)
+	b.WriteString(`<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>`)
+	b.WriteString(`<item id="style" href="style.css" media-type="text/css"/>`)
+	for i := range book.Articles {
+		fmt.Fprintf(&b, `<item id="chapter-%d" href="chapter-%d.xhtml" media-type="application/xhtml+xml"/>`, i+1, i+1)
+	}
+	for i, img := range e.order {
+		fmt.Fprintf(&b, `<item id="image-%d" href="%s" media-type="%s"/>`, i+1, e.images[img], img.MediaType)
+	}
+	b.WriteString(`</manifest><spine toc="ncx">`)
+	for i := range book.Articles {
+		fmt.Fprintf(&b, `<itemref idref="chapter-%d"/>`, i+1)
+	}
+	b.WriteString(`</spine></package>`)
+	return b.String()
+}
+
+func (e *epubWriter) nav(book *Book) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE html><html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="zh-CN">`)
+	fmt.Fprintf(&b, `<head><meta charset="utf-8"/><title>%s</title></head><body><nav epub:type="toc" id="toc"><h1>目录</h1><ol>`, xmlEscape(book.Title))
+	for i, a := range book.Articles {
+		fmt.Fprintf(&b, `<li><a href="chapter-%d.xhtml">%s</a></li>`, i+1, xmlEscape(a.Title))
+	}
+	b.WriteString(`</ol></nav></body></html>`)
+	return b.String()
+}
+
+func (e *epubWriter) ncx(book *Book) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">`)
+	fmt.Fprintf(&b, `<head><meta name="dtb:uid" content="%s"/></head><docTitle><text>%s</text></docTitle><navMap>`,
+		e.identifier(book), xmlEscape(book.Title))
+	for i, a := range book.Articles {
+		fmt.Fprintf(&b, `<navPoint id="nav-%[1]d" playOrder="%[1]d"><navLabel><text>%[2]s</text></navLabel><content src="chapter-%[1]d.xhtml"/></navPoint>`,
+			i+1, xmlEscape(a.Title))
+	}
+	b.WriteString(`</navMap></ncx>`)
+	return b.String()
+}
+
+const epubContainer = xml.Header + `<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+	`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`
+
+const epubStyle = `body { font-family: serif; line-height: 1.6; }
+h1.title { margin-bottom: 0.2em; }
+p.meta { color: #808080; font-size: 0.85em; margin-top: 0; }
+pre { background: #f5f5f5; padding: 0.6em; white-space: pre-wrap; word-wrap: break-word; font-size: 0.85em; }
+code { font-family: monospace; }
+blockquote { border-left: 4px solid #ccc; margin-left: 0; padding-left: 1em; color: #595959; }
+figure { text-align: center; margin: 1em 0; }
+figure img { max-width: 100%; }
+figcaption { color: #808080; font-size: 0.85em; }
+`
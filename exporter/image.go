@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 单张图片的最大字节数, 超过时不嵌入
+const maxImageSize = 10 << 20
+
+// ImageLoader 下载正文中的图片
+type ImageLoader interface {
+	Load(ctx context.Context, src string) ([]byte, error)
+}
+
+// httpImageLoader 只下载白名单域名下的图片, 避免导出时访问内网地址
+type httpImageLoader struct {
+	client *http.Client
+	hosts  map[string]bool
+}
+
+// NewHTTPImageLoader 创建图片下载器, hosts 为允许下载的域名, timeout 为单张图片的超时时间
+func NewHTTPImageLoader(hosts []string, timeout time.Duration) ImageLoader {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		allowed[strings.ToLower(host)] = true
+	}
+	return &httpImageLoader{
+		client: &http.Client{Timeout: timeout},
+		hosts:  allowed,
+	}
+}
+
+func (l *httpImageLoader) Load(ctx context.Context, src string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported image url %s", src)
+	}
+	if !l.hosts[strings.ToLower(u.Host)] {
+		return nil, fmt.Errorf("image host %s is not allowed", u.Host)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image %s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("image %s is too large", src)
+	}
+	return data, nil
+}
+
+// LoadImages 下载并识别 book 中的所有图片, 失败或格式不支持的图片保留为链接
+func LoadImages(ctx context.Context, book *Book, loader ImageLoader) {
+	cache := make(map[string]*Image)
+	for _, article := range book.Articles {
+		for i := range article.Blocks {
+			img := article.Blocks[i].Image
+			if img == nil || img.Src == "" {
+				continue
+			}
+			if cached, ok := cache[img.Src]; ok {
+				img.Data, img.MediaType, img.Width, img.Height = cached.Data, cached.MediaType, cached.Width, cached.Height
+				continue
+			}
+			cache[img.Src] = img
+			data, err := loader.Load(ctx, img.Src)
+			if err != nil {
+				continue
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			img.Data, img.MediaType, img.Width, img.Height = data, "image/"+format, cfg.Width, cfg.Height
+		}
+	}
+}
+
+// ext 图片在压缩包中的扩展名
+func (img *Image) ext() string {
+	switch img.MediaType {
+	case "image/jpeg":
+		return "jpg"
+	case "image/gif":
+		return "gif"
+	}
+	return "png"
+}
+
+// fit 按最大宽度等比缩放图片尺寸
+func (img *Image) fit(maxWidth float64) (float64, float64) {
+	w, h := float64(img.Width), float64(img.Height)
+	if w <= 0 || h <= 0 {
+		return maxWidth, maxWidth * 0.75
+	}
+	if w > maxWidth {
+		h = h * maxWidth / w
+		w = maxWidth
+	}
+	return w, h
+}
@@ -0,0 +1,208 @@
+package exporter
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gtext "github.com/yuin/goldmark/text"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// 导入的 WordPress 文章正文是 HTML, 按块级标签拆分段落
+	htmlBlockBreak = regexp.MustCompile(`(?i)</?(p|div|h[1-6]|li|ul|ol|blockquote|pre|table|tr)\b[^>]*>|<br\s*/?>`)
+	htmlImage      = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	htmlAttr       = regexp.MustCompile(`(?i)\b(src|alt)\s*=\s*("[^"]*"|'[^']*')`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseMarkdown 将 Markdown 正文转换为块级元素, 行内 HTML 标签会被忽略
+func ParseMarkdown(source string) []Block {
+	src := []byte(source)
+	doc := goldmark.New().Parser().Parse(gtext.NewReader(src))
+	p := &mdParser{src: src}
+	p.blocks(doc, 0)
+	return p.out
+}
+
+type mdParser struct {
+	src []byte
+	out []Block
+}
+
+func (p *mdParser) blocks(parent ast.Node, depth int) {
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Heading:
+			p.inline(n, Block{Kind: BlockHeading, Level: n.Level})
+		case *ast.Paragraph, *ast.TextBlock:
+			p.inline(n, Block{Kind: BlockParagraph})
+		case *ast.FencedCodeBlock:
+			p.out = append(p.out, Block{Kind: BlockCode, Code: p.lines(n), Lang: string(n.Language(p.src))})
+		case *ast.CodeBlock:
+			p.out = append(p.out, Block{Kind: BlockCode, Code: p.lines(n)})
+		case *ast.Blockquote:
+			start := len(p.out)
+			p.blocks(n, depth)
+			for i := start; i < len(p.out); i++ {
+				if p.out[i].Kind == BlockParagraph {
+					p.out[i].Kind = BlockQuote
+				}
+			}
+		case *ast.List:
+			p.list(n, depth)
+		case *ast.ThematicBreak:
+			p.out = append(p.out, Block{Kind: BlockRule})
+		case *ast.HTMLBlock:
+			p.out = append(p.out, ParseHTML(p.lines(n))...)
+		default:
+			p.blocks(n, depth)
+		}
+	}
+}
+
+func (p *mdParser) list(list *ast.List, depth int) {
+	index := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		first := true
+		for n := item.FirstChild(); n != nil; n = n.NextSibling() {
+			switch n := n.(type) {
+			case *ast.Paragraph, *ast.TextBlock:
+				block := Block{Kind: BlockParagraph, Level: depth}
+				// 列表项的第一段带项目符号, 其余段落只缩进
+				if first {
+					block = Block{Kind: BlockListItem, Level: depth, Ordered: list.IsOrdered(), Index: index}
+				}
+				p.inline(n, block)
+			case *ast.List:
+				p.list(n, depth+1)
+			default:
+				p.blocks(n, depth+1)
+			}
+			first = false
+		}
+		index++
+	}
+}
+
+func (p *mdParser) lines(n ast.Node) string {
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		b.Write(line.Value(p.src))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// inline 收集行内文本, 遇到图片时拆成 文字-图片-文字 三个块
+func (p *mdParser) inline(n ast.Node, block Block) {
+	var runs []Run
+	flush := func() {
+		if strings.TrimSpace(plainText(runs)) != "" {
+			b := block
+			b.Runs = runs
+			p.out = append(p.out, b)
+		}
+		runs = nil
+	}
+	var walk func(n ast.Node, style Run)
+	walk = func(n ast.Node, style Run) {
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c := c.(type) {
+			case *ast.Text:
+				r := style
+				r.Text = string(c.Segment.Value(p.src))
+				if c.HardLineBreak() {
+					r.Text += "\n"
+				} else if c.SoftLineBreak() {
+					r.Text += " "
+				}
+				runs = append(runs, r)
+			case *ast.String:
+				r := style
+				r.Text = string(c.Value)
+				runs = append(runs, r)
+			case *ast.Emphasis:
+				s := style
+				if c.Level >= 2 {
+					s.Bold = true
+				} else {
+					s.Italic = true
+				}
+				walk(c, s)
+			case *ast.CodeSpan:
+				s := style
+				s.Code = true
+				walk(c, s)
+			case *ast.Link:
+				s := style
+				s.Link = string(c.Destination)
+				walk(c, s)
+			case *ast.AutoLink:
+				r := style
+				r.Text = string(c.Label(p.src))
+				r.Link = string(c.URL(p.src))
+				runs = append(runs, r)
+			case *ast.Image:
+				flush()
+				var alt []Run
+				saved := runs
+				walk(c, Run{})
+				alt, runs = runs, saved
+				p.out = append(p.out, Block{Kind: BlockImage, Image: &Image{Src: string(c.Destination), Alt: plainText(alt)}})
+			case *ast.RawHTML:
+				// 行内 HTML 只保留其中的文字和 <img> 图片
+				var raw strings.Builder
+				for i := 0; i < c.Segments.Len(); i++ {
+					seg := c.Segments.At(i)
+					raw.Write(seg.Value(p.src))
+				}
+				if htmlImage.MatchString(raw.String()) {
+					flush()
+					p.out = append(p.out, ParseHTML(raw.String())...)
+				}
+			default:
+				walk(c, style)
+			}
+		}
+	}
+	walk(n, Run{})
+	flush()
+}
+
+// ParseHTML 粗略解析 HTML 正文, 保留段落和图片
+func ParseHTML(source string) []Block {
+	var blocks []Block
+	for _, part := range htmlBlockBreak.Split(source, -1) {
+		locs := htmlImage.FindAllStringIndex(part, -1)
+		last := 0
+		for _, loc := range locs {
+			blocks = appendHTMLText(blocks, part[last:loc[0]])
+			img := &Image{}
+			for _, m := range htmlAttr.FindAllStringSubmatch(part[loc[0]:loc[1]], -1) {
+				value := html.UnescapeString(strings.Trim(m[2], `"'`))
+				if strings.EqualFold(m[1], "src") {
+					img.Src = value
+				} else {
+					img.Alt = value
+				}
+			}
+			if img.Src != "" {
+				blocks = append(blocks, Block{Kind: BlockImage, Image: img})
+			}
+			last = loc[1]
+		}
+		blocks = appendHTMLText(blocks, part[last:])
+	}
+	return blocks
+}
+
+func appendHTMLText(blocks []Block, s string) []Block {
+	s = strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(s, "")))
+	if s == "" {
+		return blocks
+	}
+	return append(blocks, Block{Kind: BlockParagraph, Runs: []Run{{Text: s}}})
+}
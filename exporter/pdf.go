@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 像素转毫米, 按 96 DPI 计算
+const mmPerPixel = 25.4 / 96
+
+// 各级标题的字号
+var pdfHeadingSizes = []float64{0, 18, 16, 14, 13, 12, 11}
+
+// WritePDF 生成 PDF, 标题会写入书签. 未配置 FontPath 时使用内置字体, 只能显示西文字符
+func WritePDF(w io.Writer, book *Book, opts Options) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	p := &pdfWriter{pdf: pdf, tr: func(s string) string { return s }}
+	if opts.FontPath != "" {
+		font, err := os.ReadFile(opts.FontPath)
+		if err != nil {
+			return fmt.Errorf("load pdf font: %w", err)
+		}
+		// 中文字体通常没有单独的粗体、斜体文件, 各样式共用同一个字体
+		for _, style := range []string{"", "B", "I", "BI"} {
+			pdf.AddUTF8FontFromBytes("body", style, font)
+		}
+		if err := pdf.Error(); err != nil {
+			return fmt.Errorf("load pdf font %s: %w", opts.FontPath, err)
+		}
+		p.font, p.mono = "body", "body"
+	} else {
+		p.font, p.mono = "Helvetica", "Courier"
+		p.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	pdf.SetTitle(book.Title, true)
+	pdf.SetAuthor(book.Author, true)
+	pdf.SetCreator("inkgo", false)
+	pdf.SetCreationDate(time.Now())
+	if len(book.Articles) == 1 {
+		a := book.Articles[0]
+		pdf.SetSubject(strings.Join(a.Categories, ", "), true)
+		pdf.SetKeywords(strings.Join(a.Tags, ", "), true)
+		if !a.Date.IsZero() {
+			pdf.SetCreationDate(a.Date)
+		}
+		if !a.Updated.IsZero() {
+			pdf.SetModificationDate(a.Updated)
+		}
+	}
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(p.font, "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 10, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	for _, article := range book.Articles {
+		p.article(article)
+	}
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+type pdfWriter struct {
+	pdf   *fpdf.Fpdf
+	font  string
+	mono  string
+	tr    func(string) string
+	image int
+	level int // 上一个书签的层级, 书签层级不能跳级
+}
+
+func (p *pdfWriter) article(a *Article) {
+	pdf := p.pdf
+	pdf.AddPage()
+	pdf.SetFont(p.font, "B", 20)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Bookmark(p.tr(a.Title), 0, -1)
+	p.level = 0
+	pdf.MultiCell(0, 10, p.tr(a.Title), "", "L", false)
+	pdf.SetFont(p.font, "", 9)
+	pdf.SetTextColor(128, 128, 128)
+	if line := metaLine(a); line != "" {
+		pdf.MultiCell(0, 5, p.tr(line), "", "L", false)
+	}
+	if a.Source != "" {
+		pdf.Write(5, p.tr("原文: "))
+		pdf.WriteLinkString(5, p.tr(a.Source), a.Source)
+		pdf.Ln(5)
+	}
+	pdf.Ln(4)
+
+	for _, b := range a.Blocks {
+		pdf.SetTextColor(0, 0, 0)
+		switch b.Kind {
+		case BlockHeading:
+			level := b.Level
+			if level > 6 {
+				level = 6
+			}
+			pdf.Ln(2)
+			pdf.SetFont(p.font, "B", pdfHeadingSizes[level])
+			p.bookmark(plainText(b.Runs), level)
+			pdf.MultiCell(0, pdfHeadingSizes[level]*0.5, p.tr(plainText(b.Runs)), "", "L", false)
+			pdf.Ln(1)
+		case BlockCode:
+			pdf.SetFont(p.mono, "", 9)
+			pdf.SetFillColor(245, 245, 245)
+			pdf.MultiCell(0, 4.5, p.tr(strings.ReplaceAll(b.Code, "\t", "    ")), "", "L", true)
+			pdf.Ln(3)
+		case BlockImage:
+			p.imageBlock(b.Image)
+		case BlockQuote:
+			left, _, _, _ := pdf.GetMargins()
+			pdf.SetTextColor(89, 89, 89)
+			p.paragraph(b.Runs, left+6)
+		case BlockListItem:
+			left, _, _, _ := pdf.GetMargins()
+			bullet := "- "
+			if b.Ordered {
+				bullet = fmt.Sprintf("%d. ", b.Index)
+			}
+			p.paragraph(append([]Run{{Text: bullet}}, b.Runs...), left+float64(b.Level+1)*6)
+		case BlockRule:
+			left, _, right, _ := pdf.GetMargins()
+			width, _ := pdf.GetPageSize()
+			pdf.SetDrawColor(200, 200, 200)
+			pdf.Line(left, pdf.GetY()+2, width-right, pdf.GetY()+2)
+			pdf.Ln(6)
+		default:
+			left, _, _, _ := pdf.GetMargins()
+			p.paragraph(b.Runs, left+float64(b.Level)*6)
+		}
+	}
+}
+
+func (p *pdfWriter) bookmark(title string, level int) {
+	if level > p.level+1 {
+		level = p.level + 1
+	}
+	p.level = level
+	p.pdf.Bookmark(p.tr(title), level, -1)
+}
+
+// paragraph 以 indent 为左边距逐段写入行内文本
+func (p *pdfWriter) paragraph(runs []Run, indent float64) {
+	pdf := p.pdf
+	left, top, right, _ := pdf.GetMargins()
+	pdf.SetMargins(indent, top, right)
+	pdf.SetX(indent)
+	for _, r := range runs {
+		style := ""
+		if r.Bold {
+			style += "B"
+		}
+		if r.Italic {
+			style += "I"
+		}
+		family := p.font
+		if r.Code {
+			family = p.mono
+		}
+		pdf.SetFont(family, style, 11)
+		if r.Link != "" {
+			pdf.SetTextColor(5, 99, 193)
+			pdf.WriteLinkString(6, p.tr(r.Text), r.Link)
+			pdf.SetTextColor(0, 0, 0)
+			continue
+		}
+		pdf.Write(6, p.tr(r.Text))
+	}
+	pdf.Ln(8)
+	pdf.SetMargins(left, top, right)
+}
+
+func (p *pdfWriter) imageBlock(img *Image) {
+	pdf := p.pdf
+	if img.Data == nil {
+		alt := img.Alt
+		if alt == "" {
+			alt = "图片"
+		}
+		left, _, _, _ := pdf.GetMargins()
+		p.paragraph([]Run{{Text: "[" + alt + "] "}, {Text: img.Src, Link: img.Src}}, left)
+		return
+	}
+	p.image++
+	name := fmt.Sprintf("image-%d", p.image)
+	options := fpdf.ImageOptions{ImageType: strings.ToUpper(img.ext()), ReadDpi: false}
+	pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(img.Data))
+
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	w, h := img.fit((pageWidth - left - right) / mmPerPixel)
+	w, h = w*mmPerPixel, h*mmPerPixel
+	x := left + (pageWidth-left-right-w)/2
+	pdf.ImageOptions(name, x, -1, w, h, true, options, 0, "")
+	if img.Alt != "" {
+		pdf.SetFont(p.font, "", 9)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.MultiCell(0, 5, p.tr(img.Alt), "", "C", false)
+	}
+	pdf.Ln(3)
+}
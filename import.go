@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"inkgo/config"
+	"inkgo/database"
+	"inkgo/importer"
+	"inkgo/repository"
+	"inkgo/service"
+	"os"
+	"text/tabwriter"
+)
+
+// runImport 执行 import 子命令, 例如:
+//
+//	inkgo -config config/app.yaml import -user admin -dry-run=false source/_posts wordpress.xml
+func runImport(conf *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	userName := fs.String("user", "admin", "admin user performing the import, also owns posts whose author is unknown")
+	dryRun := fs.Bool("dry-run", true, "only print the changes without writing to the database")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: inkgo import [flags] <dir|file.md|file.zip|file.xml>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no import source given")
+	}
+
+	var docs []*importer.Document
+	for _, path := range fs.Args() {
+		parsed, err := importer.ParsePath(path)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, parsed...)
+	}
+
+	db, err := database.NewMysql(&conf.DB)
+	if err != nil {
+		return err
+	}
+	rdb, err := database.NewRedis(&conf.Redis)
+	if err != nil {
+		return err
+	}
+	repo := repository.NewRepository(db, rdb)
+	defer repo.Close()
+
+	user, err := repo.User().FindByUserName(*userName)
+	if err != nil {
+		return fmt.Errorf("find user %s: %w", *userName, err)
+	}
+	report, err := service.NewImportService(conf.Import, repo.Import(), repo.User()).Import(user, docs, *dryRun)
+	if err != nil {
+		return err
+	}
+	printImportReport(report)
+	return nil
+}
+
+func printImportReport(report *service.ImportReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tSOURCE\tTITLE\tAUTHOR\tDATE\tSTATE\tNOTE")
+	for _, item := range report.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", item.Action, item.Source, item.Title,
+			item.AuthorID, item.Date.Format("2006-01-02 15:04"), item.State, item.Reason)
+	}
+	w.Flush()
+
+	fmt.Printf("\ntotal: %d, create: %d, skip: %d\n", report.Total, report.Created, report.Skipped)
+	fmt.Printf("new tags: %v\n", report.NewTags)
+	fmt.Printf("new categories: %v\n", report.NewCategories)
+	if len(report.UnknownAuthors) > 0 {
+		fmt.Printf("unknown authors: %v\n", report.UnknownAuthors)
+	}
+	if report.DryRun {
+		fmt.Println("dry run, nothing was written. Re-run with -dry-run=false to import.")
+	}
+}
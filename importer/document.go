@@ -0,0 +1,70 @@
+// Package importer 解析其它博客系统导出的文章, 供导入使用
+package importer
+
+import (
+	"strings"
+	"time"
+)
+
+// Document 从外部来源解析出的一篇文章
+type Document struct {
+	Source     string    `json:"source"` // 来源文件名或原文链接
+	Title      string    `json:"title"`
+	Content    string    `json:"-"`
+	Date       time.Time `json:"date"`    // 原始发布时间
+	Updated    time.Time `json:"updated"` // 原始更新时间, 没有时与 Date 相同
+	Author     string    `json:"author"`  // 原作者用户名
+	Tags       []string  `json:"tags"`
+	Categories []string  `json:"categories"`
+	Draft      bool      `json:"draft"`
+}
+
+// 常见的日期写法, 按顺序尝试
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// splitNames 拆分以逗号或空白分隔的名称列表, 去掉空白和重复项
+func splitNames(s string) []string {
+	sep := func(r rune) bool { return r == ',' || r == '，' }
+	if !strings.ContainsAny(s, ",，") {
+		sep = func(r rune) bool { return r == ' ' || r == '\t' }
+	}
+	return uniqueNames(strings.FieldsFunc(s, sep))
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 压缩包中单个文件解压后的大小上限
+const maxEntrySize = 10 << 20
+
+var ErrTooLarge = errors.New("压缩包解压后超过大小限制")
+
+// Limit 解压缩的总大小上限, 同一次导入的多个压缩包共用
+type Limit struct {
+	remaining int64
+}
+
+// NewLimit 创建总大小为 size 字节的解压缩上限
+func NewLimit(size int64) *Limit {
+	return &Limit{remaining: size}
+}
+
+// take 占用 n 字节, 超过剩余大小时返回 ErrTooLarge. nil 表示不限制
+func (l *Limit) take(n int64) error {
+	if l == nil {
+		return nil
+	}
+	if n > l.remaining {
+		return ErrTooLarge
+	}
+	l.remaining -= n
+	return nil
+}
+
+// ParseFile 根据扩展名解析上传的文件:
+// .xml 为 WordPress 导出文件, .md/.markdown 为单篇文章, .zip 为打包的 Hexo/Jekyll 目录.
+// limit 限制压缩包解压后的大小, 为 nil 时不限制
+func ParseFile(name string, data []byte, limit *Limit) ([]*Document, error) {
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".xml":
+		return ParseWXR(bytes.NewReader(data))
+	case ext == ".zip":
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: 压缩包解析失败: %w", name, err)
+		}
+		docs, err := ParseMarkdownFS(&zipFS{Reader: zr, limit: limit})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return docs, nil
+	case IsMarkdown(name):
+		doc, err := ParseMarkdown(filepath.Base(name), data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Document{doc}, nil
+	default:
+		return nil, fmt.Errorf("%s: 不支持的文件类型 %q", name, ext)
+	}
+}
+
+// ParsePath 解析本地路径, 目录按 Hexo/Jekyll 目录处理, 文件按扩展名处理
+func ParsePath(path string) ([]*Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ParseMarkdownFS(os.DirFS(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFile(path, data, nil)
+}
+
+// zipFS 读取压缩包中的文件时检查解压后的大小. 压缩包头中的大小可以伪造, 读取时按实际字节数计算
+type zipFS struct {
+	*zip.Reader
+	limit *Limit
+}
+
+func (z *zipFS) ReadFile(name string) ([]byte, error) {
+	f, err := z.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > maxEntrySize {
+		return nil, fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxEntrySize {
+		return nil, fmt.Errorf("%s: %w", name, ErrTooLarge)
+	}
+	if err := z.limit.take(int64(len(data))); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return data, nil
+}
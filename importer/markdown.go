@@ -0,0 +1,192 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Jekyll 的文件名格式: 2006-01-02-title.md
+var jekyllName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// ParseMarkdownFS 遍历 Hexo/Jekyll 目录中的 Markdown 文件并解析 front-matter
+// 以 "_" 或 "." 开头的目录除 _posts、_drafts 外均被跳过
+func ParseMarkdownFS(fsys fs.FS) ([]*Document, error) {
+	var docs []*Document
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := d.Name()
+		if d.IsDir() {
+			if name != "." && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) &&
+				base != "_posts" && base != "_drafts" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !IsMarkdown(base) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		doc, err := ParseMarkdown(name, data)
+		if err != nil {
+			return err
+		}
+		if strings.Contains("/"+name, "/_drafts/") {
+			doc.Draft = true
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Date.Before(docs[j].Date) })
+	return docs, nil
+}
+
+// IsMarkdown 判断文件扩展名是否为 Markdown
+func IsMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// ParseMarkdown 解析单个带 YAML front-matter 的 Markdown 文件
+func ParseMarkdown(name string, data []byte) (*Document, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	meta := map[string]interface{}{}
+	body := data
+	if front, rest, ok := splitFrontMatter(data); ok {
+		if err := yaml.Unmarshal(front, &meta); err != nil {
+			return nil, fmt.Errorf("%s: front-matter 解析失败: %w", name, err)
+		}
+		body = rest
+	}
+
+	doc := &Document{
+		Source:     name,
+		Title:      metaString(meta, "title"),
+		Content:    strings.TrimSpace(string(body)),
+		Author:     metaString(meta, "author"),
+		Tags:       metaNames(meta, "tags"),
+		Categories: metaNames(meta, "categories", "category"),
+	}
+
+	// Jekyll 文件名中带有日期和标题
+	stem := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if m := jekyllName.FindStringSubmatch(stem); m != nil {
+		stem = m[2]
+		if t, ok := parseDate(m[1]); ok {
+			doc.Date = t
+		}
+	}
+	if t, ok := metaTime(meta, "date"); ok {
+		doc.Date = t
+	}
+	if t, ok := metaTime(meta, "updated", "last_modified_at"); ok {
+		doc.Updated = t
+	}
+	if doc.Updated.IsZero() {
+		doc.Updated = doc.Date
+	}
+	if doc.Title == "" {
+		doc.Title = stem
+	}
+	// Hexo 使用 draft, Jekyll 使用 published: false
+	if v, ok := meta["draft"].(bool); ok && v {
+		doc.Draft = true
+	}
+	if v, ok := meta["published"].(bool); ok && !v {
+		doc.Draft = true
+	}
+	return doc, nil
+}
+
+// splitFrontMatter 拆分 "---" 包围的 front-matter 和正文
+func splitFrontMatter(data []byte) (front, body []byte, ok bool) {
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, data, false
+	}
+	rest := data[4:]
+	if bytes.HasPrefix(rest, []byte("---\n")) {
+		return nil, rest[4:], true
+	}
+	idx := bytes.Index(rest, []byte("\n---"))
+	if idx < 0 {
+		return nil, data, false
+	}
+	body = rest[idx+4:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return rest[:idx], body, true
+}
+
+func metaValue(meta map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if v, ok := meta[key]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func metaString(meta map[string]interface{}, keys ...string) string {
+	switch v := metaValue(meta, keys...).(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
+func metaTime(meta map[string]interface{}, keys ...string) (time.Time, bool) {
+	switch v := metaValue(meta, keys...).(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseDate(v)
+	}
+	return time.Time{}, false
+}
+
+// metaNames 读取字符串或列表形式的名称, Hexo 的多级分类会被展开
+func metaNames(meta map[string]interface{}, keys ...string) []string {
+	var names []string
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case string:
+			names = append(names, splitNames(v)...)
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case nil:
+		default:
+			names = append(names, fmt.Sprint(v))
+		}
+	}
+	collect(metaValue(meta, keys...))
+	return uniqueNames(names)
+}
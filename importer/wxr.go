@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WordPress 导出文件 (WXR) 的结构, 只保留导入需要的字段
+type wxrRSS struct {
+	Channel struct {
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title      string        `xml:"title"`
+	Link       string        `xml:"link"`
+	PubDate    string        `xml:"pubDate"`
+	Creator    string        `xml:"creator"`
+	Content    string        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostDate   string        `xml:"post_date"`
+	Modified   string        `xml:"post_modified"`
+	Status     string        `xml:"status"`
+	PostType   string        `xml:"post_type"`
+	Categories []wxrCategory `xml:"category"`
+}
+
+type wxrCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+// ParseWXR 解析 WordPress 导出的 XML, 只导入文章, 页面和附件会被忽略
+func ParseWXR(r io.Reader) ([]*Document, error) {
+	var rss wxrRSS
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&rss); err != nil {
+		return nil, fmt.Errorf("WXR 解析失败: %w", err)
+	}
+
+	docs := make([]*Document, 0, len(rss.Channel.Items))
+	for _, item := range rss.Channel.Items {
+		if item.PostType != "" && item.PostType != "post" {
+			continue
+		}
+		// 回收站和自动草稿不导入
+		if item.Status == "trash" || item.Status == "auto-draft" {
+			continue
+		}
+		doc := &Document{
+			Source:  item.Link,
+			Title:   strings.TrimSpace(item.Title),
+			Content: strings.TrimSpace(item.Content),
+			Author:  strings.TrimSpace(item.Creator),
+			Draft:   item.Status != "" && item.Status != "publish",
+		}
+		if t, ok := parseDate(item.PostDate); ok {
+			doc.Date = t
+		} else if t, ok := parseDate(item.PubDate); ok {
+			doc.Date = t
+		}
+		if t, ok := parseDate(item.Modified); ok {
+			doc.Updated = t
+		} else {
+			doc.Updated = doc.Date
+		}
+		for _, c := range item.Categories {
+			switch c.Domain {
+			case "post_tag":
+				doc.Tags = append(doc.Tags, c.Name)
+			case "category":
+				doc.Categories = append(doc.Categories, c.Name)
+			}
+		}
+		doc.Tags = uniqueNames(doc.Tags)
+		doc.Categories = uniqueNames(doc.Categories)
+		if doc.Source == "" {
+			doc.Source = doc.Title
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -19,6 +19,14 @@ func main() {
 		log.Fatalf("failed to parse config: %v", err)
 	}
 
+	// 子命令
+	if flag.Arg(0) == "import" {
+		if err := runImport(conf, flag.Args()[1:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
 	logger, err := zap.NewProduction()
 	if err != nil {
 		log.Fatalf("failed to create logger: %v", err)
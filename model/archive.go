@@ -0,0 +1,36 @@
+package model
+
+// ArchiveFilter 归档和日历的筛选条件, 为 0 表示不筛选
+type ArchiveFilter struct {
+	AuthorID   uint `json:"author_id,omitempty"`
+	TagID      uint `json:"tag_id,omitempty"`
+	CategoryID uint `json:"category_id,omitempty"`
+}
+
+// ArchiveYear 某一年已发布的文章数, 按月份降序列出有文章的月份
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int64          `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// ArchiveMonth 某年某月已发布的文章数
+type ArchiveMonth struct {
+	Year  int   `json:"year"`
+	Month int   `json:"month"`
+	Count int64 `json:"count"`
+}
+
+// Calendar 写作日历, 按天统计发布的文章数, 用于绘制热力图
+type Calendar struct {
+	Start string        `json:"start"` // 开始日期, 包含
+	End   string        `json:"end"`   // 结束日期, 包含
+	Total int64         `json:"total"` // 期间发布的文章总数
+	Max   int64         `json:"max"`   // 单日最多发布的文章数
+	Days  []CalendarDay `json:"days"`  // 期间每一天的发布数, 没有发布的日期为 0
+}
+
+type CalendarDay struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
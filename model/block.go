@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Block 用户拉黑的记录, 被拉黑的用户 @ 该用户时不会生效
+type Block struct {
+	ID        uint      `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_block"`
+	BlockedID uint      `json:"blocked_id" gorm:"uniqueIndex:idx_block;index"`
+	Blocked   User      `json:"blocked" gorm:"foreignKey:BlockedID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*Block) TableName() string {
+	return "user_block"
+}
+
+// Mute 用户屏蔽的作者, 被屏蔽作者的文章不会出现在时间线中, 与拉黑不同对方不受任何限制
+type Mute struct {
+	ID        uint      `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_mute"`
+	MutedID   uint      `json:"muted_id" gorm:"uniqueIndex:idx_mute"`
+	Muted     User      `json:"muted" gorm:"foreignKey:MutedID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*Mute) TableName() string {
+	return "user_mute"
+}
@@ -0,0 +1,65 @@
+package model
+
+// BulkOperation 批量操作文章的类型
+type BulkOperation string
+
+const (
+	BulkSetState       BulkOperation = "set_state"       // 修改状态
+	BulkAddTags        BulkOperation = "add_tags"        // 添加标签
+	BulkRemoveTags     BulkOperation = "remove_tags"     // 移除标签
+	BulkSetCategories  BulkOperation = "set_categories"  // 替换分类
+	BulkTransferAuthor BulkOperation = "transfer_author" // 转移作者, 仅管理员
+	BulkDelete         BulkOperation = "delete"          // 删除, 可在回收站中恢复
+)
+
+func (o BulkOperation) Valid() bool {
+	switch o {
+	case BulkSetState, BulkAddTags, BulkRemoveTags, BulkSetCategories, BulkTransferAuthor, BulkDelete:
+		return true
+	}
+	return false
+}
+
+// BulkFilter 按条件选择要批量操作的文章, 为 0 或空表示不筛选
+type BulkFilter struct {
+	ArchiveFilter
+	State PostState `json:"state,omitempty"`
+}
+
+// BulkRequest 批量操作请求, IDs 和 Filter 二选一
+type BulkRequest struct {
+	IDs         []uint        `json:"ids"`
+	Filter      *BulkFilter   `json:"filter"`
+	Operation   BulkOperation `json:"operation"`
+	State       PostState     `json:"state,omitempty"`        // set_state 的目标状态
+	TagIDs      []uint        `json:"tag_ids,omitempty"`      // add_tags, remove_tags 的标签
+	CategoryIDs []uint        `json:"category_ids,omitempty"` // set_categories 的分类
+	AuthorID    uint          `json:"author_id,omitempty"`    // transfer_author 的新作者
+}
+
+// BulkChange 对一批文章执行的修改
+type BulkChange struct {
+	Operation   BulkOperation
+	State       PostState
+	TagIDs      []uint
+	CategoryIDs []uint
+	AuthorID    uint
+	Masked      map[uint]*Post // 发布时敏感词被打码的文章, 脱敏后的标题和正文与状态一起保存
+}
+
+// BulkItemResult 单篇文章的处理结果
+type BulkItemResult struct {
+	ID      uint      `json:"id"`
+	Success bool      `json:"success"`
+	State   PostState `json:"state,omitempty"` // set_state 后文章的实际状态, 命中敏感词时为 pending
+	Error   string    `json:"error,omitempty"`
+}
+
+// BulkReport 批量操作的结果
+type BulkReport struct {
+	Operation BulkOperation    `json:"operation"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
@@ -0,0 +1,7 @@
+package model
+
+// Captcha 图片验证码, Image 为 PNG 图片的 data URL
+type Captcha struct {
+	ID    string `json:"id"`
+	Image string `json:"image"`
+}
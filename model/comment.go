@@ -1,17 +1,49 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// CommentStatus 评论的审核状态
+type CommentStatus string
+
+const (
+	CommentApproved CommentStatus = "approved" // 正常显示
+	CommentPending  CommentStatus = "pending"  // 等待审核, 只有作者自己可见
+	CommentHidden   CommentStatus = "hidden"   // 审核未通过, 不再显示
+	CommentSpam     CommentStatus = "spam"     // 垃圾评论, 由分类器自动识别或管理员标记, 不再显示
+)
+
+// CommentSort 顶层评论的排序方式
+type CommentSort string
+
+const (
+	CommentNewest    CommentSort = "newest" // 最新, 默认
+	CommentOldest    CommentSort = "oldest" // 最早
+	CommentMostLiked CommentSort = "likes"  // 点赞最多
+)
 
 type Comment struct {
 	gorm.Model
-	Content  string    `json:"content" gorm:"size:1024"`
-	AuthorID uint      `json:"author_id"`
-	Author   User      `json:"author" gorm:"foreignKey:AuthorID"`
-	PostID   uint      `json:"postId"`
-	Post     Post      `json:"post" gorm:"foreignKey:PostID"`
-	ParentID *uint     `json:"parent_id"`
-	Parent   *Comment  `json:"parent" gorm:"foreignKey:ParentID"`
-	Replies  []Comment `json:"replies" gorm:"foreignKey:ParentID"`
+	Content       string        `json:"content" gorm:"size:1024"`
+	AuthorID      uint          `json:"author_id" gorm:"default:null"` // 游客评论没有作者, 为空
+	Author        User          `json:"author" gorm:"foreignKey:AuthorID"`
+	PostID        uint          `json:"postId"`
+	Post          Post          `json:"post" gorm:"foreignKey:PostID"`
+	ParentID      *uint         `json:"parent_id"`
+	Parent        *Comment      `json:"parent" gorm:"foreignKey:ParentID"`
+	RootID        *uint         `json:"root_id" gorm:"index"` // 所属顶层评论, 顶层评论为空. 同一楼层的回复按时间平铺, 通过 ParentID 还原层级
+	Replies       []Comment     `json:"replies" gorm:"foreignKey:ParentID"`
+	Status        CommentStatus `json:"status" gorm:"type:varchar(20);default:'approved';index"`
+	LikeCount     uint          `json:"like_count" gorm:"not null;default:0"`
+	EditedAt      *time.Time    `json:"edited_at"`                         // 最后一次编辑的时间, 没有编辑过时为空
+	ReplyCount    int64         `json:"reply_count" gorm:"-"`              // 楼层中的回复总数, 仅顶层评论返回
+	RepliesCursor string        `json:"replies_cursor,omitempty" gorm:"-"` // 加载更多回复的游标, 没有更多回复时为空
+	Mentions      []Mention     `json:"mentions,omitempty" gorm:"-"`       // 评论中 @ 到的用户
+	GuestName     string        `json:"guest_name,omitempty" gorm:"size:64"`
+	GuestEmail    string        `json:"-" gorm:"size:128;index"` // 游客注册后可以用相同的邮箱认领评论
+	GuestWebsite  string        `json:"guest_website,omitempty" gorm:"size:256"`
 }
 
 /*
@@ -24,3 +56,16 @@ type Comment struct {
 func (*Comment) TableName() string {
 	return "comment"
 }
+
+// CommentRevision 评论编辑前的版本, 只有管理员可以查看
+type CommentRevision struct {
+	ID        uint      `json:"id" gorm:"autoIncrement;primaryKey"`
+	CommentID uint      `json:"comment_id" gorm:"index"`
+	Content   string    `json:"content" gorm:"size:1024"`
+	EditorID  uint      `json:"editor_id"`  // 编辑该评论的用户, 作者本人或管理员
+	CreatedAt time.Time `json:"created_at"` // 被编辑的时间, 即该版本失效的时间
+}
+
+func (*CommentRevision) TableName() string {
+	return "comment_revision"
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// Cursor 游标分页的位置, 指向上一页的最后一条记录
+type Cursor struct {
+	At *time.Time `json:"t,omitempty"` // 按时间排序的列表中最后一条记录的时间, 按ID排序时为空
+	ID uint       `json:"i"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// ExportStatus 导出任务的状态
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending" // 等待处理
+	ExportRunning ExportStatus = "running" // 正在生成
+	ExportDone    ExportStatus = "done"    // 已完成, 可以下载
+	ExportFailed  ExportStatus = "failed"  // 失败
+)
+
+// ExportTask 导出作者全部文章的后台任务
+type ExportTask struct {
+	ID         uint         `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"index"`
+	Format     string       `json:"format" gorm:"type:varchar(10)"`                         // docx, pdf, epub
+	Status     ExportStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"` // 任务状态
+	FileName   string       `json:"file_name" gorm:"size:256"`                              // 下载时的文件名
+	FilePath   string       `json:"-" gorm:"size:512"`                                      // 生成的文件在服务器上的路径
+	Size       int64        `json:"size"`                                                   // 文件大小
+	PostCount  int          `json:"post_count"`                                             // 导出的文章数
+	Error      string       `json:"error,omitempty" gorm:"size:512"`                        // 失败原因
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	FinishedAt *time.Time   `json:"finished_at"`
+}
+
+func (*ExportTask) TableName() string {
+	return "export_task"
+}
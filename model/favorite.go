@@ -2,6 +2,7 @@ package model
 
 import (
 	"gorm.io/gorm"
+	"time"
 )
 
 /*
@@ -21,24 +22,50 @@ type Favorite struct {
 	gorm.Model
 	// Name 字段只用 unique —— 意味着全系统唯一，这可能会导致不同用户不能取相同的收藏夹名字。
 	// 更合理的做法： 添加一个联合唯一约束 (user_id, name)：
-	Name   string `json:"name" gorm:"size:256;not null;uniqueIndex:idx_user_name"` // 标签名称
-	Desc   string `json:"desc" gorm:"type:varchar(256);"`                          // 收藏夹描述
-	Public bool   `json:"public" gorm:"default:true;comment:是否公开"`                 // 是否公开收藏夹
-	// 为了满足允许一个收藏夹重复收藏同一篇文章
-	Posts []Post `json:"posts" gorm:"many2many:favorite_posts"`
-	//Posts  []FavoritePost `json:"posts"`                                                   // 收藏和帖子之间的多对多关系
-	UserID uint `json:"user_id" gorm:"not null;index;uniqueIndex:idx_user_name"` // 属主
-	User   User `json:"user" gorm:"foreignKey:UserID"`
+	Name   string `json:"name" gorm:"size:256;not null;uniqueIndex:idx_user_name"`            // 标签名称
+	Desc   string `json:"desc" gorm:"type:varchar(256);"`                                     // 收藏夹描述
+	Public bool   `json:"public" gorm:"column:is_public;not null;default:false;comment:是否公开"` // 是否公开收藏夹, 默认私有
+	// 中间表 favorite_posts 使用自定义的 FavoritePost, 记录收藏时间、备注和排序
+	Posts  []Post `json:"posts" gorm:"many2many:favorite_posts"`
+	UserID uint   `json:"user_id" gorm:"not null;index;uniqueIndex:idx_user_name"` // 属主
+	User   User   `json:"user" gorm:"foreignKey:UserID"`
 }
 
-/*
-默认 GORM 的 many2many 会去重。如果你想允许重复收藏（不常见），就得自己定义中间表结构，不用自动 many2many。为了满足允许一个收藏夹重复收藏同一篇文章
-如果你还想记录“每篇文章被收藏的时间”，则要手动定义中间表 favorite_posts: 如下
+func (*Favorite) TableName() string {
+	return "favorite"
+}
 
-Posts     []Post    `json:"posts" gorm:"many2many:favorite_posts"`                   // 收藏和帖子之间的多对多关系
+// FavoritePost 收藏夹中的一篇文章, 同一收藏夹不会重复收藏同一篇文章
+type FavoritePost struct {
+	FavoriteID uint      `json:"favorite_id" gorm:"primaryKey"`
+	PostID     uint      `json:"post_id" gorm:"primaryKey;index"`
+	Post       *Post     `json:"post,omitempty" gorm:"foreignKey:PostID"`
+	Note       string    `json:"note" gorm:"size:512"` // 收藏时的备注
+	Position   int       `json:"position"`             // 手动排序的位置, 越小越靠前
+	CreatedAt  time.Time `json:"added_at"`             // 加入收藏夹的时间
+}
 
-*/
+func (*FavoritePost) TableName() string {
+	return "favorite_posts"
+}
 
-func (*Favorite) TableName() string {
-	return "favorite"
+// FavoriteFollow 用户关注的他人公开收藏夹
+type FavoriteFollow struct {
+	ID         uint      `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"uniqueIndex:idx_favorite_follow"`
+	FavoriteID uint      `json:"favorite_id" gorm:"uniqueIndex:idx_favorite_follow;index"`
+	Favorite   Favorite  `json:"favorite" gorm:"foreignKey:FavoriteID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (*FavoriteFollow) TableName() string {
+	return "favorite_follow"
+}
+
+// FavoriteActivity 关注的收藏夹中新加入的文章
+type FavoriteActivity struct {
+	FavoritePost
+	FavoriteName string `json:"favorite_name"`
+	OwnerID      uint   `json:"owner_id"`
+	OwnerName    string `json:"owner_name"`
 }
@@ -1,6 +1,9 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 // Follow关注
 type Follow struct {
@@ -14,3 +17,45 @@ type Follow struct {
 func (*Follow) TableName() string {
 	return "follow"
 }
+
+// FollowTarget 除用户外可以关注的话题类型
+type FollowTarget string
+
+const (
+	FollowTag      FollowTarget = "tag"
+	FollowCategory FollowTarget = "category"
+)
+
+// TopicFollow 用户关注的标签或分类, 其中的新文章会出现在用户的时间线中
+type TopicFollow struct {
+	ID         uint         `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"uniqueIndex:idx_topic_follow"`
+	TargetType FollowTarget `json:"target_type" gorm:"type:varchar(20);uniqueIndex:idx_topic_follow"`
+	TargetID   uint         `json:"target_id" gorm:"uniqueIndex:idx_topic_follow"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+func (*TopicFollow) TableName() string {
+	return "topic_follow"
+}
+
+// FollowUser 关注列表和粉丝列表中的用户, 只包含公开信息
+type FollowUser struct {
+	ID         uint      `json:"id"`
+	UserName   string    `json:"user_name"`
+	Avatar     string    `json:"avatar"`
+	Intro      string    `json:"intro"`
+	Mutual     bool      `json:"mutual"`      // 是否与列表所属的用户互相关注
+	FollowedAt time.Time `json:"followed_at"` // 关注的时间
+	FollowID   uint      `json:"-"`           // 关注记录的ID, 用于游标分页
+}
+
+// FollowSuggestion 推荐关注的用户
+type FollowSuggestion struct {
+	ID       uint   `json:"id"`
+	UserName string `json:"user_name"`
+	Avatar   string `json:"avatar"`
+	Intro    string `json:"intro"`
+	Mutuals  int64  `json:"mutuals"` // 当前用户关注的人中有多少人关注了该用户
+	Likes    int64  `json:"likes"`   // 当前用户点赞过该用户的文章数
+}
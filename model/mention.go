@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// MentionTarget 可以 @ 用户的内容类型
+type MentionTarget string
+
+const (
+	MentionPost    MentionTarget = "post"
+	MentionComment MentionTarget = "comment"
+)
+
+// Mention 文章或评论中 @ 到的用户, 同一内容中多次 @ 同一用户只记录一次
+type Mention struct {
+	ID         uint          `json:"-" gorm:"autoIncrement;primaryKey"`
+	TargetType MentionTarget `json:"-" gorm:"type:varchar(20);uniqueIndex:idx_mention"`
+	TargetID   uint          `json:"-" gorm:"uniqueIndex:idx_mention"`
+	UserID     uint          `json:"user_id" gorm:"uniqueIndex:idx_mention;index"`
+	UserName   string        `json:"user_name" gorm:"size:64"`
+	AuthorID   uint          `json:"-" gorm:"index"`
+	Notified   bool          `json:"-" gorm:"not null;default:false"` // 是否已通知被 @ 的用户, 内容公开后才会通知
+	CreatedAt  time.Time     `json:"-"`
+}
+
+func (*Mention) TableName() string {
+	return "mention"
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// ModerationTarget 需要审核的内容类型
+type ModerationTarget string
+
+const (
+	ModerationPost    ModerationTarget = "post"
+	ModerationComment ModerationTarget = "comment"
+)
+
+// ModerationStatus 审核状态
+type ModerationStatus string
+
+const (
+	ModerationPending  ModerationStatus = "pending"  // 等待审核
+	ModerationApproved ModerationStatus = "approved" // 审核通过, 内容已发布
+	ModerationRejected ModerationStatus = "rejected" // 审核未通过
+)
+
+// ModerationReview 命中敏感词后进入审核队列的内容, 同一内容只保留一条待审核记录
+type ModerationReview struct {
+	ID         uint             `json:"id" gorm:"autoIncrement;primaryKey"`
+	TargetType ModerationTarget `json:"target_type" gorm:"type:varchar(20);index:idx_moderation_target"`
+	TargetID   uint             `json:"target_id" gorm:"index:idx_moderation_target"`
+	AuthorID   uint             `json:"author_id" gorm:"index;default:null"` // 游客评论为空
+	Author     User             `json:"author" gorm:"foreignKey:AuthorID"`
+	Excerpt    string           `json:"excerpt" gorm:"size:512"`                                // 内容摘要, 方便审核时查看
+	Words      string           `json:"words" gorm:"size:512"`                                  // 命中的敏感词, 逗号分隔
+	Status     ModerationStatus `json:"status" gorm:"type:varchar(20);default:'pending';index"` // 审核状态
+	ReviewerID *uint            `json:"reviewer_id"`                                            // 审核人
+	Reason     string           `json:"reason,omitempty" gorm:"size:256"`                       // 拒绝原因
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	ReviewedAt *time.Time       `json:"reviewed_at"`
+}
+
+func (*ModerationReview) TableName() string {
+	return "moderation_review"
+}
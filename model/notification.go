@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// NotificationType 通知类型
+type NotificationType string
+
+const (
+	NotificationMention NotificationType = "mention" // 在文章或评论中被 @
+)
+
+// Notification 发给用户的站内通知
+type Notification struct {
+	ID         uint             `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint             `json:"user_id" gorm:"index:idx_notification_user"` // 接收通知的用户
+	ActorID    uint             `json:"actor_id"`                                   // 触发通知的用户
+	Actor      User             `json:"actor" gorm:"foreignKey:ActorID"`
+	Type       NotificationType `json:"type" gorm:"type:varchar(20)"`
+	TargetType string           `json:"target_type" gorm:"type:varchar(20)"` // post 或 comment
+	TargetID   uint             `json:"target_id"`
+	PostID     uint             `json:"post_id"` // 评论所在的文章, 方便客户端跳转
+	Excerpt    string           `json:"excerpt" gorm:"size:512"`
+	ReadAt     *time.Time       `json:"read_at" gorm:"index:idx_notification_user"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+func (*Notification) TableName() string {
+	return "notification"
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"gorm.io/gorm"
+	"time"
 )
 
 const (
@@ -43,28 +44,64 @@ const (
 	PostDraft     PostState = "draft"     // 草稿
 	PostPublished PostState = "published" // 已发布
 	PostArchived  PostState = "archived"  // 已归档（可选）
+	PostPending   PostState = "pending"   // 命中敏感词, 等待审核后发布
 )
 
+// PostVisibility 文章的可见范围
+type PostVisibility string
+
+const (
+	VisibilityPublic    PostVisibility = "public"    // 所有人可见
+	VisibilityUnlisted  PostVisibility = "unlisted"  // 不出现在列表中, 知道链接即可访问
+	VisibilityFollowers PostVisibility = "followers" // 仅关注作者的用户可见
+	VisibilityPrivate   PostVisibility = "private"   // 仅作者本人可见
+	VisibilityPassword  PostVisibility = "password"  // 输入密码后可见
+)
+
+// Valid 是否为合法的可见范围
+func (v PostVisibility) Valid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityFollowers, VisibilityPrivate, VisibilityPassword:
+		return true
+	}
+	return false
+}
+
 // Post 文章模型
 type Post struct {
 	gorm.Model
-	Title      string     `json:"title" gorm:"type:varchar(100);not null"`       // 文章标题
-	Content    string     `json:"content" gorm:"type:text;not null"`             // 文章内容
-	Cover      string     `json:"cover" gorm:"not null"`                         // 封面图片
-	AuthorID   uint       `json:"author_id"`                                     // 外键
-	Author     User       `json:"author" gorm:"foreignKey:AuthorID"`             // 关联 User 实体
-	Tags       []Tag      `json:"tags" gorm:"many2many:tag_posts"`               // Post 和 Tag 之间的多对多关系
-	Categories []Category `json:"categories" gorm:"many2many:category_posts"`    // Post 和 Category 之间的多对多关系
-	Comments   []Comment  `json:"comments,omitempty"`                            // Post 和 Comment 之间的一对多关系
-	ViewCount  uint       `json:"view_count" gorm:"type:uint"`                   // 文章阅读量
-	LikeCount  uint       `json:"like_count" gorm:"type:uint"`                   // 点赞数
-	UserLiked  bool       `json:"user_liked" gorm:"-"`                           // 用户是否点赞
-	Original   bool       `json:"original"`                                      // 是否原创，true:原创，false:转载
-	State      PostState  `json:"state" gorm:"type:varchar(20);default:'draft'"` // 文章的发布状态，已发布/草稿
+	Title        string         `json:"title" gorm:"type:varchar(100);not null"`                   // 文章标题
+	Content      string         `json:"content" gorm:"type:text;not null"`                         // 文章内容
+	Cover        string         `json:"cover" gorm:"not null"`                                     // 封面图片
+	AuthorID     uint           `json:"author_id"`                                                 // 外键
+	Author       User           `json:"author" gorm:"foreignKey:AuthorID"`                         // 关联 User 实体
+	Tags         []Tag          `json:"tags" gorm:"many2many:tag_posts"`                           // Post 和 Tag 之间的多对多关系
+	Categories   []Category     `json:"categories" gorm:"many2many:category_posts"`                // Post 和 Category 之间的多对多关系
+	Comments     []Comment      `json:"comments,omitempty"`                                        // Post 和 Comment 之间的一对多关系
+	ViewCount    uint           `json:"view_count" gorm:"type:uint"`                               // 文章阅读量
+	LikeCount    uint           `json:"like_count" gorm:"type:uint"`                               // 点赞数
+	UserLiked    bool           `json:"user_liked" gorm:"-"`                                       // 用户是否点赞
+	Original     bool           `json:"original"`                                                  // 是否原创，true:原创，false:转载
+	State        PostState      `json:"state" gorm:"type:varchar(20);default:'draft'"`             // 文章的发布状态，已发布/草稿
+	Repost       *Repost        `json:"repost,omitempty" gorm:"foreignKey:PostID"`                 // 转载来源，原创文章为空
+	Visibility   PostVisibility `json:"visibility" gorm:"type:varchar(20);default:'public';index"` // 可见范围，默认公开
+	Password     string         `json:"password,omitempty" gorm:"-"`                               // 设置加密文章时传入的明文密码, 不落库
+	PasswordHash string         `json:"-" gorm:"column:password;size:64"`                          // 加密文章的密码(bcrypt)
+	Version      uint           `json:"version" gorm:"not null;default:1"`                         // 版本号, 每次修改加一, 用于乐观锁
+	Related      []Post         `json:"related,omitempty" gorm:"-"`                                // 相关文章, 仅在文章详情中返回
+	Mentions     []Mention      `json:"mentions,omitempty" gorm:"-"`                               // 文章中 @ 到的用户
 }
 
-//Repost      *Post      `json:"repost,omitempty" gorm:"foreignKey:RepostID"`    // 转载的文章
-
 func (p *Post) TableName() string {
 	return "post"
 }
+
+// PostRankStat 计算热门排行所需的文章互动数据, 不对应数据表
+type PostRankStat struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ViewCount     int64     `json:"view_count"`
+	LikeCount     int64     `json:"like_count"`
+	CommentCount  int64     `json:"comment_count"`
+	FavoriteCount int64     `json:"favorite_count"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PostSortKey 文章列表允许的排序字段, 与文章 JSON 中的字段名一致
+type PostSortKey string
+
+const (
+	SortCreatedAt    PostSortKey = "created_at"
+	SortUpdatedAt    PostSortKey = "updated_at"
+	SortViewCount    PostSortKey = "view_count"
+	SortLikeCount    PostSortKey = "like_count"
+	SortCommentCount PostSortKey = "comment_count"
+	SortTitle        PostSortKey = "title"
+)
+
+func (k PostSortKey) Valid() bool {
+	switch k {
+	case SortCreatedAt, SortUpdatedAt, SortViewCount, SortLikeCount, SortCommentCount, SortTitle:
+		return true
+	}
+	return false
+}
+
+// PostSort 一个排序条件
+type PostSort struct {
+	Key  PostSortKey `json:"key"`
+	Desc bool        `json:"desc"`
+}
+
+// ParsePostSort 解析 "-like_count,created_at" 形式的排序参数, 字段前加 - 表示降序
+func ParsePostSort(s string) ([]PostSort, error) {
+	sorts := make([]PostSort, 0)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		sort := PostSort{Key: PostSortKey(strings.TrimPrefix(field, "-")), Desc: strings.HasPrefix(field, "-")}
+		if !sort.Key.Valid() {
+			return nil, fmt.Errorf("不支持的排序字段: %s", sort.Key)
+		}
+		sorts = append(sorts, sort)
+	}
+	return sorts, nil
+}
+
+// PostQuery 文章列表的筛选和排序条件, 零值表示不筛选. 多个标签或分类时文章需要全部包含
+type PostQuery struct {
+	TagIDs        []uint     `json:"tag_ids,omitempty"`
+	TagNames      []string   `json:"tag_names,omitempty"`
+	CategoryIDs   []uint     `json:"category_ids,omitempty"`
+	CategoryNames []string   `json:"category_names,omitempty"`
+	AuthorID      uint       `json:"author_id,omitempty"`
+	State         PostState  `json:"state,omitempty"`
+	From          *time.Time `json:"from,omitempty"` // 创建时间 >= From
+	To            *time.Time `json:"to,omitempty"`   // 创建时间 < To
+	Original      *bool      `json:"original,omitempty"`
+	Sort          []PostSort `json:"sort,omitempty"` // 为空时按创建时间降序
+}
@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// ReactionTarget 可以添加表情的内容类型
+type ReactionTarget string
+
+const (
+	ReactionPost    ReactionTarget = "post"
+	ReactionComment ReactionTarget = "comment"
+)
+
+// ReactionKind 表情类型, 点赞也是一种表情
+type ReactionKind string
+
+const (
+	ReactionLike      ReactionKind = "like"      // 👍
+	ReactionLove      ReactionKind = "love"      // ❤️
+	ReactionLaugh     ReactionKind = "laugh"     // 😂
+	ReactionCelebrate ReactionKind = "celebrate" // 🎉
+)
+
+// ReactionKinds 支持的表情, 按展示顺序排列
+var ReactionKinds = []ReactionKind{ReactionLike, ReactionLove, ReactionLaugh, ReactionCelebrate}
+
+var reactionEmojis = map[ReactionKind]string{
+	ReactionLike:      "👍",
+	ReactionLove:      "❤️",
+	ReactionLaugh:     "😂",
+	ReactionCelebrate: "🎉",
+}
+
+// Emoji 表情对应的字符
+func (k ReactionKind) Emoji() string {
+	return reactionEmojis[k]
+}
+
+// ParseReactionKind 解析表情, 名称和表情字符都可以
+func ParseReactionKind(s string) (ReactionKind, bool) {
+	for _, kind := range ReactionKinds {
+		if s == string(kind) || s == kind.Emoji() {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// Reaction 用户对文章或评论添加的表情, 同一用户对同一内容的每种表情只记录一次
+type Reaction struct {
+	ID         uint           `json:"id" gorm:"autoIncrement;primaryKey"`
+	TargetType ReactionTarget `json:"target_type" gorm:"type:varchar(20);uniqueIndex:idx_reaction"`
+	TargetID   uint           `json:"target_id" gorm:"uniqueIndex:idx_reaction"`
+	UserID     uint           `json:"user_id" gorm:"uniqueIndex:idx_reaction;index"`
+	Kind       ReactionKind   `json:"kind" gorm:"type:varchar(20);uniqueIndex:idx_reaction"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+func (*Reaction) TableName() string {
+	return "reaction"
+}
+
+// ReactionCount 某种表情的数量, Reacted 表示当前用户是否添加过
+type ReactionCount struct {
+	Kind    ReactionKind `json:"kind"`
+	Emoji   string       `json:"emoji"`
+	Count   int64        `json:"count"`
+	Reacted bool         `json:"reacted"`
+}
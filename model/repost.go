@@ -1,14 +1,38 @@
 package model
 
-// repost 转发文章
+import "time"
+
+const RepostAssociation = "Repost"
+
+type RepostStatus string
+
+const (
+	RepostPending  RepostStatus = "pending"  // 等待原作者授权
+	RepostApproved RepostStatus = "approved" // 原作者已授权
+	RepostRejected RepostStatus = "rejected" // 原作者已拒绝
+)
+
+// repost 转发文章, 记录转载文章的来源和授权状态
 type Repost struct {
-	ID           uint   `json:"id" gorm:"autoIncrement;primaryKey"`
-	Url          string `json:"url" gorm:"type:varchar(200);not null"` // 转发的文章链接
-	IsAuthorized bool   `json:"is_authorized" gorm:"default:false"`    // 是否授权转载，默认 false
-	RepostID     uint   `json:"repost_id"`                             // 被转发的文章ID
-	Repost       Post   `json:"-" gorm:"foreignKey:RepostID"`          // 关联被转发的 Post 实体
+	ID             uint         `json:"id" gorm:"autoIncrement;primaryKey"`
+	PostID         uint         `json:"post_id" gorm:"uniqueIndex"`                       // 转载后的文章ID
+	Url            string       `json:"url" gorm:"type:varchar(200);not null"`            // 转发的文章链接
+	SourceTitle    string       `json:"source_title" gorm:"type:varchar(100)"`            // 原文标题
+	SourceAuthor   string       `json:"source_author" gorm:"type:varchar(64)"`            // 原文作者名称, 用于署名
+	SourceAuthorID *uint        `json:"source_author_id" gorm:"index"`                    // 站内原作者ID, 外部来源为空
+	IsAuthorized   bool         `json:"is_authorized" gorm:"default:false"`               // 是否授权转载，默认 false
+	Status         RepostStatus `json:"status" gorm:"type:varchar(20);default:'pending'"` // 授权申请状态
+	RepostID       *uint        `json:"repost_id"`                                        // 被转发的文章ID, 外部来源为空
+	Repost         *Post        `json:"-" gorm:"foreignKey:RepostID"`                     // 关联被转发的 Post 实体
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
 }
 
 func (*Repost) TableName() string {
 	return "repost"
 }
+
+// IsExternal 是否为站外来源
+func (r *Repost) IsExternal() bool {
+	return r.RepostID == nil
+}
@@ -1,17 +1,57 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 // Share 分享文章
 type Share struct {
 	gorm.Model
-	AuthorID  uint  `json:"author_id"`                         // 作者ID
-	Author    User  `json:"author" gorm:"foreignKey:AuthorID"` // 关联的用户实体
-	PostID    uint  `json:"post_id"`                           // 文章ID
-	Post      Post  `json:"post" gorm:"foreignKey:PostID"`     // 关联的文章实体
-	CreatedAt int64 `json:"created_at" gorm:"autoCreateTime"`  // 分享时间
+	AuthorID   uint   `json:"author_id" gorm:"index:idx_share_author_post"` // 作者ID
+	Author     User   `json:"author" gorm:"foreignKey:AuthorID"`            // 关联的用户实体
+	PostID     uint   `json:"post_id" gorm:"index:idx_share_author_post"`   // 文章ID
+	Post       Post   `json:"post" gorm:"foreignKey:PostID"`                // 关联的文章实体
+	Code       string `json:"code" gorm:"type:varchar(16);uniqueIndex"`     // 短链接码
+	ClickCount uint   `json:"click_count" gorm:"default:0"`                 // 短链接被点击的次数
+	URL        string `json:"url" gorm:"-"`                                 // 完整的短链接地址
+	CreatedAt  int64  `json:"created_at" gorm:"autoCreateTime"`             // 分享时间
 }
 
 func (*Share) TableName() string {
 	return "share"
 }
+
+// ShareClick 短链接的一次点击记录
+type ShareClick struct {
+	ID        uint      `json:"id" gorm:"autoIncrement;primaryKey"`
+	ShareID   uint      `json:"share_id" gorm:"index"`
+	PostID    uint      `json:"post_id" gorm:"index"`       // 冗余文章ID, 方便按文章统计
+	Referrer  string    `json:"referrer" gorm:"size:512"`   // 来源页面
+	IP        string    `json:"ip" gorm:"size:64"`          // 访问者IP
+	UserAgent string    `json:"user_agent" gorm:"size:256"` // 访问者的浏览器
+	ClickedAt time.Time `json:"clicked_at" gorm:"index"`    // 点击时间
+}
+
+func (*ShareClick) TableName() string {
+	return "share_click"
+}
+
+// ShareStats 文章的分享统计
+type ShareStats struct {
+	PostID     uint                `json:"post_id"`
+	ShareCount int64               `json:"share_count"` // 统计期间生成的短链接数
+	ClickCount int64               `json:"click_count"` // 统计期间短链接的点击数
+	Referrers  []ShareReferrerStat `json:"referrers"`   // 按来源统计的点击数
+	Daily      []ShareDailyStat    `json:"daily"`       // 按天统计的点击数
+}
+
+type ShareReferrerStat struct {
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+type ShareDailyStat struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
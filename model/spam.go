@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// SpamLabel 管理员对评论的标记, 同时是垃圾评论分类器的训练样本, 每条评论只保留最后一次标记
+type SpamLabel struct {
+	CommentID uint      `json:"comment_id" gorm:"primaryKey;autoIncrement:false"`
+	Spam      bool      `json:"spam" gorm:"index"`
+	Features  string    `json:"-" gorm:"type:text"` // 训练时使用的特征, 换行分隔, 重新标记时据此撤销原来的训练
+	UserID    uint      `json:"user_id"`            // 标记的管理员
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (*SpamLabel) TableName() string {
+	return "spam_label"
+}
+
+// SpamFeature 特征在垃圾评论和正常评论样本中出现的次数
+type SpamFeature struct {
+	Feature string `json:"feature" gorm:"primaryKey;size:64"`
+	Spam    int64  `json:"spam" gorm:"not null;default:0"`
+	Ham     int64  `json:"ham" gorm:"not null;default:0"`
+}
+
+func (*SpamFeature) TableName() string {
+	return "spam_feature"
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// SubscriptionTarget 可以订阅回复通知的内容类型
+type SubscriptionTarget string
+
+const (
+	SubscriptionPost    SubscriptionTarget = "post"    // 文章下的所有新评论
+	SubscriptionComment SubscriptionTarget = "comment" // 自己评论收到的直接回复
+	SubscriptionAll     SubscriptionTarget = "all"     // 仅用于退订链接, 表示退订全部
+)
+
+// Subscription 用户订阅的回复邮件通知
+type Subscription struct {
+	ID         uint               `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint               `json:"user_id" gorm:"uniqueIndex:idx_subscription"`
+	TargetType SubscriptionTarget `json:"target_type" gorm:"type:varchar(20);uniqueIndex:idx_subscription;index:idx_subscription_target"`
+	TargetID   uint               `json:"target_id" gorm:"uniqueIndex:idx_subscription;index:idx_subscription_target"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func (*Subscription) TableName() string {
+	return "subscription"
+}
+
+// ReplyEmail 等待发送的回复通知, 同一用户在一个发送周期内的回复合并为一封邮件
+type ReplyEmail struct {
+	ID         uint               `json:"id" gorm:"autoIncrement;primaryKey"`
+	UserID     uint               `json:"user_id" gorm:"uniqueIndex:idx_reply_email"` // 收件的订阅者
+	User       User               `json:"-" gorm:"foreignKey:UserID"`
+	CommentID  uint               `json:"comment_id" gorm:"uniqueIndex:idx_reply_email"` // 同一条回复命中多个订阅时只通知一次
+	PostID     uint               `json:"post_id"`
+	TargetType SubscriptionTarget `json:"target_type" gorm:"type:varchar(20)"` // 命中的订阅, 用于生成单独的退订链接
+	TargetID   uint               `json:"target_id"`
+	ActorName  string             `json:"actor_name" gorm:"size:64"`
+	Excerpt    string             `json:"excerpt" gorm:"size:512"`
+	SentAt     *time.Time         `json:"sent_at" gorm:"index"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func (*ReplyEmail) TableName() string {
+	return "reply_email"
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// TrashType 回收站中内容的类型
+type TrashType string
+
+const (
+	TrashPost     TrashType = "post"
+	TrashComment  TrashType = "comment"
+	TrashFavorite TrashType = "favorite"
+)
+
+func (t TrashType) Valid() bool {
+	switch t {
+	case TrashPost, TrashComment, TrashFavorite:
+		return true
+	}
+	return false
+}
+
+// TrashItem 回收站中已删除的文章、评论或收藏夹
+type TrashItem struct {
+	Type      TrashType `json:"type"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`    // 文章标题、评论内容或收藏夹名称
+	OwnerID   uint      `json:"owner_id"` // 文章或评论的作者, 收藏夹的属主
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at" gorm:"-"` // 超过保留期后将被彻底删除的时间
+}
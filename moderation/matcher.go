@@ -0,0 +1,242 @@
+// Package moderation 敏感词匹配, 使用 Aho-Corasick 自动机一次扫描匹配所有敏感词
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Action 命中敏感词后的处理方式
+type Action string
+
+const (
+	ActionMask   Action = "mask"   // 用 * 替换敏感词后发布
+	ActionReview Action = "review" // 保存但不发布, 等待人工审核
+	ActionBlock  Action = "block"  // 拒绝保存
+)
+
+// 处理方式的优先级, 多个词命中时取最严格的
+var actionLevels = map[Action]int{
+	ActionMask:   1,
+	ActionReview: 2,
+	ActionBlock:  3,
+}
+
+func ParseAction(s string) (Action, error) {
+	a := Action(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := actionLevels[a]; !ok {
+		return "", fmt.Errorf("unknown moderation action %q", s)
+	}
+	return a, nil
+}
+
+// Rule 一个敏感词及其处理方式
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+// Match 文本中命中的一个敏感词, Start 和 End 为原文中的字符(rune)下标, 不包含 End
+type Match struct {
+	Word   string `json:"word"`
+	Action Action `json:"action"`
+	Start  int    `json:"-"`
+	End    int    `json:"-"`
+}
+
+// Result 一段文本的匹配结果
+type Result struct {
+	Action  Action  `json:"action"` // 最严格的处理方式, 没有命中时为空
+	Matches []Match `json:"matches"`
+}
+
+// Words 去重后命中的敏感词
+func (r *Result) Words() []string {
+	seen := make(map[string]bool, len(r.Matches))
+	words := make([]string, 0, len(r.Matches))
+	for _, m := range r.Matches {
+		if !seen[m.Word] {
+			seen[m.Word] = true
+			words = append(words, m.Word)
+		}
+	}
+	return words
+}
+
+// Merge 合并另一段文本的匹配结果
+func (r *Result) Merge(other *Result) {
+	r.Matches = append(r.Matches, other.Matches...)
+	if actionLevels[other.Action] > actionLevels[r.Action] {
+		r.Action = other.Action
+	}
+}
+
+type node struct {
+	children map[rune]int
+	fail     int
+	rule     int // 以该节点结尾的规则下标, 没有为 -1
+	output   int // 沿失败指针可以到达的最近的结尾节点, 没有为 -1
+	depth    int
+}
+
+// Matcher 敏感词自动机, 构建后只读, 可以并发使用
+type Matcher struct {
+	nodes []node
+	rules []Rule
+}
+
+// NewMatcher 构建自动机. 匹配时忽略大小写, 并跳过空白和标点, 因此 "敏 感" 和 "敏-感" 都能匹配 "敏感"
+func NewMatcher(rules []Rule) *Matcher {
+	m := &Matcher{nodes: []node{newNode(0)}}
+	for _, rule := range rules {
+		word := normalize(rule.Word)
+		if len(word) == 0 {
+			continue
+		}
+		cur := 0
+		for _, r := range word {
+			next, ok := m.nodes[cur].children[r]
+			if !ok {
+				next = len(m.nodes)
+				m.nodes = append(m.nodes, newNode(m.nodes[cur].depth+1))
+				m.nodes[cur].children[r] = next
+			}
+			cur = next
+		}
+		// 同一个词重复配置时取更严格的处理方式
+		if i := m.nodes[cur].rule; i >= 0 {
+			if actionLevels[rule.Action] > actionLevels[m.rules[i].Action] {
+				m.rules[i].Action = rule.Action
+			}
+			continue
+		}
+		m.nodes[cur].rule = len(m.rules)
+		m.rules = append(m.rules, Rule{Word: rule.Word, Action: rule.Action})
+	}
+	m.build()
+	return m
+}
+
+func newNode(depth int) node {
+	return node{children: make(map[rune]int), rule: -1, output: -1, depth: depth}
+}
+
+// build 按层次遍历计算失败指针
+func (m *Matcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].children {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].children[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].children[r]; ok && next != child {
+				m.nodes[child].fail = next
+			}
+			f := m.nodes[child].fail
+			if m.nodes[f].rule >= 0 {
+				m.nodes[child].output = f
+			} else {
+				m.nodes[child].output = m.nodes[f].output
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 敏感词数量
+func (m *Matcher) Len() int {
+	return len(m.rules)
+}
+
+// Match 查找文本中的所有敏感词
+func (m *Matcher) Match(text string) *Result {
+	result := &Result{Matches: make([]Match, 0)}
+	if len(m.rules) == 0 {
+		return result
+	}
+	// positions 记录参与匹配的字符在原文中的下标
+	var positions []int
+	cur, i := 0, 0
+	for _, r := range text {
+		pos := i
+		i++
+		r, ok := normalizeRune(r)
+		if !ok {
+			continue
+		}
+		positions = append(positions, pos)
+		for cur != 0 {
+			if _, ok := m.nodes[cur].children[r]; ok {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if next, ok := m.nodes[cur].children[r]; ok {
+			cur = next
+		}
+		end := len(positions)
+		for out := cur; out > 0; out = m.nodes[out].output {
+			if m.nodes[out].rule < 0 {
+				continue
+			}
+			rule := m.rules[m.nodes[out].rule]
+			result.Matches = append(result.Matches, Match{
+				Word:   rule.Word,
+				Action: rule.Action,
+				Start:  positions[end-m.nodes[out].depth],
+				End:    positions[end-1] + 1,
+			})
+			if actionLevels[rule.Action] > actionLevels[result.Action] {
+				result.Action = rule.Action
+			}
+		}
+	}
+	return result
+}
+
+// Mask 将命中的敏感词替换为 *, 中间的空白和标点保持不变
+func Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	for _, match := range matches {
+		for i := match.Start; i < match.End && i < len(runes); i++ {
+			if _, ok := normalizeRune(runes[i]); ok {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes)
+}
+
+func normalize(word string) []rune {
+	runes := make([]rune, 0, len(word))
+	for _, r := range word {
+		if r, ok := normalizeRune(r); ok {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// normalizeRune 转为小写, 全角字母数字转为半角, 空白和标点不参与匹配
+func normalizeRune(r rune) (rune, bool) {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return r, false
+	}
+	return unicode.ToLower(r), true
+}
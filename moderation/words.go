@@ -0,0 +1,50 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ParseRules 读取敏感词列表. 每行一个词, 可以用 | 指定处理方式, 例如 "某词|block";
+// 未指定时使用 defaultAction. 空行和以 # 开头的行会被忽略
+func ParseRules(r io.Reader, defaultAction Action) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule := Rule{Word: text, Action: defaultAction}
+		if i := strings.LastIndex(text, "|"); i >= 0 {
+			action, err := ParseAction(text[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			rule.Word, rule.Action = strings.TrimSpace(text[:i]), action
+		}
+		if rule.Word == "" {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadRules 从文件读取敏感词列表
+func LoadRules(path string, defaultAction Action) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRules(f, defaultAction)
+}
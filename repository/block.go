@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"inkgo/model"
+)
+
+type BlockRepository interface {
+	// Block 拉黑用户, 重复拉黑不会报错
+	Block(userID, blockedID uint) error
+	// Unblock 取消拉黑
+	Unblock(userID, blockedID uint) error
+	// List 分页获取用户拉黑的用户, 最近拉黑的在前
+	List(userID uint, page, pageSize int) ([]model.Block, int64, error)
+	// ListBlockers 返回 userIDs 中拉黑了 blockedID 的用户
+	ListBlockers(blockedID uint, userIDs []uint) ([]uint, error)
+	// Mute 屏蔽用户, 重复屏蔽不会报错
+	Mute(userID, mutedID uint) error
+	// Unmute 取消屏蔽
+	Unmute(userID, mutedID uint) error
+	// ListMutes 分页获取用户屏蔽的用户, 最近屏蔽的在前
+	ListMutes(userID uint, page, pageSize int) ([]model.Mute, int64, error)
+	// ListHiddenAuthors 返回 userID 屏蔽的、拉黑的以及拉黑了 userID 的用户, 他们的文章不出现在时间线中
+	ListHiddenAuthors(userID uint) ([]uint, error)
+	Migrate() error
+}
+
+type blockRepository struct {
+	db *gorm.DB
+}
+
+func NewBlockRepository(db *gorm.DB) BlockRepository {
+	return &blockRepository{
+		db: db,
+	}
+}
+
+func (b *blockRepository) Block(userID, blockedID uint) error {
+	return b.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Block{UserID: userID, BlockedID: blockedID}).Error
+}
+
+func (b *blockRepository) Unblock(userID, blockedID uint) error {
+	return b.db.Where("user_id = ? AND blocked_id = ?", userID, blockedID).Delete(&model.Block{}).Error
+}
+
+func (b *blockRepository) List(userID uint, page, pageSize int) ([]model.Block, int64, error) {
+	blocks := make([]model.Block, 0)
+	var total int64
+	db := b.db.Model(&model.Block{}).Where("user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Preload("Blocked").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&blocks).Error; err != nil {
+		return nil, 0, err
+	}
+	return blocks, total, nil
+}
+
+func (b *blockRepository) ListBlockers(blockedID uint, userIDs []uint) ([]uint, error) {
+	blockers := make([]uint, 0)
+	if len(userIDs) == 0 {
+		return blockers, nil
+	}
+	err := b.db.Model(&model.Block{}).Where("blocked_id = ? AND user_id IN ?", blockedID, userIDs).
+		Pluck("user_id", &blockers).Error
+	return blockers, err
+}
+
+func (b *blockRepository) Mute(userID, mutedID uint) error {
+	return b.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Mute{UserID: userID, MutedID: mutedID}).Error
+}
+
+func (b *blockRepository) Unmute(userID, mutedID uint) error {
+	return b.db.Where("user_id = ? AND muted_id = ?", userID, mutedID).Delete(&model.Mute{}).Error
+}
+
+func (b *blockRepository) ListMutes(userID uint, page, pageSize int) ([]model.Mute, int64, error) {
+	mutes := make([]model.Mute, 0)
+	var total int64
+	db := b.db.Model(&model.Mute{}).Where("user_id = ?", userID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Preload("Muted").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&mutes).Error; err != nil {
+		return nil, 0, err
+	}
+	return mutes, total, nil
+}
+
+func (b *blockRepository) ListHiddenAuthors(userID uint) ([]uint, error) {
+	var muted, blocked, blockers []uint
+	if err := b.db.Model(&model.Mute{}).Where("user_id = ?", userID).Pluck("muted_id", &muted).Error; err != nil {
+		return nil, err
+	}
+	if err := b.db.Model(&model.Block{}).Where("user_id = ?", userID).Pluck("blocked_id", &blocked).Error; err != nil {
+		return nil, err
+	}
+	if err := b.db.Model(&model.Block{}).Where("blocked_id = ?", userID).Pluck("user_id", &blockers).Error; err != nil {
+		return nil, err
+	}
+	return append(append(muted, blocked...), blockers...), nil
+}
+
+// 自动创建表结构到db
+func (b *blockRepository) Migrate() error {
+	return b.db.AutoMigrate(&model.Block{}, &model.Mute{})
+}
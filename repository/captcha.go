@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"inkgo/database"
+	"time"
+)
+
+const captchaKeyPrefix = "captcha:"
+
+type CaptchaRepository interface {
+	// Save 保存验证码的答案, 过期后自动删除
+	Save(id, answer string, expiration time.Duration) error
+	// Take 取出验证码的答案并删除, 每个验证码只能校验一次; 不存在或已过期时返回空字符串
+	Take(id string) (string, error)
+}
+
+type captchaRepository struct {
+	rdb *database.RedisDB
+}
+
+func NewCaptchaRepository(rdb *database.RedisDB) CaptchaRepository {
+	return &captchaRepository{
+		rdb: rdb,
+	}
+}
+
+func (c *captchaRepository) Save(id, answer string, expiration time.Duration) error {
+	return c.rdb.Set(context.Background(), captchaKeyPrefix+id, answer, expiration)
+}
+
+func (c *captchaRepository) Take(id string) (string, error) {
+	return c.rdb.GetDel(context.Background(), captchaKeyPrefix+id)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"gorm.io/gorm"
 	"inkgo/model"
+	"time"
 )
 
 type commentRepository struct {
@@ -30,7 +31,7 @@ func (c *commentRepository) Delete(id string) error {
 
 func (c *commentRepository) List(pid string) ([]model.Comment, error) {
 	comments := make([]model.Comment, 0)
-	err := c.db.Where("post_id = ?", pid).Find(&comments).Error
+	err := c.db.Where("post_id = ? AND status = ?", pid, model.CommentApproved).Find(&comments).Error
 	return comments, err
 }
 
@@ -53,7 +54,217 @@ func (c *commentRepository) AddReply(comment *model.Comment) (*model.Comment, er
 	return comment, nil
 }
 
+// Get 获取评论
+func (c *commentRepository) Get(id uint) (*model.Comment, error) {
+	comment := new(model.Comment)
+	if err := c.db.First(comment, id).Error; err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (c *commentRepository) GetWithAuthor(id uint) (*model.Comment, error) {
+	comment := new(model.Comment)
+	if err := c.db.Preload("Author").First(comment, id).Error; err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// visibleComments 已通过审核的评论, 以及 viewerID 自己待审核的评论
+func visibleComments(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(comment.status = ? OR (comment.status = ? AND comment.author_id = ?))",
+			model.CommentApproved, model.CommentPending, viewerID)
+	}
+}
+
+// ListThreads 分页获取文章的顶层评论
+func (c *commentRepository) ListThreads(postID, viewerID uint, sort model.CommentSort, page, pageSize int) ([]model.Comment, int64, error) {
+	comments := make([]model.Comment, 0)
+	var total int64
+	db := c.db.Model(&model.Comment{}).Scopes(visibleComments(viewerID)).Where("comment.post_id = ? AND comment.root_id IS NULL", postID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := c.db.Preload(model.AuthorAssociation).Scopes(visibleComments(viewerID)).
+		Where("comment.post_id = ? AND comment.root_id IS NULL", postID)
+	switch sort {
+	case model.CommentOldest:
+		query = query.Order("comment.created_at").Order("comment.id")
+	case model.CommentMostLiked:
+		query = query.Order("comment.like_count desc").Order("comment.id desc")
+	default:
+		query = query.Order("comment.created_at desc").Order("comment.id desc")
+	}
+	if err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// ListReplyPreviews 获取每个楼层最早的 n 条回复及楼层的回复总数
+func (c *commentRepository) ListReplyPreviews(rootIDs []uint, viewerID uint, n int) (map[uint][]model.Comment, map[uint]int64, error) {
+	replies := make(map[uint][]model.Comment, len(rootIDs))
+	counts := make(map[uint]int64, len(rootIDs))
+	if len(rootIDs) == 0 {
+		return replies, counts, nil
+	}
+
+	type result struct {
+		RootID uint
+		Count  int64
+	}
+	results := make([]result, 0)
+	if err := c.db.Model(&model.Comment{}).Scopes(visibleComments(viewerID)).
+		Select("root_id, count(*) AS count").
+		Where("root_id IN ?", rootIDs).
+		Group("root_id").
+		Scan(&results).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, r := range results {
+		counts[r.RootID] = r.Count
+	}
+	if n <= 0 {
+		return replies, counts, nil
+	}
+
+	// 用窗口函数一次取出每个楼层的前 n 条回复
+	ranked := c.db.Model(&model.Comment{}).Scopes(visibleComments(viewerID)).
+		Select("comment.*, ROW_NUMBER() OVER (PARTITION BY comment.root_id ORDER BY comment.id) AS reply_rank").
+		Where("comment.root_id IN ?", rootIDs)
+	previews := make([]model.Comment, 0)
+	if err := c.db.Table("(?) AS comment", ranked).Preload(model.AuthorAssociation).
+		Where("reply_rank <= ?", n).
+		Order("comment.id").
+		Find(&previews).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, reply := range previews {
+		replies[*reply.RootID] = append(replies[*reply.RootID], reply)
+	}
+	return replies, counts, nil
+}
+
+// ListReplies 按游标获取楼层中的回复, 按时间升序
+func (c *commentRepository) ListReplies(rootID, viewerID uint, cursor *model.Cursor, limit int) ([]model.Comment, *model.Cursor, error) {
+	replies := make([]model.Comment, 0, limit+1)
+	if err := c.db.Preload(model.AuthorAssociation).Scopes(visibleComments(viewerID), IDAfter("comment", cursor)).
+		Where("comment.root_id = ?", rootID).
+		Limit(limit + 1).
+		Find(&replies).Error; err != nil {
+		return nil, nil, err
+	}
+	replies, next := cursorPage(replies, limit, func(reply *model.Comment) model.Cursor {
+		return model.Cursor{ID: reply.ID}
+	})
+	return replies, next, nil
+}
+
+// ListByStatus 按状态分页获取评论, 最新的在前, 用于管理员处理待审核和垃圾评论
+func (c *commentRepository) ListByStatus(status model.CommentStatus, page, pageSize int) ([]model.Comment, int64, error) {
+	comments := make([]model.Comment, 0)
+	var total int64
+	if err := c.db.Model(&model.Comment{}).Where("status = ?", status).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := c.db.Preload(model.AuthorAssociation).Where("status = ?", status).
+		Order("id desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+// CountByPost 统计文章的评论数, 包括回复
+func (c *commentRepository) CountByPost(postID, viewerID uint) (int64, error) {
+	var count int64
+	err := c.db.Model(&model.Comment{}).Scopes(visibleComments(viewerID)).Where("comment.post_id = ?", postID).Count(&count).Error
+	return count, err
+}
+
+// Like 点赞评论, 重复点赞不会重复计数
+func (c *commentRepository) Like(commentID, userID uint) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		return addReaction(tx, &model.Reaction{
+			TargetType: model.ReactionComment,
+			TargetID:   commentID,
+			UserID:     userID,
+			Kind:       model.ReactionLike,
+		})
+	})
+}
+
+// Unlike 取消点赞, 没有点赞时什么也不做
+func (c *commentRepository) Unlike(commentID, userID uint) error {
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		return removeReaction(tx, model.ReactionComment, commentID, userID, model.ReactionLike)
+	})
+}
+
+func (c *commentRepository) Edit(comment *model.Comment, editorID uint) (*model.Comment, error) {
+	edited := new(model.Comment)
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		current := new(model.Comment)
+		if err := tx.First(current, comment.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.CommentRevision{
+			CommentID: current.ID,
+			Content:   current.Content,
+			EditorID:  editorID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(current).Updates(map[string]interface{}{
+			"content":   comment.Content,
+			"status":    comment.Status,
+			"edited_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.First(edited, comment.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
+
+func (c *commentRepository) ClaimGuestComments(email string, userID uint) (int64, error) {
+	var claimed int64
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&model.Comment{}).Where("author_id IS NULL AND guest_email = ?", email).
+			Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.Model(&model.Comment{}).Where("id IN ?", ids).Update("author_id", userID).Error; err != nil {
+			return err
+		}
+		claimed = int64(len(ids))
+		return tx.Model(&model.ModerationReview{}).Where("target_type = ? AND target_id IN ?", model.ModerationComment, ids).
+			Update("author_id", userID).Error
+	})
+	return claimed, err
+}
+
+func (c *commentRepository) ListRevisions(commentID uint) ([]model.CommentRevision, error) {
+	revisions := make([]model.CommentRevision, 0)
+	err := c.db.Where("comment_id = ?", commentID).Order("id DESC").Find(&revisions).Error
+	return revisions, err
+}
+
 // 自动创建表结构到db
 func (a *commentRepository) Migrate() error {
-	return a.db.AutoMigrate(&model.Comment{})
+	if err := a.db.AutoMigrate(&model.Comment{}, &model.CommentRevision{}); err != nil {
+		return err
+	}
+	// 之前的回复没有记录所属楼层, 回复的都是顶层评论
+	return a.db.Model(&model.Comment{}).Where("parent_id IS NOT NULL AND root_id IS NULL").
+		UpdateColumn("root_id", gorm.Expr("parent_id")).Error
 }
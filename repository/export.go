@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+	"time"
+)
+
+type ExportRepository interface {
+	// Create 创建导出任务
+	Create(task *model.ExportTask) (*model.ExportTask, error)
+	// Get 根据ID获取导出任务
+	Get(id uint) (*model.ExportTask, error)
+	// ListByUser 列出用户的导出任务, 最新的在前
+	ListByUser(userID uint) ([]model.ExportTask, error)
+	// Claim 领取最早的一个待处理任务并标记为处理中, 没有任务时返回 gorm.ErrRecordNotFound
+	Claim() (*model.ExportTask, error)
+	// Save 保存任务的处理结果
+	Save(task *model.ExportTask) error
+	// ResetRunning 将处理中的任务重置为待处理, 用于服务重启后恢复中断的任务
+	ResetRunning() error
+	// ListFinishedBefore 列出在 before 之前结束的任务, 用于清理过期文件
+	ListFinishedBefore(before time.Time) ([]model.ExportTask, error)
+	// Delete 删除任务记录
+	Delete(id uint) error
+	Migrate() error
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{
+		db: db,
+	}
+}
+
+func (e *exportRepository) Create(task *model.ExportTask) (*model.ExportTask, error) {
+	if err := e.db.Create(task).Error; err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (e *exportRepository) Get(id uint) (*model.ExportTask, error) {
+	task := new(model.ExportTask)
+	if err := e.db.First(task, id).Error; err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (e *exportRepository) ListByUser(userID uint) ([]model.ExportTask, error) {
+	tasks := make([]model.ExportTask, 0)
+	if err := e.db.Where("user_id = ?", userID).Order("id desc").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (e *exportRepository) Claim() (*model.ExportTask, error) {
+	for {
+		task := new(model.ExportTask)
+		// 使用 Find 而不是 First, 队列为空时不打印 record not found 日志
+		result := e.db.Where("status = ?", model.ExportPending).Order("id").Limit(1).Find(task)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+		// 条件更新保证多个实例不会领取同一个任务
+		result = e.db.Model(task).Where("status = ?", model.ExportPending).Update("status", model.ExportRunning)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			task.Status = model.ExportRunning
+			return task, nil
+		}
+	}
+}
+
+func (e *exportRepository) Save(task *model.ExportTask) error {
+	return e.db.Save(task).Error
+}
+
+func (e *exportRepository) ResetRunning() error {
+	return e.db.Model(&model.ExportTask{}).Where("status = ?", model.ExportRunning).
+		Update("status", model.ExportPending).Error
+}
+
+func (e *exportRepository) ListFinishedBefore(before time.Time) ([]model.ExportTask, error) {
+	tasks := make([]model.ExportTask, 0)
+	if err := e.db.Where("finished_at < ?", before).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (e *exportRepository) Delete(id uint) error {
+	return e.db.Delete(&model.ExportTask{}, id).Error
+}
+
+func (e *exportRepository) Migrate() error {
+	return e.db.AutoMigrate(&model.ExportTask{})
+}
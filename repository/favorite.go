@@ -3,9 +3,13 @@ package repository
 import (
 	"errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"inkgo/model"
 )
 
+// ErrFavoriteNotFound 收藏夹不存在, 或当前用户无权操作
+var ErrFavoriteNotFound = errors.New("收藏夹不存在或不属于当前用户")
+
 type favoriteRepository struct {
 	db *gorm.DB
 }
@@ -57,17 +61,18 @@ func (f *favoriteRepository) UpdateFavorite(userID uint, favorite *model.Favorit
 		return nil, gorm.ErrRecordNotFound
 	}
 
-	if err := f.db.Updates(favorite).Error; err != nil {
+	// 明确更新 public, 否则取消公开时 false 会被 Updates 忽略
+	if err := f.db.Select("Name", "Desc", "Public").Where("user_id = ?", userID).Updates(favorite).Error; err != nil {
 		return nil, err
 	}
 	return favorite, nil
 }
 
-// GetFavoriteByID 根据收藏夹ID获取收藏夹信息
+// GetFavoriteByID 根据收藏夹ID获取收藏夹信息, 公开的收藏夹所有人可见
 func (f *favoriteRepository) GetFavoriteByID(userID uint, favoriteID uint) (*model.Favorite, error) {
 	var favorite model.Favorite
-	err := f.db.Preload(model.PostAssociation).
-		Where("id = ? AND user_id = ?", favoriteID, userID).
+	err := f.db.Preload(model.PostAssociation, listedPosts(userID)).Preload("User", publicUser).
+		Where("id = ? AND (user_id = ? OR is_public = ?)", favoriteID, userID, true).
 		First(&favorite).Error
 	if err != nil {
 		return nil, err
@@ -78,59 +83,146 @@ func (f *favoriteRepository) GetFavoriteByID(userID uint, favoriteID uint) (*mod
 // GetFavoritesByUserID 获取用户的收藏列表
 func (f *favoriteRepository) GetFavoritesByUserID(userID uint) ([]model.Favorite, error) {
 	var favorites []model.Favorite
-	err := f.db.Where("user_id = ?", userID).Preload(model.PostAssociation).Find(&favorites).Error
+	err := f.db.Where("user_id = ?", userID).Preload(model.PostAssociation, listedPosts(userID)).Find(&favorites).Error
 	if err != nil {
 		return nil, err
 	}
 	return favorites, nil
 }
 
-// AddPostToFavorite 添加文章到收藏夹
-func (r *favoriteRepository) AddPostToFavorite(userID uint, postID uint, favoriteID uint) error {
-	var favorite model.Favorite
-	if err := r.db.Where("id = ? AND user_id = ?", favoriteID, userID).First(&favorite).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("收藏夹不存在或不属于当前用户")
-		}
+// AddPostToFavorite 添加文章到收藏夹末尾, 已经收藏过时幂等返回
+func (f *favoriteRepository) AddPostToFavorite(userID uint, postID uint, favoriteID uint, note string) error {
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
 		return err
 	}
-
-	// 第二步：判断是否已收藏（避免重复）
-	var count int64
-	err := r.db.Model(&favorite).
-		Where("favorite_id = ? AND post_id = ?", favoriteID, postID).
-		Count(&count).Error
-	if err != nil {
+	var post model.Post
+	if err := f.db.Select("id").First(&post, postID).Error; err != nil {
 		return err
 	}
-	if count > 0 {
-		return nil // 已经收藏过，幂等返回
+	return f.db.Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, favoriteID)
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.FavoritePost{
+			FavoriteID: favoriteID,
+			PostID:     postID,
+			Note:       note,
+			Position:   position,
+		}).Error
+	})
+}
+
+// BatchAddPostsToFavorite 按顺序批量添加文章到收藏夹末尾, 跳过不存在和已收藏的文章, 返回新加入的数量
+func (f *favoriteRepository) BatchAddPostsToFavorite(userID uint, favoriteID uint, postIDs []uint) (int64, error) {
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
+		return 0, err
+	}
+	if len(postIDs) == 0 {
+		return 0, nil
 	}
+	var existing, added []uint
+	if err := f.db.Model(&model.Post{}).Where("id IN ?", postIDs).Pluck("id", &existing).Error; err != nil {
+		return 0, err
+	}
+	if err := f.db.Model(&model.FavoritePost{}).Where("favorite_id = ? AND post_id IN ?", favoriteID, postIDs).
+		Pluck("post_id", &added).Error; err != nil {
+		return 0, err
+	}
+	skip := make(map[uint]bool, len(postIDs))
+	exists := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		exists[id] = true
+	}
+	for _, id := range added {
+		skip[id] = true
+	}
+	var count int64
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, favoriteID)
+		if err != nil {
+			return err
+		}
+		items := make([]model.FavoritePost, 0, len(postIDs))
+		for _, id := range postIDs {
+			if !exists[id] || skip[id] {
+				continue
+			}
+			skip[id] = true
+			items = append(items, model.FavoritePost{FavoriteID: favoriteID, PostID: id, Position: position + len(items)})
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&items)
+		count = result.RowsAffected
+		return result.Error
+	})
+	return count, err
+}
 
-	return r.db.Create(&favorite).Error
+// nextPosition 收藏夹末尾的下一个位置
+func nextPosition(tx *gorm.DB, favoriteID uint) (int, error) {
+	var position int
+	err := tx.Model(&model.FavoritePost{}).Where("favorite_id = ?", favoriteID).
+		Select("COALESCE(MAX(position), 0) + 1").Scan(&position).Error
+	return position, err
 }
 
 // RemovePostFromFavorite 从收藏夹中移除文章
 func (f *favoriteRepository) RemovePostFromFavorite(userID uint, postID uint, favoriteID uint) error {
-
-	var fav model.Favorite
-	if err := f.db.Where("id = ? AND user_id = ?", favoriteID, userID).First(&fav).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("收藏夹不存在或不属于当前用户")
-		}
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
 		return err
 	}
-	var post model.Post
-	if err := f.db.Where("id = ?", postID).First(&post).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("文章不存在")
-		}
-		return err
+	return f.db.Where("favorite_id = ? AND post_id = ?", favoriteID, postID).Delete(&model.FavoritePost{}).Error
+}
+
+// RemoveAllPostsFromFavorite 清空收藏夹, 返回移除的文章数
+func (f *favoriteRepository) RemoveAllPostsFromFavorite(userID uint, favoriteID uint) (int64, error) {
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
+		return 0, err
 	}
+	result := f.db.Where("favorite_id = ?", favoriteID).Delete(&model.FavoritePost{})
+	return result.RowsAffected, result.Error
+}
 
-	// 使用关联删除方法从收藏夹中移除文章
+// UpdatePostNote 修改收藏夹中文章的备注
+func (f *favoriteRepository) UpdatePostNote(userID uint, favoriteID, postID uint, note string) error {
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
+		return err
+	}
+	result := f.db.Model(&model.FavoritePost{}).Where("favorite_id = ? AND post_id = ?", favoriteID, postID).
+		Update("note", note)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
 
-	return f.db.Delete(&post).Error
+// ReorderPosts 按 postIDs 的顺序排列收藏夹中的文章, 未列出的文章保持原来的相对顺序排在后面
+func (f *favoriteRepository) ReorderPosts(userID uint, favoriteID uint, postIDs []uint) error {
+	if err := f.ownedFavorite(userID, favoriteID); err != nil {
+		return err
+	}
+	if len(postIDs) == 0 {
+		return nil
+	}
+	return f.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.FavoritePost{}).Where("favorite_id = ? AND post_id NOT IN ?", favoriteID, postIDs).
+			Update("position", gorm.Expr("position + ?", len(postIDs))).Error; err != nil {
+			return err
+		}
+		for i, id := range postIDs {
+			if err := tx.Model(&model.FavoritePost{}).Where("favorite_id = ? AND post_id = ?", favoriteID, id).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // GetUserFavorites 获取用户收藏夹的文章
@@ -145,7 +237,7 @@ func (f *favoriteRepository) GetUserFavorites(userID uint, page, pageSize int) (
 	}
 
 	err = f.db.Where("user_id = ?", userID).
-		Preload(model.PostAssociation).
+		Preload(model.PostAssociation, listedPosts(userID)).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&favorites).Error
@@ -155,20 +247,31 @@ func (f *favoriteRepository) GetUserFavorites(userID uint, page, pageSize int) (
 	return favorites, total, nil
 }
 
+// GetUserFavoritesByCursor 按游标分页获取用户收藏夹
+func (f *favoriteRepository) GetUserFavoritesByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.Favorite, *model.Cursor, error) {
+	favorites := make([]model.Favorite, 0, limit+1)
+	if err := f.db.Where("user_id = ?", userID).
+		Preload(model.PostAssociation, listedPosts(userID)).
+		Scopes(IDAfter("favorite", cursor)).
+		Limit(limit + 1).
+		Find(&favorites).Error; err != nil {
+		return nil, nil, err
+	}
+	favorites, next := cursorPage(favorites, limit, func(favorite *model.Favorite) model.Cursor {
+		return model.Cursor{ID: favorite.ID}
+	})
+	return favorites, next, nil
+}
+
 // IsPostInFavorite 检查文章是否在收藏夹中
 func (f *favoriteRepository) IsPostInFavorite(userID uint, favoriteID, postID uint) (bool, error) {
 	var count int64
 
-	// 检查收藏夹是否存在
-	var existingFavorite model.Favorite
-	// 这里假设收藏夹名称是唯一的
-	if err := f.db.Where("id = ? AND user_id = ?", favoriteID, userID).First(&existingFavorite).Error; err == nil {
-		// 收藏夹已存在，返回错误
-		return false, gorm.ErrRecordNotFound
+	if err := f.readableFavorite(userID, favoriteID); err != nil {
+		return false, err
 	}
-
-	err := f.db.Model(&model.Favorite{}).
-		Where("id = ? AND EXISTS (SELECT 1 FROM favorite_posts WHERE favorite_id = ? AND post_id = ?)", favoriteID, favoriteID, postID).
+	err := f.db.Model(&model.FavoritePost{}).
+		Where("favorite_id = ? AND post_id = ?", favoriteID, postID).
 		Count(&count).Error
 	if err != nil {
 		return false, err
@@ -190,43 +293,99 @@ func (r *favoriteRepository) isFavoriteOwnedByUser(userID, favoriteID uint) (boo
 	return exists, nil
 }
 
-// ListPostsInFavorite 列出收藏夹中的文章
-func (f *favoriteRepository) ListPostsInFavorite(userID uint, favoriteID uint) ([]model.Post, error) {
-
+// ownedFavorite 收藏夹不属于 userID 时返回 ErrFavoriteNotFound
+func (f *favoriteRepository) ownedFavorite(userID, favoriteID uint) error {
 	exists, err := f.isFavoriteOwnedByUser(userID, favoriteID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if !exists {
-		return nil, errors.New("收藏夹不存在或不属于当前用户")
+		return ErrFavoriteNotFound
 	}
+	return nil
+}
+
+// readableFavorite 收藏夹既不属于 userID 也不公开时返回 ErrFavoriteNotFound
+func (f *favoriteRepository) readableFavorite(userID, favoriteID uint) error {
+	var count int64
+	if err := f.db.Model(&model.Favorite{}).
+		Where("id = ? AND (user_id = ? OR is_public = ?)", favoriteID, userID, true).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrFavoriteNotFound
+	}
+	return nil
+}
+
+// favoritePosts 收藏夹中 viewerID 可见的文章. 收藏之后作者可能删除、撤回文章或修改可见范围, 只返回当前仍然可见的已发布文章
+func favoritePosts(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN post ON post.id = favorite_posts.post_id AND post.deleted_at IS NULL AND post.state = ?", model.PostPublished).
+			Scopes(VisibleTo(viewerID))
+	}
+}
+
+// listedPosts 预加载收藏夹中的文章, 只包含 viewerID 可见的已发布文章. 公开的收藏夹免登录可读, 不返回正文
+func listedPosts(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Omit("content").Where("post.state = ?", model.PostPublished).Scopes(VisibleTo(viewerID))
+	}
+}
+
+// publicUser 预加载用户时只查询公开的字段, 不返回邮箱和手机号
+func publicUser(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "user_name", "avatar")
+}
 
+// ListPostsInFavorite 列出收藏夹中的文章, 按手动排序的位置
+func (f *favoriteRepository) ListPostsInFavorite(userID uint, favoriteID uint) ([]model.Post, error) {
+	if err := f.readableFavorite(userID, favoriteID); err != nil {
+		return nil, err
+	}
 	var posts []model.Post
-	err = f.db.Model(&model.Favorite{}).
-		Where("id = ?", favoriteID).
-		Association(model.PostAssociation).
-		Find(&posts)
+	err := f.db.Model(&model.Post{}).
+		Joins("JOIN favorite_posts ON favorite_posts.post_id = post.id").
+		Where("favorite_posts.favorite_id = ?", favoriteID).
+		Scopes(listedPosts(userID)).
+		Order("favorite_posts.position").Order("favorite_posts.created_at").
+		Find(&posts).Error
 	if err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
+// ListItems 分页列出收藏夹中的文章, 包含备注和收藏时间, 按手动排序的位置
+func (f *favoriteRepository) ListItems(userID uint, favoriteID uint, page, pageSize int) ([]model.FavoritePost, int64, error) {
+	if err := f.readableFavorite(userID, favoriteID); err != nil {
+		return nil, 0, err
+	}
+	items := make([]model.FavoritePost, 0)
+	var total int64
+	db := f.db.Model(&model.FavoritePost{}).Scopes(favoritePosts(userID)).Where("favorite_posts.favorite_id = ?", favoriteID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Select("favorite_posts.*").
+		Preload("Post", func(db *gorm.DB) *gorm.DB { return db.Omit("content") }).Preload("Post.Author", publicUser).
+		Order("favorite_posts.position").Order("favorite_posts.created_at").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
 // CountPostsInFavorite 计算收藏夹中的文章数量
 func (f *favoriteRepository) CountPostsInFavorite(userID uint, favoriteID uint) (int64, error) {
-	exists, err := f.isFavoriteOwnedByUser(userID, favoriteID)
-	if err != nil {
+	if err := f.readableFavorite(userID, favoriteID); err != nil {
 		return 0, err
 	}
-	if !exists {
-		return 0, errors.New("收藏夹不存在或不属于当前用户")
-	}
-
-	// 第二步：获取收藏夹中的文章数量
 	var count int64
-
-	err = f.db.Model(&model.Favorite{}).
-		Where("id = ?", favoriteID).
+	err := f.db.Model(&model.FavoritePost{}).Scopes(favoritePosts(userID)).
+		Where("favorite_posts.favorite_id = ?", favoriteID).
 		Count(&count).Error
 	if err != nil {
 		return 0, err
@@ -234,7 +393,85 @@ func (f *favoriteRepository) CountPostsInFavorite(userID uint, favoriteID uint)
 	return count, nil
 }
 
-// Migrate 自动创建表结构到db
+// ListFavoritesOfPost 查询收藏了文章的收藏夹, 只包含 userID 自己的和公开的收藏夹, 自己的在前
+func (f *favoriteRepository) ListFavoritesOfPost(userID uint, postID uint) ([]model.Favorite, error) {
+	favorites := make([]model.Favorite, 0)
+	err := f.db.Preload("User", publicUser).
+		Where("id IN (SELECT favorite_id FROM favorite_posts WHERE post_id = ?)", postID).
+		Where("user_id = ? OR is_public = ?", userID, true).
+		Order(gorm.Expr("user_id = ? DESC", userID)).Order("id").
+		Find(&favorites).Error
+	return favorites, err
+}
+
+// FollowFavorite 关注收藏夹, 重复关注不会报错
+func (f *favoriteRepository) FollowFavorite(userID uint, favoriteID uint) error {
+	return f.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.FavoriteFollow{UserID: userID, FavoriteID: favoriteID}).Error
+}
+
+// UnfollowFavorite 取消关注收藏夹
+func (f *favoriteRepository) UnfollowFavorite(userID uint, favoriteID uint) error {
+	return f.db.Where("user_id = ? AND favorite_id = ?", userID, favoriteID).Delete(&model.FavoriteFollow{}).Error
+}
+
+// ListFollowedFavorites 分页获取用户关注的收藏夹, 已删除或不再公开的收藏夹不返回
+func (f *favoriteRepository) ListFollowedFavorites(userID uint, page, pageSize int) ([]model.FavoriteFollow, int64, error) {
+	follows := make([]model.FavoriteFollow, 0)
+	var total int64
+	db := f.db.Model(&model.FavoriteFollow{}).
+		Joins("JOIN favorite ON favorite.id = favorite_follow.favorite_id AND favorite.deleted_at IS NULL").
+		Where("favorite_follow.user_id = ? AND favorite.is_public = ?", userID, true)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Select("favorite_follow.*").Preload("Favorite.User", publicUser).
+		Order("favorite_follow.id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&follows).Error; err != nil {
+		return nil, 0, err
+	}
+	return follows, total, nil
+}
+
+// ListFollowedActivity 分页获取关注的收藏夹中新加入的文章, 最近加入的在前
+func (f *favoriteRepository) ListFollowedActivity(userID uint, page, pageSize int) ([]model.FavoriteActivity, int64, error) {
+	activities := make([]model.FavoriteActivity, 0)
+	var total int64
+	db := f.db.Table("favorite_posts").Scopes(favoritePosts(userID)).
+		Joins("JOIN favorite_follow ON favorite_follow.favorite_id = favorite_posts.favorite_id").
+		Joins("JOIN favorite ON favorite.id = favorite_posts.favorite_id AND favorite.deleted_at IS NULL").
+		Joins("JOIN user ON user.id = favorite.user_id").
+		// 关注之前加入的文章不算新动态
+		Where("favorite_follow.user_id = ? AND favorite.is_public = ? AND favorite_posts.created_at >= favorite_follow.created_at", userID, true)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Select("favorite_posts.*, favorite.name AS favorite_name, favorite.user_id AS owner_id, user.user_name AS owner_name").
+		Preload("Post", func(db *gorm.DB) *gorm.DB { return db.Omit("content") }).Preload("Post.Author", publicUser).
+		Order("favorite_posts.created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&activities).Error; err != nil {
+		return nil, 0, err
+	}
+	return activities, total, nil
+}
+
+// Migrate 自动创建表结构到db, favorite_posts 使用自定义的中间表
 func (f *favoriteRepository) Migrate() error {
-	return f.db.AutoMigrate(&model.Favorite{})
+	if err := f.db.SetupJoinTable(&model.Favorite{}, model.PostAssociation, &model.FavoritePost{}); err != nil {
+		return err
+	}
+	// 旧的 public 列迁移到默认私有的 is_public 列, 已有的收藏夹保持原来的公开状态
+	legacy := f.db.Migrator().HasTable(&model.Favorite{}) && f.db.Migrator().HasColumn(&model.Favorite{}, "public")
+	if err := f.db.AutoMigrate(&model.Favorite{}, &model.FavoritePost{}, &model.FavoriteFollow{}); err != nil {
+		return err
+	}
+	if !legacy {
+		return nil
+	}
+	if err := f.db.Exec("UPDATE favorite SET is_public = public").Error; err != nil {
+		return err
+	}
+	return f.db.Migrator().DropColumn(&model.Favorite{}, "public")
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"inkgo/model"
 )
 
@@ -33,42 +34,185 @@ func (r *followRepository) GetFollowerCount(userID uint) (int64, error) {
 
 // GetFollowedList获取用户的关注列表
 func (r *followRepository) GetFollowedList(userID uint, page, pageSize int) ([]model.User, error) {
-	var follows []model.Follow
-	if err := r.db.Where("user_id = ?", userID).Offset((page - 1) * pageSize).Limit(pageSize).Find(&follows).Error; err != nil {
-		return nil, err
+	users := make([]model.User, 0)
+	err := r.db.Joins("JOIN follow ON follow.followed_id = user.id AND follow.deleted_at IS NULL").
+		Where("follow.user_id = ?", userID).Order("follow.id").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	return users, err
+}
+
+// GetFollowerList 获取用户的粉丝列表
+func (r *followRepository) GetFollowerList(userID uint, page, pageSize int) ([]model.User, error) {
+	users := make([]model.User, 0)
+	err := r.db.Joins("JOIN follow ON follow.user_id = user.id AND follow.deleted_at IS NULL").
+		Where("follow.followed_id = ?", userID).Order("follow.id").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	return users, err
+}
+
+// Follow 关注用户, 重复关注不会报错; 以前取消过的关注记录会被恢复
+func (r *followRepository) Follow(userID, followedID uint) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "followed_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"deleted_at": nil}),
+	}).Create(&model.Follow{UserID: userID, FollowedID: followedID}).Error
+}
+
+// Unfollow 取消关注, 直接删除记录以便再次关注
+func (r *followRepository) Unfollow(userID, followedID uint) error {
+	return r.db.Unscoped().Where("user_id = ? AND followed_id = ?", userID, followedID).Delete(&model.Follow{}).Error
+}
+
+// ListFollowing 分页获取用户关注的人, 最近关注的在前
+func (r *followRepository) ListFollowing(userID uint, page, pageSize int) ([]model.FollowUser, int64, error) {
+	return r.listFollowUsers("follow.followed_id", "follow.user_id", userID, page, pageSize)
+}
+
+// ListFollowers 分页获取用户的粉丝, 最近关注的在前
+func (r *followRepository) ListFollowers(userID uint, page, pageSize int) ([]model.FollowUser, int64, error) {
+	return r.listFollowUsers("follow.user_id", "follow.followed_id", userID, page, pageSize)
+}
+
+// listFollowUsers 关联查询关注记录和另一方的用户, mutual 表示另一方与 userID 是否互相关注
+func (r *followRepository) listFollowUsers(other, owner string, userID uint, page, pageSize int) ([]model.FollowUser, int64, error) {
+	users := make([]model.FollowUser, 0)
+	var total int64
+	if err := r.db.Table("follow").Where(owner+" = ? AND follow.deleted_at IS NULL", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
+	err := r.followUsers(other, owner, userID).
+		Order("follow.id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&users).Error
+	return users, total, err
+}
 
-	var followedUsers []model.User
-	for _, follow := range follows {
-		var user model.User
-		if err := r.db.First(&user, follow.FollowedID).Error; err != nil {
-			return nil, err
-		}
-		followedUsers = append(followedUsers, user)
+// followUsers 查询 userID 的关注记录及另一方的用户
+func (r *followRepository) followUsers(other, owner string, userID uint) *gorm.DB {
+	// 互相关注: 存在方向相反的关注记录, 与列表是关注还是粉丝无关
+	reverse := "EXISTS (SELECT 1 FROM follow AS reverse WHERE reverse.user_id = follow.followed_id" +
+		" AND reverse.followed_id = follow.user_id AND reverse.deleted_at IS NULL)"
+	return r.db.Table("follow").Where(owner+" = ? AND follow.deleted_at IS NULL", userID).
+		Select("user.id, user.user_name, user.avatar, user.intro, follow.id AS follow_id, follow.created_at AS followed_at, " + reverse + " AS mutual").
+		Joins("JOIN user ON user.id = " + other)
+}
+
+// ListFriendsOfFriends 推荐 userID 关注的人所关注的用户, 按共同关注数排序
+func (r *followRepository) ListFriendsOfFriends(userID uint, limit int) ([]model.FollowSuggestion, error) {
+	suggestions := make([]model.FollowSuggestion, 0)
+	err := r.db.Table("follow AS mine").
+		Select("user.id, user.user_name, user.avatar, user.intro, COUNT(*) AS mutuals").
+		Joins("JOIN follow AS theirs ON theirs.user_id = mine.followed_id AND theirs.deleted_at IS NULL").
+		Joins("JOIN user ON user.id = theirs.followed_id AND user.deleted_at IS NULL").
+		Where("mine.user_id = ? AND mine.deleted_at IS NULL", userID).
+		Scopes(suggestable(userID)).
+		Group("user.id, user.user_name, user.avatar, user.intro").
+		Order("mutuals DESC, user.id").Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+// ListLikedAuthors 推荐 userID 点赞过的文章的作者, 按点赞的文章数排序
+func (r *followRepository) ListLikedAuthors(userID uint, limit int) ([]model.FollowSuggestion, error) {
+	suggestions := make([]model.FollowSuggestion, 0)
+	err := r.db.Table("reaction").
+		Select("user.id, user.user_name, user.avatar, user.intro, COUNT(*) AS likes").
+		Joins("JOIN post ON post.id = reaction.target_id AND post.deleted_at IS NULL").
+		Joins("JOIN user ON user.id = post.author_id AND user.deleted_at IS NULL").
+		Where("reaction.user_id = ? AND reaction.target_type = ? AND reaction.kind = ?", userID, model.ReactionPost, model.ReactionLike).
+		Scopes(suggestable(userID)).
+		Group("user.id, user.user_name, user.avatar, user.intro").
+		Order("likes DESC, user.id").Limit(limit).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+// suggestable 排除自己、已关注、已注销以及与 userID 之间有拉黑关系的用户
+func suggestable(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user.id <> ? AND user.status <> ?", userID, "deleted").
+			Where("NOT EXISTS (SELECT 1 FROM follow AS followed WHERE followed.user_id = ? AND followed.followed_id = user.id AND followed.deleted_at IS NULL)", userID).
+			Where("NOT EXISTS (SELECT 1 FROM user_block WHERE (user_block.user_id = ? AND user_block.blocked_id = user.id) OR (user_block.user_id = user.id AND user_block.blocked_id = ?))", userID, userID)
 	}
+}
 
-	return followedUsers, nil
+// GetFollowedListByCursor 按游标获取用户关注的人, 最近关注的在前
+func (r *followRepository) GetFollowedListByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error) {
+	return r.listFollowUsersByCursor("follow.followed_id", "follow.user_id", userID, cursor, limit)
 }
 
-// GetFollowerList 获取用户的粉丝列表
-func (r *followRepository) GetFollowerList(userID uint, page, pageSize int) ([]model.User, error) {
-	var follows []model.Follow
-	if err := r.db.Where("followed_id = ?", userID).Offset((page - 1) * pageSize).Limit(pageSize).Find(&follows).Error; err != nil {
-		return nil, err
+// GetFollowerListByCursor 按游标获取用户的粉丝, 最近关注的在前
+func (r *followRepository) GetFollowerListByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error) {
+	return r.listFollowUsersByCursor("follow.user_id", "follow.followed_id", userID, cursor, limit)
+}
+
+// listFollowUsersByCursor 与 listFollowUsers 相同, 游标为关注记录的创建时间和ID
+func (r *followRepository) listFollowUsersByCursor(other, owner string, userID uint, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error) {
+	users := make([]model.FollowUser, 0, limit+1)
+	if err := r.followUsers(other, owner, userID).
+		Scopes(CreatedBefore("follow", cursor)).
+		Limit(limit + 1).
+		Scan(&users).Error; err != nil {
+		return nil, nil, err
 	}
+	users, next := cursorPage(users, limit, func(user *model.FollowUser) model.Cursor {
+		return createdCursor(user.FollowedAt, user.FollowID)
+	})
+	return users, next, nil
+}
 
-	var followerUsers []model.User
-	for _, follow := range follows {
-		var user model.User
-		if err := r.db.First(&user, follow.UserID).Error; err != nil {
-			return nil, err
-		}
-		followerUsers = append(followerUsers, user)
+// IsFollowing 判断 userID 是否关注了 followedID
+func (r *followRepository) IsFollowing(userID, followedID uint) (bool, error) {
+	var count int64
+	if err := r.db.Model(&model.Follow{}).Where("user_id = ? AND followed_id = ?", userID, followedID).Count(&count).Error; err != nil {
+		return false, err
 	}
+	return count > 0, nil
+}
+
+// ListFollowedIDs 获取用户关注的所有用户ID
+func (r *followRepository) ListFollowedIDs(userID uint) ([]uint, error) {
+	ids := make([]uint, 0)
+	err := r.db.Model(&model.Follow{}).Where("user_id = ?", userID).Pluck("followed_id", &ids).Error
+	return ids, err
+}
+
+// ListFollowerIDs 获取用户的所有粉丝ID
+func (r *followRepository) ListFollowerIDs(followedID uint) ([]uint, error) {
+	ids := make([]uint, 0)
+	err := r.db.Model(&model.Follow{}).Where("followed_id = ?", followedID).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// ListPopularFollowed 获取用户关注的人中粉丝数不少于 minFollowers 的用户ID
+func (r *followRepository) ListPopularFollowed(userID uint, minFollowers int64) ([]uint, error) {
+	ids := make([]uint, 0)
+	err := r.db.Model(&model.Follow{}).
+		Where("user_id = ? AND (SELECT COUNT(*) FROM follow AS fans WHERE fans.followed_id = follow.followed_id AND fans.deleted_at IS NULL) >= ?",
+			userID, minFollowers).
+		Pluck("followed_id", &ids).Error
+	return ids, err
+}
+
+// FollowTopic 关注标签或分类, 重复关注不会报错
+func (r *followRepository) FollowTopic(userID uint, target model.FollowTarget, targetID uint) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.TopicFollow{UserID: userID, TargetType: target, TargetID: targetID}).Error
+}
+
+// UnfollowTopic 取消关注标签或分类
+func (r *followRepository) UnfollowTopic(userID uint, target model.FollowTarget, targetID uint) error {
+	return r.db.Where("user_id = ? AND target_type = ? AND target_id = ?", userID, target, targetID).
+		Delete(&model.TopicFollow{}).Error
+}
 
-	return followerUsers, nil
+// ListTopics 获取用户关注的所有标签和分类
+func (r *followRepository) ListTopics(userID uint) ([]model.TopicFollow, error) {
+	topics := make([]model.TopicFollow, 0)
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&topics).Error
+	return topics, err
 }
 
 func (r *followRepository) Migrate() error {
-	return r.db.AutoMigrate(&model.Follow{})
+	return r.db.AutoMigrate(&model.Follow{}, &model.TopicFollow{})
 }
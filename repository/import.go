@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+)
+
+type ImportRepository interface {
+	// ExistingTags 返回给定名称中已存在的标签
+	ExistingTags(names []string) (map[string]bool, error)
+	// ExistingCategories 返回给定名称中已存在的分类
+	ExistingCategories(names []string) (map[string]bool, error)
+	// PostExists 作者是否已有同名文章, 用于跳过重复导入
+	PostExists(authorID uint, title string) (bool, error)
+	// Import 在同一事务中创建文章及缺少的标签和分类, 保留文章的原始时间
+	Import(posts []*model.Post) error
+}
+
+type importRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{
+		db: db,
+	}
+}
+
+func (i *importRepository) ExistingTags(names []string) (map[string]bool, error) {
+	return i.existingNames(&model.Tag{}, names)
+}
+
+func (i *importRepository) ExistingCategories(names []string) (map[string]bool, error) {
+	return i.existingNames(&model.Category{}, names)
+}
+
+func (i *importRepository) existingNames(table interface{}, names []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(names))
+	if len(names) == 0 {
+		return existing, nil
+	}
+	var found []string
+	if err := i.db.Model(table).Where("name IN ?", names).Pluck("name", &found).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range found {
+		existing[name] = true
+	}
+	return existing, nil
+}
+
+func (i *importRepository) PostExists(authorID uint, title string) (bool, error) {
+	var count int64
+	err := i.db.Model(&model.Post{}).Where("author_id = ? AND title = ?", authorID, title).Count(&count).Error
+	return count > 0, err
+}
+
+func (i *importRepository) Import(posts []*model.Post) error {
+	return i.db.Transaction(func(tx *gorm.DB) error {
+		tags := make(map[string]model.Tag)
+		categories := make(map[string]model.Category)
+		for _, post := range posts {
+			for idx, tag := range post.Tags {
+				if cached, ok := tags[tag.Name]; ok {
+					post.Tags[idx] = cached
+					continue
+				}
+				if err := tx.Where("name = ?", tag.Name).FirstOrCreate(&tag).Error; err != nil {
+					return err
+				}
+				tags[tag.Name] = tag
+				post.Tags[idx] = tag
+			}
+			for idx, category := range post.Categories {
+				if cached, ok := categories[category.Name]; ok {
+					post.Categories[idx] = cached
+					continue
+				}
+				if err := tx.Where("name = ?", category.Name).FirstOrCreate(&category).Error; err != nil {
+					return err
+				}
+				categories[category.Name] = category
+				post.Categories[idx] = category
+			}
+			// CreatedAt/UpdatedAt 非零时 gorm 不会覆盖, 原始时间得以保留
+			if err := tx.Omit(model.AuthorAssociation, model.RepostAssociation).Create(post).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
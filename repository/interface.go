@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/casbin/casbin/v2"
 	"inkgo/model"
+	"time"
 )
 
 // 工厂模式接口
@@ -16,9 +17,25 @@ type Repository interface {
 	Like() LikeRepository
 	Favorite() FavoriteRepository
 	Follow() FollowRepository
+	Share() ShareRepository
 	Activity() ActivityRepository
 	Auth() AuthRepository
 	Token() TokenRepository
+	Rank() RankRepository
+	Related() RelatedRepository
+	Repost() RepostRepository
+	Import() ImportRepository
+	Export() ExportRepository
+	Moderation() ModerationRepository
+	Trash() TrashRepository
+	Spam() SpamRepository
+	Reaction() ReactionRepository
+	Mention() MentionRepository
+	Notification() NotificationRepository
+	Block() BlockRepository
+	Captcha() CaptchaRepository
+	Subscription() SubscriptionRepository
+	Timeline() TimelineRepository
 	Close() error
 	Ping(ctx context.Context) error
 	Migrant
@@ -37,6 +54,8 @@ type UserRepository interface {
 	FindByMobile(mobile string) (*model.User, error)
 	FindByEmail(email string) (*model.User, error)
 	List(pageSize int, pageNum int) ([]model.User, int64, error)
+	// ListByCursor 按游标列出用户, 按ID升序
+	ListByCursor(cursor *model.Cursor, limit int) ([]model.User, *model.Cursor, error)
 	// 创建用户（注册）
 	Create(user *model.User) (*model.User, error)
 	// 注销用户
@@ -60,31 +79,72 @@ type UserRepository interface {
 type PostRepository interface {
 	// GetPostByID 根据ID获取文章
 	GetPostByID(uint) (*model.Post, error)
-	// GetPostByName 根据文章名称获取文章
-	GetPostByName(string) (*model.Post, error)
-	// ListHasPublished 列出所有已发布的文章
-	ListHasPublished(page int, pageSize int) ([]model.Post, int64, error)
+	// FindByID 根据ID获取文章及作者, 不增加阅读量
+	FindByID(uint) (*model.Post, error)
+	// GetPostByName 根据文章名称搜索 viewerID 可见的文章
+	GetPostByName(viewerID uint, title string) (*model.Post, error)
+	// ListHasPublished 列出 viewerID 可见的已发布文章
+	ListHasPublished(viewerID uint, page int, pageSize int) ([]model.Post, int64, error)
+	// ListHasPublishedByCursor 按游标列出 viewerID 可见的已发布文章, 返回下一页的游标, 没有下一页时为 nil
+	ListHasPublishedByCursor(viewerID uint, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
 	//ListDrafts 列出所有草稿文章
 	ListDrafts(page int, pageSize int) ([]model.Post, int64, error)
+	// Query 按标签、分类、作者、状态、时间等条件列出 viewerID 可见的文章
+	Query(viewerID uint, query *model.PostQuery, page, pageSize int) ([]model.Post, int64, error)
+	// ListDraftsByCursor 按游标列出草稿文章
+	ListDraftsByCursor(cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
 	// Create 创建一篇文章
 	Create(*model.User, *model.Post) (*model.Post, error)
-	// Update 更新一篇文章
-	Update(*model.Post) (*model.Post, error)
+	// Update 更新一篇文章, version 与数据库不一致时返回 ErrVersionConflict 和最新的文章
+	Update(post *model.Post, version uint) (*model.Post, error)
+	// Autosave 自动保存草稿的标题、正文和封面, 版本规则与 Update 相同
+	Autosave(post *model.Post, version uint) (*model.Post, error)
 	// UpdateStatus 更新文章状态
 	UpdateStatus(id uint, state model.PostState) (*model.Post, error)
+	// UpdateModerated 更新文章状态, 同时保存审核脱敏后的标题和正文
+	UpdateModerated(id uint, title, content string, state model.PostState) (*model.Post, error)
 
 	// Delete 删除一篇文章
 	Delete(uint) error
 	// IncView 增加文章的浏览量
 	IncView(id uint) error
 	// SortByViewCountDesc 按浏览量降序排序文章
-	SortByViewCountDesc(page int, pageSize int) ([]model.Post, int64, error)
+	SortByViewCountDesc(viewerID uint, page int, pageSize int) ([]model.Post, int64, error)
 
 	// ListHotPosts  列出热门文章
-	ListHotPosts(limit int) ([]model.Post, error)
+	ListHotPosts(viewerID uint, limit int) ([]model.Post, error)
 
 	// ListRecentPosts 列出最近的文章
-	ListRecentPosts(limit int) ([]model.Post, error)
+	ListRecentPosts(viewerID uint, limit int) ([]model.Post, error)
+	// ListTimeline 按游标列出 viewerID 时间线中的文章, 按创建时间降序
+	ListTimeline(viewerID uint, filter TimelineFilter, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
+	// ListRecentIDsByAuthors 获取作者们最近发布的 limit 篇文章的ID, 用于重建时间线
+	ListRecentIDsByAuthors(authorIDs []uint, limit int) ([]uint, error)
+
+	// ListByIDs 按给定ID的顺序获取 viewerID 可见的已发布文章
+	ListByIDs(viewerID uint, ids []uint) ([]model.Post, error)
+	// FindDetailByID 获取文章及作者、标签、分类和转载来源, 不增加阅读量
+	FindDetailByID(id uint) (*model.Post, error)
+	// ListByAuthor 获取作者的全部文章, 包括草稿, 按创建时间升序
+	ListByAuthor(authorID uint) ([]model.Post, error)
+	// ListRelatedCorpus 获取参与相关文章计算的已发布文章及其标签和分类
+	ListRelatedCorpus() ([]model.Post, error)
+	// FindRelatedCorpus 获取一篇参与相关文章计算的文章, 不参与计算时返回 gorm.ErrRecordNotFound
+	FindRelatedCorpus(id uint) (*model.Post, error)
+	// CountByMonth 按年月统计 viewerID 可见的已发布文章数, 按时间降序
+	CountByMonth(viewerID uint, filter model.ArchiveFilter) ([]model.ArchiveMonth, error)
+	// CountByDay 按天统计 [start, end) 期间 viewerID 可见的已发布文章数, 只返回有文章的日期
+	CountByDay(viewerID uint, filter model.ArchiveFilter, start, end time.Time) ([]model.CalendarDay, error)
+	// ListByDateRange 列出 [start, end) 期间 viewerID 可见的已发布文章, 按发布时间降序
+	ListByDateRange(viewerID uint, filter model.ArchiveFilter, start, end time.Time, page, pageSize int) ([]model.Post, int64, error)
+	// ListRankStats 获取所有已发布文章的浏览、点赞、评论、收藏数, 用于计算热门排行
+	ListRankStats() ([]model.PostRankStat, error)
+	// ListIDsByFilter 按作者、标签、分类和状态筛选文章ID, 最多返回 limit 个
+	ListIDsByFilter(filter model.BulkFilter, limit int) ([]uint, error)
+	// FindByIDs 获取多篇文章, 不预加载关联, 不保证顺序
+	FindByIDs(ids []uint) ([]model.Post, error)
+	// BulkApply 分批在事务中修改文章, 返回修改失败的文章及原因
+	BulkApply(ids []uint, change model.BulkChange) (map[uint]error, error)
 
 	Migrate() error
 }
@@ -111,16 +171,41 @@ type TagRepository interface {
 
 type CommentRepository interface {
 	Add(comment *model.Comment) (*model.Comment, error)
+	Get(id uint) (*model.Comment, error)
+	// GetWithAuthor 获取评论及其作者, 游客评论的作者为空
+	GetWithAuthor(id uint) (*model.Comment, error)
 	Delete(id string) error
 	List(aid string) ([]model.Comment, error)
+	// ListThreads 分页获取 viewerID 可见的顶层评论
+	ListThreads(postID, viewerID uint, sort model.CommentSort, page, pageSize int) ([]model.Comment, int64, error)
+	// ListReplyPreviews 获取每个楼层最早的 n 条回复, 以及每个楼层的回复总数
+	ListReplyPreviews(rootIDs []uint, viewerID uint, n int) (map[uint][]model.Comment, map[uint]int64, error)
+	// ListReplies 按游标获取楼层中的回复, 按时间升序
+	ListReplies(rootID, viewerID uint, cursor *model.Cursor, limit int) ([]model.Comment, *model.Cursor, error)
+	// ListByStatus 按状态分页获取评论, 最新的在前
+	ListByStatus(status model.CommentStatus, page, pageSize int) ([]model.Comment, int64, error)
+	// CountByPost 统计文章中 viewerID 可见的评论数, 包括回复
+	CountByPost(postID, viewerID uint) (int64, error)
+	// Like 和 Unlike 点赞和取消点赞评论, 重复操作不会改变点赞数
+	Like(commentID, userID uint) error
+	Unlike(commentID, userID uint) error
+	// Edit 修改评论内容和状态, 修改前的内容保存为历史版本
+	Edit(comment *model.Comment, editorID uint) (*model.Comment, error)
+	// ClaimGuestComments 将 email 发表的游客评论归到 userID 名下, 返回认领的评论数
+	ClaimGuestComments(email string, userID uint) (int64, error)
+	// ListRevisions 获取评论的历史版本, 最新的在前
+	ListRevisions(commentID uint) ([]model.CommentRevision, error)
 	Migrate() error
 }
 
 type LikeRepository interface {
+	// LikePost 和 UnLikePost 在事务中点赞和取消点赞, 重复操作不会改变点赞数
 	LikePost(pid, uid uint) error
 	UnLikePost(pid, uid uint) error
 	CountLikes(pid uint) (int64, error)
 	IsLiked(pid, uid uint) (bool, error)
+	// ListLiked 批量判断用户点赞过 pids 中的哪些文章, 用于文章列表
+	ListLiked(uid uint, pids []uint) (map[uint]bool, error)
 	Migrate() error
 }
 
@@ -137,23 +222,42 @@ type FavoriteRepository interface {
 	GetFavoriteByID(userID uint, favoriteID uint) (*model.Favorite, error)
 	// GetUserFavorites 获取某个用户创建的所有收藏夹列表
 	GetUserFavorites(userID uint, page, pageSize int) ([]model.Favorite, int64, error)
+	// GetUserFavoritesByCursor 按游标获取用户的收藏夹列表, 按ID升序
+	GetUserFavoritesByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.Favorite, *model.Cursor, error)
 
 	// 收藏操作（核心）
-	// AddPostToFavorite 添加文章到收藏夹
-	AddPostToFavorite(userID uint, postID uint, favoriteID uint) error
+	// AddPostToFavorite 添加文章到收藏夹末尾, note 为收藏备注
+	AddPostToFavorite(userID uint, postID uint, favoriteID uint, note string) error
+	// BatchAddPostsToFavorite 批量收藏多个文章, 返回新加入的数量
+	BatchAddPostsToFavorite(userID uint, favoriteID uint, postIDs []uint) (int64, error)
 	// RemovePostFromFavorite 移除文章从收藏
 	RemovePostFromFavorite(userID uint, postID uint, favoriteID uint) error
+	// RemoveAllPostsFromFavorite 清空收藏夹, 返回移除的数量
+	RemoveAllPostsFromFavorite(userID uint, favoriteID uint) (int64, error)
 	// IsPostInFavorite 检查文章是否在收藏夹中
 	IsPostInFavorite(userID uint, favoriteID, postID uint) (bool, error)
+	// UpdatePostNote 修改收藏的备注
+	UpdatePostNote(userID uint, favoriteID, postID uint, note string) error
+	// ReorderPosts 手动调整收藏夹中文章的顺序
+	ReorderPosts(userID uint, favoriteID uint, postIDs []uint) error
 
-	// 收藏夹内容查询
+	// 收藏夹内容查询, 公开的收藏夹所有人可读
 	ListPostsInFavorite(userID uint, favoriteID uint) ([]model.Post, error) // 列出收藏夹中的文章
 	CountPostsInFavorite(userID uint, favoriteID uint) (int64, error)       // 获取收藏夹中的文章数量
+	// ListItems 分页列出收藏夹中的文章, 包含备注和收藏时间
+	ListItems(userID uint, favoriteID uint, page, pageSize int) ([]model.FavoritePost, int64, error)
+	// ListFavoritesOfPost 查询某篇文章被哪些收藏夹收藏, 只包含自己的和公开的收藏夹
+	ListFavoritesOfPost(userID uint, postID uint) ([]model.Favorite, error)
+
+	// 关注他人的公开收藏夹
+	FollowFavorite(userID uint, favoriteID uint) error
+	UnfollowFavorite(userID uint, favoriteID uint) error
+	// ListFollowedFavorites 分页获取关注的收藏夹
+	ListFollowedFavorites(userID uint, page, pageSize int) ([]model.FavoriteFollow, int64, error)
+	// ListFollowedActivity 分页获取关注的收藏夹中新加入的文章
+	ListFollowedActivity(userID uint, page, pageSize int) ([]model.FavoriteActivity, int64, error)
 
 	// attach functions
-	//	ListFavoritesOfPost(postID uint)	查询某篇文章被哪些收藏夹收藏（例如：后台统计）
-	//BatchAddPostsToFavorite(favoriteID uint, postIDs []uint)	批量收藏多个文章（性能更优）
-	//RemoveAllPostsFromFavorite(favoriteID uint)	清空收藏夹
 	//FavoriteExists(favoriteID uint) (bool, error)	判断收藏夹是否存在（用于权限验证或幂等）
 	Migrate() error
 }
@@ -168,6 +272,35 @@ type FollowRepository interface {
 	GetFollowedList(userID uint, page, pageSize int) ([]model.User, error)
 	// 获取用户的粉丝列表
 	GetFollowerList(userID uint, page, pageSize int) ([]model.User, error)
+	// GetFollowedListByCursor 按游标获取用户关注的人, 最近关注的在前
+	GetFollowedListByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error)
+	// GetFollowerListByCursor 按游标获取用户的粉丝, 最近关注的在前
+	GetFollowerListByCursor(userID uint, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error)
+	// IsFollowing 判断 userID 是否关注了 followedID
+	IsFollowing(userID, followedID uint) (bool, error)
+	// Follow 关注用户, 重复关注不会报错
+	Follow(userID, followedID uint) error
+	// Unfollow 取消关注
+	Unfollow(userID, followedID uint) error
+	// ListFollowing 分页获取用户关注的人, 包含是否互相关注
+	ListFollowing(userID uint, page, pageSize int) ([]model.FollowUser, int64, error)
+	// ListFollowers 分页获取用户的粉丝, 包含是否互相关注
+	ListFollowers(userID uint, page, pageSize int) ([]model.FollowUser, int64, error)
+	// ListFriendsOfFriends 推荐关注的人所关注的用户
+	ListFriendsOfFriends(userID uint, limit int) ([]model.FollowSuggestion, error)
+	// ListLikedAuthors 推荐点赞过的文章的作者
+	ListLikedAuthors(userID uint, limit int) ([]model.FollowSuggestion, error)
+	// ListFollowedIDs 获取用户关注的所有用户ID
+	ListFollowedIDs(userID uint) ([]uint, error)
+	// ListFollowerIDs 获取用户的所有粉丝ID
+	ListFollowerIDs(followedID uint) ([]uint, error)
+	// ListPopularFollowed 获取用户关注的人中粉丝数不少于 minFollowers 的用户ID
+	ListPopularFollowed(userID uint, minFollowers int64) ([]uint, error)
+	// FollowTopic 和 UnfollowTopic 关注和取消关注标签或分类
+	FollowTopic(userID uint, target model.FollowTarget, targetID uint) error
+	UnfollowTopic(userID uint, target model.FollowTarget, targetID uint) error
+	// ListTopics 获取用户关注的所有标签和分类
+	ListTopics(userID uint) ([]model.TopicFollow, error)
 	Migrate() error
 }
 
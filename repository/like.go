@@ -15,92 +15,65 @@ func NewLikeRepository(db *gorm.DB) LikeRepository {
 	}
 }
 
-// Add 添加用户对某个帖子的点赞
+// LikePost 点赞文章, 点赞保存为 like 表情, 重复点赞不会重复计数
 func (l *likeRepository) LikePost(pid, uid uint) error {
-	// 检查是否已经点赞
-	exists, err := l.IsLiked(uid, pid)
-	if err != nil {
-		return err
-	}
-	if exists {
-		// 减少帖子点赞数
-		if err := l.DecLike(pid); err != nil {
-			return err
-		}
-	}
-
-	// 插入点赞记录
-	like := &model.Likes{
-		PostID:   pid,
-		AuthorID: uid,
-	}
-	if err := l.db.Create(like).Error; err != nil {
-		return err
-	}
-	// 增加帖子点赞数
-	if err := l.IncLike(pid); err != nil {
-		return err
-	}
-
-	return nil
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		return addReaction(tx, &model.Reaction{
+			TargetType: model.ReactionPost,
+			TargetID:   pid,
+			UserID:     uid,
+			Kind:       model.ReactionLike,
+		})
+	})
 }
 
-// Delete 删除用户对某个帖子的点赞
+// UnLikePost 取消点赞文章
 func (l *likeRepository) UnLikePost(pid, uid uint) error {
-	// 检查是否存在点赞记录
-	exists, err := l.IsLiked(uid, pid)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		// 增加帖子点赞数
-		if err := l.IncLike(pid); err != nil {
-			return err
-		}
-	}
-	// 删除点赞记录
-	like := &model.Likes{}
-	err = l.db.Where("post_id = ? and author_id = ?", pid, uid).Delete(like).Error
-	if err != nil {
-		return err
-	}
-	// 减少帖子点赞数
-	if err := l.DecLike(pid); err != nil {
-		return err
-	}
-	return nil
-}
-
-// IncLike 增加帖子的点赞数
-func (r *likeRepository) IncLike(pid uint) error {
-	return r.db.Model(&model.Post{}).Where("id = ?", pid).
-		UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error
-}
-
-func (r *likeRepository) DecLike(pid uint) error {
-	return r.db.Model(&model.Post{}).Where("id = ?", pid).
-		UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		return removeReaction(tx, model.ReactionPost, pid, uid, model.ReactionLike)
+	})
 }
 
 // CountLikes 统计某个帖子的点赞数
 func (l *likeRepository) CountLikes(pid uint) (int64, error) {
 	var count int64
-	like := &model.Likes{}
-
-	if err := l.db.Model(like).Where("post_id = ?", pid).Count(&count).Error; err != nil {
-		return count, err
-	}
-	return count, nil
+	err := l.db.Model(&model.Reaction{}).Scopes(postLikes(pid)).Count(&count).Error
+	return count, err
 }
 
 // IsLiked 判断是否已经点赞
-func (r *likeRepository) IsLiked(userID uint, postID uint) (bool, error) {
+func (l *likeRepository) IsLiked(pid, uid uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.Likes{}).Where("author_id = ? AND post_id = ?", userID, postID).Count(&count).Error
+	err := l.db.Model(&model.Reaction{}).Scopes(postLikes(pid)).Where("user_id = ?", uid).Count(&count).Error
 	return count > 0, err
 }
 
-// 自动创建表结构到db
+// ListLiked 批量判断用户点赞过 pids 中的哪些文章
+func (l *likeRepository) ListLiked(uid uint, pids []uint) (map[uint]bool, error) {
+	liked := make(map[uint]bool)
+	if uid == 0 || len(pids) == 0 {
+		return liked, nil
+	}
+	ids := make([]uint, 0)
+	if err := l.db.Model(&model.Reaction{}).
+		Where("target_type = ? AND target_id IN ? AND user_id = ? AND kind = ?", model.ReactionPost, pids, uid, model.ReactionLike).
+		Pluck("target_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
+
+// postLikes 文章的点赞记录
+func postLikes(pid uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("target_type = ? AND target_id = ? AND kind = ?", model.ReactionPost, pid, model.ReactionLike)
+	}
+}
+
+// 点赞保存在 reaction 表中, 原来 likes 表的数据由 ReactionRepository 迁移
 func (a *likeRepository) Migrate() error {
-	return a.db.AutoMigrate(&model.Likes{})
+	return nil
 }
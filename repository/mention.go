@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"inkgo/model"
+)
+
+type MentionRepository interface {
+	// Replace 将内容的 @ 记录替换为 mentions, 已有的记录保持不变, 返回替换后的全部记录
+	Replace(target model.MentionTarget, targetID uint, mentions []model.Mention) ([]model.Mention, error)
+	// MarkNotified 标记为已通知
+	MarkNotified(ids []uint) error
+	// ListByTargets 批量获取多篇内容的 @ 记录
+	ListByTargets(target model.MentionTarget, targetIDs []uint) (map[uint][]model.Mention, error)
+	Migrate() error
+}
+
+type mentionRepository struct {
+	db *gorm.DB
+}
+
+func NewMentionRepository(db *gorm.DB) MentionRepository {
+	return &mentionRepository{
+		db: db,
+	}
+}
+
+func (m *mentionRepository) Replace(target model.MentionTarget, targetID uint, mentions []model.Mention) ([]model.Mention, error) {
+	result := make([]model.Mention, 0, len(mentions))
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		userIDs := make([]uint, len(mentions))
+		for i := range mentions {
+			mentions[i].ID = 0
+			mentions[i].TargetType, mentions[i].TargetID = target, targetID
+			userIDs[i] = mentions[i].UserID
+		}
+		query := tx.Where("target_type = ? AND target_id = ?", target, targetID)
+		if len(userIDs) > 0 {
+			query = query.Where("user_id NOT IN ?", userIDs)
+		}
+		if err := query.Delete(&model.Mention{}).Error; err != nil {
+			return err
+		}
+		if len(mentions) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&mentions).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("target_type = ? AND target_id = ?", target, targetID).Order("id").Find(&result).Error
+	})
+	return result, err
+}
+
+func (m *mentionRepository) MarkNotified(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.db.Model(&model.Mention{}).Where("id IN ?", ids).Update("notified", true).Error
+}
+
+func (m *mentionRepository) ListByTargets(target model.MentionTarget, targetIDs []uint) (map[uint][]model.Mention, error) {
+	result := make(map[uint][]model.Mention, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return result, nil
+	}
+	mentions := make([]model.Mention, 0)
+	if err := m.db.Where("target_type = ? AND target_id IN ?", target, targetIDs).Order("id").Find(&mentions).Error; err != nil {
+		return nil, err
+	}
+	for _, mention := range mentions {
+		result[mention.TargetID] = append(result[mention.TargetID], mention)
+	}
+	return result, nil
+}
+
+// 自动创建表结构到db
+func (m *mentionRepository) Migrate() error {
+	return m.db.AutoMigrate(&model.Mention{})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+	"time"
+)
+
+type ModerationRepository interface {
+	// Submit 提交待审核的内容, 同一内容已有待审核记录时更新该记录
+	Submit(review *model.ModerationReview) (*model.ModerationReview, error)
+	// Cancel 内容修改后不再需要审核时, 删除其待审核记录
+	Cancel(targetType model.ModerationTarget, targetID uint) error
+	// Get 根据ID获取审核记录
+	Get(id uint) (*model.ModerationReview, error)
+	// List 按状态列出审核记录, status 为空时列出全部, 最早提交的在前
+	List(status model.ModerationStatus, page, pageSize int) ([]model.ModerationReview, int64, error)
+	// Approve 审核通过并发布内容
+	Approve(review *model.ModerationReview, reviewerID uint) error
+	// Reject 审核不通过, 文章退回草稿, 评论隐藏
+	Reject(review *model.ModerationReview, reviewerID uint, reason string) error
+	Migrate() error
+}
+
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &moderationRepository{
+		db: db,
+	}
+}
+
+func (m *moderationRepository) Submit(review *model.ModerationReview) (*model.ModerationReview, error) {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		existing := new(model.ModerationReview)
+		result := tx.Where("target_type = ? AND target_id = ? AND status = ?",
+			review.TargetType, review.TargetID, model.ModerationPending).Limit(1).Find(existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			review.Status = model.ModerationPending
+			return tx.Create(review).Error
+		}
+		existing.Excerpt, existing.Words = review.Excerpt, review.Words
+		*review = *existing
+		return tx.Save(review).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (m *moderationRepository) Cancel(targetType model.ModerationTarget, targetID uint) error {
+	return m.db.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, model.ModerationPending).
+		Delete(&model.ModerationReview{}).Error
+}
+
+func (m *moderationRepository) Get(id uint) (*model.ModerationReview, error) {
+	review := new(model.ModerationReview)
+	if err := m.db.Preload(model.AuthorAssociation).First(review, id).Error; err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+func (m *moderationRepository) List(status model.ModerationStatus, page, pageSize int) ([]model.ModerationReview, int64, error) {
+	reviews := make([]model.ModerationReview, 0)
+	var total int64
+	db := m.db.Model(&model.ModerationReview{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Preload(model.AuthorAssociation).
+		Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+func (m *moderationRepository) Approve(review *model.ModerationReview, reviewerID uint) error {
+	return m.resolve(review, reviewerID, model.ModerationApproved, "", model.PostPublished, model.CommentApproved)
+}
+
+func (m *moderationRepository) Reject(review *model.ModerationReview, reviewerID uint, reason string) error {
+	return m.resolve(review, reviewerID, model.ModerationRejected, reason, model.PostDraft, model.CommentHidden)
+}
+
+// resolve 在同一个事务中更新审核记录和内容的状态
+func (m *moderationRepository) resolve(review *model.ModerationReview, reviewerID uint, status model.ModerationStatus,
+	reason string, postState model.PostState, commentStatus model.CommentStatus) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 只处理仍在待审核的记录, 防止两个管理员同时审核
+		result := tx.Model(review).Where("status = ?", model.ModerationPending).Updates(map[string]interface{}{
+			"status":      status,
+			"reason":      reason,
+			"reviewer_id": reviewerID,
+			"reviewed_at": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		review.Status, review.Reason, review.ReviewerID, review.ReviewedAt = status, reason, &reviewerID, &now
+
+		switch review.TargetType {
+		case model.ModerationPost:
+			return tx.Model(&model.Post{}).Where("id = ? AND state = ?", review.TargetID, model.PostPending).
+				Updates(map[string]interface{}{
+					"state":   postState,
+					"version": gorm.Expr("version + 1"),
+				}).Error
+		case model.ModerationComment:
+			return tx.Model(&model.Comment{}).Where("id = ?", review.TargetID).
+				Update("status", commentStatus).Error
+		}
+		return nil
+	})
+}
+
+// automatically create the table if it does not exist
+func (m *moderationRepository) Migrate() error {
+	return m.db.AutoMigrate(&model.ModerationReview{})
+}
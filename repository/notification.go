@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+	"time"
+)
+
+type NotificationRepository interface {
+	// Create 批量创建通知
+	Create(notifications []model.Notification) error
+	// List 分页获取用户的通知, 最新的在前; unread 为 true 时只返回未读通知
+	List(userID uint, unread bool, page, pageSize int) ([]model.Notification, int64, error)
+	// CountUnread 统计未读通知数
+	CountUnread(userID uint) (int64, error)
+	// MarkRead 将用户的通知标记为已读, ids 为空时标记全部
+	MarkRead(userID uint, ids []uint) error
+	Migrate() error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+func (n *notificationRepository) Create(notifications []model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return n.db.Omit("Actor").Create(&notifications).Error
+}
+
+func (n *notificationRepository) List(userID uint, unread bool, page, pageSize int) ([]model.Notification, int64, error) {
+	notifications := make([]model.Notification, 0)
+	var total int64
+	db := n.db.Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unread {
+		db = db.Where("read_at IS NULL")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Preload("Actor").Order("id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
+
+func (n *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := n.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func (n *notificationRepository) MarkRead(userID uint, ids []uint) error {
+	db := n.db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	return db.Update("read_at", time.Now()).Error
+}
+
+// 自动创建表结构到db
+func (n *notificationRepository) Migrate() error {
+	return n.db.AutoMigrate(&model.Notification{})
+}
@@ -1,9 +1,14 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"inkgo/model"
+	"sort"
+	"strings"
+	"time"
 )
 
 type postRepository struct {
@@ -22,8 +27,8 @@ func (a *postRepository) GetPostByID(id uint) (*model.Post, error) {
 	//文章关联了,creator, tag, category, comments, comment.user(评论的用户)
 	// gorm.Preload() 会自动填充结构体的关联字段，gin 会自动将结构体转为 JSON，
 	if err := a.db.Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
-		Preload("Comments.Author").Preload(model.CommentsAssociation).
-		Find(Post).Error; err != nil {
+		Preload("Comments.Author").Preload(model.CommentsAssociation).Preload(model.RepostAssociation).
+		First(Post, id).Error; err != nil {
 		return nil, err
 	}
 	// 获取文章的点赞数
@@ -40,6 +45,15 @@ func (a *postRepository) GetPostByID(id uint) (*model.Post, error) {
 	return Post, nil
 }
 
+// FindByID 获取文章及作者和转载来源, 供内部校验使用, 不增加阅读量
+func (p *postRepository) FindByID(id uint) (*model.Post, error) {
+	post := new(model.Post)
+	if err := p.db.Preload(model.AuthorAssociation).Preload(model.RepostAssociation).First(post, id).Error; err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
 // 按创建时间降序排列文章
 func (p *postRepository) SortByTimeOfCreation() ([]model.Post, error) {
 	var posts []model.Post
@@ -73,24 +87,23 @@ func (p *postRepository) SortByViewLike() ([]model.Post, error) {
 // 统计文章的点赞数
 func (p *postRepository) CountLike(id uint) (int64, error) {
 	var count int64
-	// select count(*) from likes where post_id = ?
-	if err := p.db.Model(&model.Likes{}).Where("post_id = ?", id).Count(&count).Error; err != nil {
+	if err := p.db.Model(&model.Reaction{}).Scopes(postLikes(id)).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
 // 通过名字获取文章,这是个搜索功能
-func (a *postRepository) GetPostByName(title string) (*model.Post, error) {
+func (a *postRepository) GetPostByName(viewerID uint, title string) (*model.Post, error) {
 	post := new(model.Post)
-	if err := a.db.Where("title = ?", title).First(post).Error; err != nil {
+	if err := a.db.Preload(model.RepostAssociation).Scopes(VisibleTo(viewerID)).Where("title = ?", title).First(post).Error; err != nil {
 		return nil, err
 	}
 	return post, nil
 }
 
 // 获取所有的已发布文章
-func (p *postRepository) ListHasPublished(page, pageSize int) ([]model.Post, int64, error) {
+func (p *postRepository) ListHasPublished(viewerID uint, page, pageSize int) ([]model.Post, int64, error) {
 	posts := make([]model.Post, 0)
 
 	var total int64
@@ -101,13 +114,14 @@ func (p *postRepository) ListHasPublished(page, pageSize int) ([]model.Post, int
 		// 表示按照创建时间顺序来获取输出
 	*/
 
-	db := p.db.Model(&model.Post{})
+	db := p.db.Model(&model.Post{}).Scopes(VisibleTo(viewerID))
 	// 计算总数
 	if err := db.Where(" state = ?", model.PostPublished).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	if err := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID)).
 		Where(" state = ?", model.PostPublished).
 		Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: true}).
 		Offset((page - 1) * pageSize).
@@ -116,6 +130,38 @@ func (p *postRepository) ListHasPublished(page, pageSize int) ([]model.Post, int
 		return nil, 0, err
 	}
 
+	if err := p.fillLikeCount(posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ListHasPublishedByCursor 按游标获取已发布文章, 按创建时间降序
+func (p *postRepository) ListHasPublishedByCursor(viewerID uint, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	posts := make([]model.Post, 0, limit+1)
+	if err := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID), CreatedBefore("post", cursor)).
+		Where("post.state = ?", model.PostPublished).
+		Limit(limit + 1).
+		Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	posts, next := cursorPage(posts, limit, postCursor)
+	if err := p.fillLikeCount(posts); err != nil {
+		return nil, nil, err
+	}
+	return posts, next, nil
+}
+
+func postCursor(post *model.Post) model.Cursor {
+	return createdCursor(post.CreatedAt, post.ID)
+}
+
+// fillLikeCount 批量统计文章的点赞数
+func (p *postRepository) fillLikeCount(posts []model.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
 	ids := make([]uint, len(posts))
 	for i, Post := range posts {
 		ids[i] = Post.ID
@@ -127,8 +173,10 @@ func (p *postRepository) ListHasPublished(page, pageSize int) ([]model.Post, int
 	}
 
 	results := []result{}
-	if err := p.db.Model(&model.Likes{}).Select("post_id as id, count(likes.post_id) as likes").Where("post_id in ?", ids).Group("post_id").Scan(&results).Error; err != nil {
-		return nil, 0, err
+	if err := p.db.Model(&model.Reaction{}).Select("target_id as id, count(*) as likes").
+		Where("target_type = ? AND target_id in ? AND kind = ?", model.ReactionPost, ids, model.ReactionLike).
+		Group("target_id").Scan(&results).Error; err != nil {
+		return err
 	}
 
 	resMap := make(map[uint]uint, len(results))
@@ -139,7 +187,7 @@ func (p *postRepository) ListHasPublished(page, pageSize int) ([]model.Post, int
 	for i := range posts {
 		posts[i].LikeCount = resMap[posts[i].ID]
 	}
-	return posts, total, nil
+	return nil
 }
 
 // 获取所有的未发布文章
@@ -165,6 +213,40 @@ func (p *postRepository) ListDrafts(page, pageSize int) ([]model.Post, int64, er
 	return posts, total, nil
 }
 
+// Query 按筛选条件列出 viewerID 可见的文章
+func (p *postRepository) Query(viewerID uint, query *model.PostQuery, page, pageSize int) ([]model.Post, int64, error) {
+	posts := make([]model.Post, 0)
+	var total int64
+	if err := p.db.Model(&model.Post{}).Scopes(VisibleTo(viewerID), PostQuery(query)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID), PostQuery(query), PostOrder(query.Sort)).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := p.fillLikeCount(posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ListDraftsByCursor 按游标获取草稿, 按创建时间降序
+func (p *postRepository) ListDraftsByCursor(cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	posts := make([]model.Post, 0, limit+1)
+	if err := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(CreatedBefore("post", cursor)).
+		Where("post.state = ?", model.PostDraft).
+		Limit(limit + 1).
+		Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	posts, next := cursorPage(posts, limit, postCursor)
+	return posts, next, nil
+}
+
 // 创建文章
 func (a *postRepository) Create(user *model.User, Post *model.Post) (*model.Post, error) {
 	// 检查作者是否存在
@@ -176,19 +258,51 @@ func (a *postRepository) Create(user *model.User, Post *model.Post) (*model.Post
 	return Post, err
 }
 
-// 更新文章
-func (a *postRepository) Update(post *model.Post) (*model.Post, error) {
-	var existing model.Post
-	// 检查文章是否存在
-	if result := a.db.First(&existing, post.ID); result.RowsAffected == 0 {
-		return nil, gorm.ErrRecordNotFound // 文章不存在
-	}
-	// 我这omit 添了哪些字段, 但是我update中传了这些字段,还是被修改了为啥?
-	err := a.db.Model(&existing).Omit("view_count", "author_id", "view_count", "like_count", "user_liked", "state").Updates(&post).Error
-	if err != nil {
+// ErrVersionConflict 文章的版本号与数据库中的不一致, 说明文章在读取之后已被他人修改
+var ErrVersionConflict = errors.New("文章已被他人修改, 请合并后重试")
+
+// 更新文章, version 为客户端读取文章时的版本号, 版本不一致时返回 ErrVersionConflict 和最新的文章
+func (a *postRepository) Update(post *model.Post, version uint) (*model.Post, error) {
+	return a.updateWithVersion(post.ID, version, func(tx *gorm.DB) *gorm.DB {
+		post.Version = version + 1
+		return tx.Omit("view_count", "author_id", "like_count", "user_liked", "state", model.RepostAssociation).Updates(post)
+	})
+}
+
+// Autosave 自动保存草稿, 只更新标题、正文和封面, 版本规则与 Update 相同
+func (a *postRepository) Autosave(post *model.Post, version uint) (*model.Post, error) {
+	return a.updateWithVersion(post.ID, version, func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("title", "content", "cover", "version").Updates(&model.Post{
+			Title:   post.Title,
+			Content: post.Content,
+			Cover:   post.Cover,
+			Version: version + 1,
+		})
+	})
+}
+
+// updateWithVersion 在事务中执行带版本号条件的更新, 没有更新到记录时回滚(包括关联的更新)
+func (a *postRepository) updateWithVersion(id, version uint, update func(tx *gorm.DB) *gorm.DB) (*model.Post, error) {
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		result := update(tx.Model(&model.Post{Model: gorm.Model{ID: id}}).Where("version = ?", version))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrVersionConflict) {
 		return nil, err
 	}
-	return post, err
+
+	// 无论成功还是冲突都返回数据库中最新的文章, 冲突时客户端据此合并
+	current, findErr := a.FindByID(id)
+	if findErr != nil {
+		return nil, findErr
+	}
+	return current, err
 }
 
 // 更新文章的状态, 例如从草稿到已发布
@@ -199,10 +313,34 @@ func (p *postRepository) UpdateStatus(id uint, state model.PostState) (*model.Po
 		return nil, gorm.ErrRecordNotFound // 文章不存在
 	}
 
-	// 更新文章状态
-	if err := p.db.Model(Post).Update("state", state).Error; err != nil {
+	// 更新文章状态, 同时递增版本号, 使编辑中的旧版本失效
+	if err := p.db.Model(Post).Updates(map[string]interface{}{
+		"state":   state,
+		"version": gorm.Expr("version + 1"),
+	}).Error; err != nil {
 		return nil, err
 	}
+	Post.State = state
+	Post.Version++
+	return Post, nil
+}
+
+// UpdateModerated 更新文章状态并保存脱敏后的标题和正文, 与 UpdateStatus 一样递增版本号
+func (p *postRepository) UpdateModerated(id uint, title, content string, state model.PostState) (*model.Post, error) {
+	Post := &model.Post{Model: gorm.Model{ID: id}}
+	if result := p.db.First(Post); result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := p.db.Model(Post).Updates(map[string]interface{}{
+		"title":   title,
+		"content": content,
+		"state":   state,
+		"version": gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return nil, err
+	}
+	Post.Title, Post.Content, Post.State = title, content, state
+	Post.Version++
 	return Post, nil
 }
 
@@ -247,17 +385,18 @@ func (p *postRepository) CountNumberOfDraft() (int64, error) {
 }
 
 // 根据文章阅读量从高到低排序输出
-func (p *postRepository) SortByViewCountDesc(page int, pageSize int) ([]model.Post, int64, error) {
+func (p *postRepository) SortByViewCountDesc(viewerID uint, page int, pageSize int) ([]model.Post, int64, error) {
 	posts := make([]model.Post, 0)
 	var total int64
 	// 计算总数
-	if err := p.db.Model(&model.Post{}).Where("state = ?", model.PostPublished).Count(&total).Error; err != nil {
+	if err := p.db.Model(&model.Post{}).Scopes(VisibleTo(viewerID)).Where("state = ?", model.PostPublished).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// 按照阅读量降序排列
-	if err := p.db.Order("view_count desc").
+	if err := p.db.Omit("content").Order("view_count desc").
 		Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID)).
 		Where("state = ?", model.PostPublished).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
@@ -274,19 +413,381 @@ func (p *postRepository) SortByViewCountDesc(page int, pageSize int) ([]model.Po
 固定数量展示
 */
 // 列出热门文章
-func (p *postRepository) ListHotPosts(limit int) ([]model.Post, error) {
+func (p *postRepository) ListHotPosts(viewerID uint, limit int) ([]model.Post, error) {
 	posts := make([]model.Post, 0)
-	err := p.db.Where("state = ?", model.PostPublished).Order("view_count desc").Limit(limit).Find(&posts).Error
+	err := p.db.Omit("content").Scopes(VisibleTo(viewerID)).Where("state = ?", model.PostPublished).Order("view_count desc").Limit(limit).Find(&posts).Error
 	return posts, err
 }
 
 // 列出最近的文章
-func (p *postRepository) ListRecentPosts(limit int) ([]model.Post, error) {
+func (p *postRepository) ListRecentPosts(viewerID uint, limit int) ([]model.Post, error) {
 	posts := make([]model.Post, 0)
-	err := p.db.Where("state = ?", model.PostPublished).Order("created_at desc").Limit(limit).Find(&posts).Error
+	err := p.db.Omit("content").Scopes(VisibleTo(viewerID)).Where("state = ?", model.PostPublished).Order("created_at desc").Limit(limit).Find(&posts).Error
 	return posts, err
 }
 
+// TimelineFilter 时间线中文章的来源, 满足任意一个来源即可
+type TimelineFilter struct {
+	PostIDs          []uint // 推送到时间线中的文章
+	AuthorIDs        []uint // 读取时拉取的作者
+	TagIDs           []uint // 关注的标签
+	CategoryIDs      []uint // 关注的分类
+	ExcludeAuthorIDs []uint // 屏蔽或拉黑的作者
+}
+
+func (p *postRepository) ListTimeline(viewerID uint, filter TimelineFilter, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	posts := make([]model.Post, 0, limit+1)
+	sources := make([]string, 0, 4)
+	args := make([]interface{}, 0, 4)
+	if len(filter.PostIDs) > 0 {
+		sources = append(sources, "post.id IN ?")
+		args = append(args, filter.PostIDs)
+	}
+	if len(filter.AuthorIDs) > 0 {
+		sources = append(sources, "post.author_id IN ?")
+		args = append(args, filter.AuthorIDs)
+	}
+	if len(filter.TagIDs) > 0 {
+		sources = append(sources, "post.id IN (SELECT post_id FROM tag_posts WHERE tag_id IN ?)")
+		args = append(args, filter.TagIDs)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		sources = append(sources, "post.id IN (SELECT post_id FROM category_posts WHERE category_id IN ?)")
+		args = append(args, filter.CategoryIDs)
+	}
+	if len(sources) == 0 {
+		return posts, nil, nil
+	}
+	db := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID), CreatedBefore("post", cursor)).
+		Where("post.state = ?", model.PostPublished).
+		Where("("+strings.Join(sources, " OR ")+")", args...)
+	if len(filter.ExcludeAuthorIDs) > 0 {
+		db = db.Where("post.author_id NOT IN ?", filter.ExcludeAuthorIDs)
+	}
+	if err := db.Limit(limit + 1).Find(&posts).Error; err != nil {
+		return nil, nil, err
+	}
+	posts, next := cursorPage(posts, limit, postCursor)
+	if err := p.fillLikeCount(posts); err != nil {
+		return nil, nil, err
+	}
+	return posts, next, nil
+}
+
+func (p *postRepository) ListRecentIDsByAuthors(authorIDs []uint, limit int) ([]uint, error) {
+	ids := make([]uint, 0)
+	if len(authorIDs) == 0 {
+		return ids, nil
+	}
+	err := p.db.Model(&model.Post{}).Where("author_id IN ? AND state = ?", authorIDs, model.PostPublished).
+		Order("created_at DESC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ListByIDs 按给定ID的顺序获取已发布的文章, 用于从缓存的排行榜中还原文章
+func (p *postRepository) ListByIDs(viewerID uint, ids []uint) ([]model.Post, error) {
+	posts := make([]model.Post, 0, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	if err := p.db.Omit("content").Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Scopes(VisibleTo(viewerID)).
+		Where("id in ? AND state = ?", ids, model.PostPublished).
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	// 数据库返回的顺序不确定, 按传入的ID顺序重新排列
+	index := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return index[posts[i].ID] < index[posts[j].ID]
+	})
+	return posts, nil
+}
+
+func (p *postRepository) FindDetailByID(id uint) (*model.Post, error) {
+	post := new(model.Post)
+	if err := p.db.Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Preload(model.RepostAssociation).First(post, id).Error; err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// ListByAuthor 获取作者的全部文章及标签、分类和转载来源
+func (p *postRepository) ListByAuthor(authorID uint) ([]model.Post, error) {
+	posts := make([]model.Post, 0)
+	if err := p.db.Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Preload(model.RepostAssociation).
+		Where("author_id = ?", authorID).Order("created_at").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// ListRelatedCorpus 私密和不公开列出的文章不参与推荐, 加密文章只按标签和分类计算
+func (p *postRepository) ListRelatedCorpus() ([]model.Post, error) {
+	posts := make([]model.Post, 0)
+	if err := p.relatedCorpus().Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		if posts[i].Visibility == model.VisibilityPassword {
+			posts[i].Content = ""
+		}
+	}
+	return posts, nil
+}
+
+// FindRelatedCorpus 获取一篇参与相关文章计算的文章, 文章不参与计算时返回 gorm.ErrRecordNotFound
+func (p *postRepository) FindRelatedCorpus(id uint) (*model.Post, error) {
+	post := new(model.Post)
+	if err := p.relatedCorpus().First(post, id).Error; err != nil {
+		return nil, err
+	}
+	if post.Visibility == model.VisibilityPassword {
+		post.Content = ""
+	}
+	return post, nil
+}
+
+// relatedCorpus 参与相关文章计算的已发布文章及其标签和分类
+func (p *postRepository) relatedCorpus() *gorm.DB {
+	return p.db.Select("id, title, content, visibility").
+		Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Where("state = ? AND visibility NOT IN ?", model.PostPublished,
+			[]model.PostVisibility{model.VisibilityPrivate, model.VisibilityUnlisted})
+}
+
+// publishedArchive 归档统计和列表共用的条件
+func (p *postRepository) publishedArchive(viewerID uint, filter model.ArchiveFilter) *gorm.DB {
+	return p.db.Model(&model.Post{}).Scopes(VisibleTo(viewerID), ArchiveFilter(filter)).
+		Where("post.state = ?", model.PostPublished)
+}
+
+func (p *postRepository) CountByMonth(viewerID uint, filter model.ArchiveFilter) ([]model.ArchiveMonth, error) {
+	months := make([]model.ArchiveMonth, 0)
+	err := p.publishedArchive(viewerID, filter).
+		Select("YEAR(post.created_at) AS year, MONTH(post.created_at) AS month, count(*) AS count").
+		Group("year, month").
+		Order("year desc, month desc").
+		Scan(&months).Error
+	if err != nil {
+		return nil, err
+	}
+	return months, nil
+}
+
+func (p *postRepository) CountByDay(viewerID uint, filter model.ArchiveFilter, start, end time.Time) ([]model.CalendarDay, error) {
+	days := make([]model.CalendarDay, 0)
+	err := p.publishedArchive(viewerID, filter).
+		Select("DATE_FORMAT(post.created_at, '%Y-%m-%d') AS date, count(*) AS count").
+		Where("post.created_at >= ? AND post.created_at < ?", start, end).
+		Group("date").
+		Order("date").
+		Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+func (p *postRepository) ListByDateRange(viewerID uint, filter model.ArchiveFilter, start, end time.Time, page, pageSize int) ([]model.Post, int64, error) {
+	posts := make([]model.Post, 0)
+	var total int64
+	if err := p.publishedArchive(viewerID, filter).
+		Where("post.created_at >= ? AND post.created_at < ?", start, end).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := p.publishedArchive(viewerID, filter).Omit("content").
+		Preload(model.AuthorAssociation).Preload(model.TagsAssociation).Preload(model.CategoryAssociation).
+		Where("post.created_at >= ? AND post.created_at < ?", start, end).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Desc: true}).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts).Error; err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// ListRankStats 统计已发布文章的互动数据
+func (p *postRepository) ListRankStats() ([]model.PostRankStat, error) {
+	stats := make([]model.PostRankStat, 0)
+	err := p.db.Model(&model.Post{}).
+		Select("post.id, post.created_at, post.view_count, "+
+			postLikeCountSQL+" AS like_count, "+
+			"(SELECT count(*) FROM comment WHERE comment.post_id = post.id AND comment.deleted_at IS NULL) AS comment_count, "+
+			"(SELECT count(*) FROM favorite_posts WHERE favorite_posts.post_id = post.id) AS favorite_count").
+		Where("post.state = ? AND post.visibility NOT IN ?", model.PostPublished,
+			[]model.PostVisibility{model.VisibilityPrivate, model.VisibilityUnlisted}).
+		Scan(&stats).Error
+	return stats, err
+}
+
+// 批量操作每个事务处理的文章数
+const bulkChunkSize = 200
+
+// ListIDsByFilter 按条件获取文章ID, 最多返回 limit 个, 用于批量操作
+func (p *postRepository) ListIDsByFilter(filter model.BulkFilter, limit int) ([]uint, error) {
+	ids := make([]uint, 0)
+	db := p.db.Model(&model.Post{}).Scopes(ArchiveFilter(filter.ArchiveFilter))
+	if filter.State != "" {
+		db = db.Where("post.state = ?", filter.State)
+	}
+	if err := db.Order("post.id").Limit(limit).Pluck("post.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FindByIDs 获取文章用于批量操作前的校验, 不包含已删除的文章, 不保证顺序
+func (p *postRepository) FindByIDs(ids []uint) ([]model.Post, error) {
+	posts := make([]model.Post, 0, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	if err := p.db.Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// BulkApply 对文章执行批量修改, 每 bulkChunkSize 篇一个事务, 每篇文章使用单独的保存点,
+// 一篇失败不影响同一事务中的其它文章. 返回失败的文章及原因; 标签、分类或作者不存在时直接返回错误
+func (p *postRepository) BulkApply(ids []uint, change model.BulkChange) (map[uint]error, error) {
+	if err := p.checkBulkTargets(change); err != nil {
+		return nil, err
+	}
+	failed := make(map[uint]error)
+	for start := 0; start < len(ids); start += bulkChunkSize {
+		chunk := ids[start:min(start+bulkChunkSize, len(ids))]
+		chunkFailed := make(map[uint]error)
+		err := p.db.Transaction(func(tx *gorm.DB) error {
+			for _, id := range chunk {
+				if err := tx.Transaction(func(tx *gorm.DB) error {
+					return applyBulkChange(tx, id, change)
+				}); err != nil {
+					chunkFailed[id] = err
+				}
+			}
+			return nil
+		})
+		// 提交失败时整批回滚
+		if err != nil {
+			for _, id := range chunk {
+				chunkFailed[id] = err
+			}
+		}
+		for id, err := range chunkFailed {
+			failed[id] = err
+		}
+	}
+	return failed, nil
+}
+
+// checkBulkTargets 校验批量操作引用的标签、分类和作者都存在
+func (p *postRepository) checkBulkTargets(change model.BulkChange) error {
+	check := func(value interface{}, ids []uint, name string) error {
+		ids = uniqueIDs(ids)
+		if len(ids) == 0 {
+			return nil
+		}
+		var count int64
+		if err := p.db.Model(value).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return fmt.Errorf("%s不存在: %w", name, gorm.ErrRecordNotFound)
+		}
+		return nil
+	}
+	switch change.Operation {
+	case model.BulkAddTags, model.BulkRemoveTags:
+		return check(&model.Tag{}, change.TagIDs, "标签")
+	case model.BulkSetCategories:
+		return check(&model.Category{}, change.CategoryIDs, "分类")
+	case model.BulkTransferAuthor:
+		return check(&model.User{}, []uint{change.AuthorID}, "作者")
+	}
+	return nil
+}
+
+// applyBulkChange 修改一篇文章, 除删除外都会递增版本号, 使编辑中的旧版本失效
+func applyBulkChange(tx *gorm.DB, id uint, change model.BulkChange) error {
+	if change.Operation == model.BulkDelete {
+		result := tx.Delete(&model.Post{}, id)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	}
+
+	updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	switch change.Operation {
+	case model.BulkSetState:
+		updates["state"] = change.State
+		if masked, ok := change.Masked[id]; ok {
+			updates["title"], updates["content"] = masked.Title, masked.Content
+		}
+	case model.BulkTransferAuthor:
+		updates["author_id"] = change.AuthorID
+	}
+	result := tx.Model(&model.Post{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	switch change.Operation {
+	case model.BulkAddTags:
+		return insertPostLinks(tx, "tag_posts", "tag_id", id, change.TagIDs)
+	case model.BulkRemoveTags:
+		if len(change.TagIDs) == 0 {
+			return nil
+		}
+		return tx.Exec("DELETE FROM tag_posts WHERE post_id = ? AND tag_id IN ?", id, change.TagIDs).Error
+	case model.BulkSetCategories:
+		if err := tx.Exec("DELETE FROM category_posts WHERE post_id = ?", id).Error; err != nil {
+			return err
+		}
+		return insertPostLinks(tx, "category_posts", "category_id", id, change.CategoryIDs)
+	}
+	return nil
+}
+
+// insertPostLinks 向多对多的中间表添加关联, 已存在的关联会被忽略
+func insertPostLinks(tx *gorm.DB, table, column string, postID uint, ids []uint) error {
+	ids = uniqueIDs(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, map[string]interface{}{"post_id": postID, column: id})
+	}
+	return tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).Create(rows).Error
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
 // 自动创建表结构到db
 func (a *postRepository) Migrate() error {
 	return a.db.AutoMigrate(&model.Post{})
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"inkgo/database"
+	"strconv"
+)
+
+const (
+	hotPostsKey    = "rank:post:hot"
+	hotPostsTmpKey = "rank:post:hot:tmp"
+)
+
+// RankRepository 基于 Redis 有序集合的文章排行榜
+type RankRepository interface {
+	// ReplaceHotPosts 用新计算的得分整体替换热门文章榜
+	ReplaceHotPosts(ctx context.Context, scores map[uint]float64) error
+	// ListHotPostIDs 按得分降序获取热门文章ID
+	ListHotPostIDs(ctx context.Context, limit int) ([]uint, error)
+}
+
+type rankRepository struct {
+	rdb *database.RedisDB
+}
+
+func NewRankRepository(rdb *database.RedisDB) RankRepository {
+	return &rankRepository{
+		rdb: rdb,
+	}
+}
+
+func (r *rankRepository) ReplaceHotPosts(ctx context.Context, scores map[uint]float64) error {
+	if !r.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	if len(scores) == 0 {
+		return r.rdb.Del(ctx, hotPostsKey)
+	}
+
+	members := make([]*redis.Z, 0, len(scores))
+	for id, score := range scores {
+		members = append(members, &redis.Z{Score: score, Member: strconv.FormatUint(uint64(id), 10)})
+	}
+	// 先写入临时 key 再 RENAME, 读取方不会看到写了一半的榜单
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, hotPostsTmpKey)
+		pipe.ZAdd(ctx, hotPostsTmpKey, members...)
+		pipe.Rename(ctx, hotPostsTmpKey, hotPostsKey)
+		return nil
+	})
+	return err
+}
+
+func (r *rankRepository) ListHotPostIDs(ctx context.Context, limit int) ([]uint, error) {
+	if !r.rdb.Enable() {
+		return nil, database.RedisDisableError
+	}
+	members, err := r.rdb.ZRevRange(ctx, hotPostsKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"inkgo/model"
+)
+
+// postLikeCountSQL 统计文章点赞数的子查询, 用于排序和排行
+const postLikeCountSQL = "(SELECT count(*) FROM reaction WHERE reaction.target_type = 'post' " +
+	"AND reaction.target_id = post.id AND reaction.kind = 'like')"
+
+type ReactionRepository interface {
+	// React 添加表情, 重复添加不会重复计数
+	React(reaction *model.Reaction) error
+	// Unreact 取消表情, 没有添加过时什么也不做
+	Unreact(target model.ReactionTarget, targetID, userID uint, kind model.ReactionKind) error
+	// Count 按 model.ReactionKinds 的顺序统计每种表情的数量, 以及 userID 是否添加过
+	Count(target model.ReactionTarget, targetID, userID uint) ([]model.ReactionCount, error)
+	Migrate() error
+}
+
+type reactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &reactionRepository{
+		db: db,
+	}
+}
+
+func (r *reactionRepository) React(reaction *model.Reaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return addReaction(tx, reaction)
+	})
+}
+
+func (r *reactionRepository) Unreact(target model.ReactionTarget, targetID, userID uint, kind model.ReactionKind) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return removeReaction(tx, target, targetID, userID, kind)
+	})
+}
+
+func (r *reactionRepository) Count(target model.ReactionTarget, targetID, userID uint) ([]model.ReactionCount, error) {
+	type result struct {
+		Kind  model.ReactionKind
+		Count int64
+	}
+	results := make([]result, 0, len(model.ReactionKinds))
+	if err := r.db.Model(&model.Reaction{}).Select("kind, count(*) AS count").
+		Where("target_type = ? AND target_id = ?", target, targetID).
+		Group("kind").Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	var reacted []model.ReactionKind
+	if err := r.db.Model(&model.Reaction{}).
+		Where("target_type = ? AND target_id = ? AND user_id = ?", target, targetID, userID).
+		Pluck("kind", &reacted).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make([]model.ReactionCount, len(model.ReactionKinds))
+	for i, kind := range model.ReactionKinds {
+		counts[i] = model.ReactionCount{Kind: kind, Emoji: kind.Emoji()}
+		for _, r := range results {
+			if r.Kind == kind {
+				counts[i].Count = r.Count
+			}
+		}
+		for _, k := range reacted {
+			if k == kind {
+				counts[i].Reacted = true
+			}
+		}
+	}
+	return counts, nil
+}
+
+// addReaction 在事务中添加表情, 点赞时同时增加文章或评论的点赞数
+func addReaction(tx *gorm.DB, reaction *model.Reaction) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if reaction.Kind != model.ReactionLike {
+		return nil
+	}
+	return tx.Table(string(reaction.TargetType)).Where("id = ?", reaction.TargetID).
+		UpdateColumn("like_count", gorm.Expr("like_count + 1")).Error
+}
+
+// removeReaction 在事务中取消表情, 取消点赞时同时减少点赞数
+func removeReaction(tx *gorm.DB, target model.ReactionTarget, targetID, userID uint, kind model.ReactionKind) error {
+	result := tx.Where("target_type = ? AND target_id = ? AND user_id = ? AND kind = ?", target, targetID, userID, kind).
+		Delete(&model.Reaction{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if kind != model.ReactionLike {
+		return nil
+	}
+	return tx.Table(string(target)).Where("id = ? AND like_count > 0", targetID).
+		UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error
+}
+
+// 自动创建表结构到db, 并把原来 likes 和 comment_like 表中的点赞迁移为 like 表情, 旧表重命名为 likes_migrated 和 comment_like_migrated
+func (r *reactionRepository) Migrate() error {
+	if err := r.db.AutoMigrate(&model.Reaction{}); err != nil {
+		return err
+	}
+	// 已删除的文章和评论的点赞不再迁移
+	legacies := []struct {
+		target model.ReactionTarget
+		table  string
+		query  string
+	}{
+		{model.ReactionPost, "likes", "SELECT ?, likes.post_id, likes.author_id, ?, likes.created_at FROM likes " +
+			"WHERE likes.deleted_at IS NULL AND EXISTS (SELECT 1 FROM post WHERE post.id = likes.post_id) " +
+			"AND NOT EXISTS (SELECT 1 FROM reaction WHERE reaction.target_type = ? " +
+			"AND reaction.target_id = likes.post_id AND reaction.user_id = likes.author_id AND reaction.kind = ?)"},
+		{model.ReactionComment, "comment_like", "SELECT ?, comment_like.comment_id, comment_like.user_id, ?, comment_like.created_at FROM comment_like " +
+			"WHERE EXISTS (SELECT 1 FROM comment WHERE comment.id = comment_like.comment_id) " +
+			"AND NOT EXISTS (SELECT 1 FROM reaction WHERE reaction.target_type = ? " +
+			"AND reaction.target_id = comment_like.comment_id AND reaction.user_id = comment_like.user_id AND reaction.kind = ?)"},
+	}
+	for _, legacy := range legacies {
+		if !r.db.Migrator().HasTable(legacy.table) {
+			continue
+		}
+		result := r.db.Exec("INSERT INTO reaction (target_type, target_id, user_id, kind, created_at) "+legacy.query,
+			legacy.target, model.ReactionLike, legacy.target, model.ReactionLike)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			// 迁移后按新表重新计算点赞数
+			table := string(legacy.target)
+			if err := r.db.Table(table).Where("1 = 1").UpdateColumn("like_count", gorm.Expr(
+				"(SELECT count(*) FROM reaction WHERE reaction.target_type = ? AND reaction.target_id = "+table+".id AND reaction.kind = ?)",
+				legacy.target, model.ReactionLike)).Error; err != nil {
+				return err
+			}
+		}
+		// 迁移完成后重命名旧表作为备份, 避免下次启动时把已经取消的点赞再迁移回来
+		if err := r.db.Migrator().RenameTable(legacy.table, legacy.table+"_migrated"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
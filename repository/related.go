@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"inkgo/database"
+	"strconv"
+)
+
+// RelatedRepository 在 Redis 有序集合中缓存每篇文章的相关文章及相似度
+type RelatedRepository interface {
+	// ReplaceRelated 整体替换多篇文章的相关文章, key 为文章ID
+	ReplaceRelated(ctx context.Context, related map[uint]map[uint]float64) error
+	// AddRelated 将 relatedID 加入 postID 的相关文章, 只保留相似度最高的 size 篇
+	AddRelated(ctx context.Context, postID, relatedID uint, score float64, size int) error
+	// ListRelatedIDs 按相似度降序获取相关文章ID
+	ListRelatedIDs(ctx context.Context, postID uint, limit int) ([]uint, error)
+	// DeleteRelated 删除文章的相关文章缓存
+	DeleteRelated(ctx context.Context, postID uint) error
+}
+
+type relatedRepository struct {
+	rdb *database.RedisDB
+}
+
+func NewRelatedRepository(rdb *database.RedisDB) RelatedRepository {
+	return &relatedRepository{
+		rdb: rdb,
+	}
+}
+
+func relatedKey(postID uint) string {
+	return fmt.Sprintf("related:post:%d", postID)
+}
+
+func (r *relatedRepository) ReplaceRelated(ctx context.Context, related map[uint]map[uint]float64) error {
+	if !r.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for postID, scores := range related {
+			key := relatedKey(postID)
+			pipe.Del(ctx, key)
+			if len(scores) == 0 {
+				continue
+			}
+			members := make([]*redis.Z, 0, len(scores))
+			for id, score := range scores {
+				members = append(members, &redis.Z{Score: score, Member: strconv.FormatUint(uint64(id), 10)})
+			}
+			pipe.ZAdd(ctx, key, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *relatedRepository) AddRelated(ctx context.Context, postID, relatedID uint, score float64, size int) error {
+	if !r.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	key := relatedKey(postID)
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, &redis.Z{Score: score, Member: strconv.FormatUint(uint64(relatedID), 10)})
+		// 按得分升序删除多余的成员, 只保留最高的 size 个
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-size-1))
+		return nil
+	})
+	return err
+}
+
+func (r *relatedRepository) ListRelatedIDs(ctx context.Context, postID uint, limit int) ([]uint, error) {
+	if !r.rdb.Enable() {
+		return nil, database.RedisDisableError
+	}
+	members, err := r.rdb.ZRevRange(ctx, relatedKey(postID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+func (r *relatedRepository) DeleteRelated(ctx context.Context, postID uint) error {
+	if !r.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	return r.rdb.Del(ctx, relatedKey(postID))
+}
@@ -19,6 +19,21 @@ type repository struct {
 	share    ShareRepository
 	token    TokenRepository
 	auth     AuthRepository // 假设有一个 AuthRepository 接口
+	rank     RankRepository
+	related  RelatedRepository
+	repost   RepostRepository
+	imports  ImportRepository
+	export   ExportRepository
+	moderate ModerationRepository
+	trash    TrashRepository
+	spam     SpamRepository
+	reaction ReactionRepository
+	mention  MentionRepository
+	notice   NotificationRepository
+	block    BlockRepository
+	captcha  CaptchaRepository
+	subs     SubscriptionRepository
+	timeline TimelineRepository
 	db       *gorm.DB
 	rdb      *database.RedisDB
 	migrants []Migrant
@@ -38,6 +53,21 @@ func NewRepository(db *gorm.DB, rdb *database.RedisDB) Repository {
 		share:    NewShareRepository(db),
 		token:    NewTokenRepository(rdb),
 		auth:     NewAuthRepository(rdb),
+		rank:     NewRankRepository(rdb),
+		related:  NewRelatedRepository(rdb),
+		repost:   NewRepostRepository(db),
+		imports:  NewImportRepository(db),
+		export:   NewExportRepository(db),
+		moderate: NewModerationRepository(db),
+		trash:    NewTrashRepository(db),
+		spam:     NewSpamRepository(db),
+		reaction: NewReactionRepository(db),
+		mention:  NewMentionRepository(db),
+		notice:   NewNotificationRepository(db),
+		block:    NewBlockRepository(db),
+		captcha:  NewCaptchaRepository(rdb),
+		subs:     NewSubscriptionRepository(db),
+		timeline: NewTimelineRepository(rdb),
 		db:       db,
 		rdb:      rdb,
 	}
@@ -52,6 +82,15 @@ func NewRepository(db *gorm.DB, rdb *database.RedisDB) Repository {
 		r.activity,
 		r.favorite,
 		r.share,
+		r.repost,
+		r.export,
+		r.moderate,
+		r.spam,
+		r.reaction,
+		r.mention,
+		r.notice,
+		r.block,
+		r.subs,
 		r.auth,
 		r.token,
 	)
@@ -92,6 +131,66 @@ func (r *repository) Token() TokenRepository {
 	return r.token
 }
 
+func (r *repository) Rank() RankRepository {
+	return r.rank
+}
+
+func (r *repository) Related() RelatedRepository {
+	return r.related
+}
+
+func (r *repository) Repost() RepostRepository {
+	return r.repost
+}
+
+func (r *repository) Import() ImportRepository {
+	return r.imports
+}
+
+func (r *repository) Export() ExportRepository {
+	return r.export
+}
+
+func (r *repository) Moderation() ModerationRepository {
+	return r.moderate
+}
+
+func (r *repository) Trash() TrashRepository {
+	return r.trash
+}
+
+func (r *repository) Spam() SpamRepository {
+	return r.spam
+}
+
+func (r *repository) Reaction() ReactionRepository {
+	return r.reaction
+}
+
+func (r *repository) Mention() MentionRepository {
+	return r.mention
+}
+
+func (r *repository) Notification() NotificationRepository {
+	return r.notice
+}
+
+func (r *repository) Block() BlockRepository {
+	return r.block
+}
+
+func (r *repository) Captcha() CaptchaRepository {
+	return r.captcha
+}
+
+func (r *repository) Subscription() SubscriptionRepository {
+	return r.subs
+}
+
+func (r *repository) Timeline() TimelineRepository {
+	return r.timeline
+}
+
 func (r *repository) Post() PostRepository {
 	return r.post
 }
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+)
+
+type RepostRepository interface {
+	// Create 在同一事务中创建转载文章和转载记录
+	Create(post *model.Post, repost *model.Repost) (*model.Post, error)
+	// Get 根据ID获取转载记录
+	Get(id uint) (*model.Repost, error)
+	// GetByPostID 获取文章的转载来源
+	GetByPostID(postID uint) (*model.Repost, error)
+	// Save 创建或更新文章的转载来源
+	Save(repost *model.Repost) (*model.Repost, error)
+	// ListRequests 列出某个作者收到的转载授权申请
+	ListRequests(authorID uint, status model.RepostStatus, page, pageSize int) ([]model.Repost, int64, error)
+	// UpdateStatus 更新授权状态和 IsAuthorized, 同时把转载文章的正文更新为 content
+	UpdateStatus(id uint, status model.RepostStatus, content string) (*model.Repost, error)
+	Migrate() error
+}
+
+type repostRepository struct {
+	db *gorm.DB
+}
+
+func NewRepostRepository(db *gorm.DB) RepostRepository {
+	return &repostRepository{
+		db: db,
+	}
+}
+
+func (r *repostRepository) Create(post *model.Post, repost *model.Repost) (*model.Post, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(model.RepostAssociation).Create(post).Error; err != nil {
+			return err
+		}
+		repost.PostID = post.ID
+		return tx.Create(repost).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	post.Repost = repost
+	return post, nil
+}
+
+func (r *repostRepository) Get(id uint) (*model.Repost, error) {
+	repost := new(model.Repost)
+	if err := r.db.First(repost, id).Error; err != nil {
+		return nil, err
+	}
+	return repost, nil
+}
+
+func (r *repostRepository) GetByPostID(postID uint) (*model.Repost, error) {
+	repost := new(model.Repost)
+	if err := r.db.Where("post_id = ?", postID).First(repost).Error; err != nil {
+		return nil, err
+	}
+	return repost, nil
+}
+
+func (r *repostRepository) Save(repost *model.Repost) (*model.Repost, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 登记了来源的文章不再是原创
+		if err := tx.Model(&model.Post{}).Where("id = ?", repost.PostID).Update("original", false).Error; err != nil {
+			return err
+		}
+		existing := new(model.Repost)
+		err := tx.Where("post_id = ?", repost.PostID).First(existing).Error
+		if err == nil {
+			repost.ID = existing.ID
+			repost.CreatedAt = existing.CreatedAt
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		return tx.Save(repost).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repost, nil
+}
+
+func (r *repostRepository) ListRequests(authorID uint, status model.RepostStatus, page, pageSize int) ([]model.Repost, int64, error) {
+	reposts := make([]model.Repost, 0)
+	var total int64
+
+	db := r.db.Model(&model.Repost{}).Where("source_author_id = ?", authorID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&reposts).Error; err != nil {
+		return nil, 0, err
+	}
+	return reposts, total, nil
+}
+
+func (r *repostRepository) UpdateStatus(id uint, status model.RepostStatus, content string) (*model.Repost, error) {
+	repost := new(model.Repost)
+	if result := r.db.First(repost, id); result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(repost).Updates(map[string]interface{}{
+			"status":        status,
+			"is_authorized": status == model.RepostApproved,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Post{}).Where("id = ?", repost.PostID).UpdateColumn("content", content).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repost, nil
+}
+
+// 自动创建表结构到db
+func (r *repostRepository) Migrate() error {
+	return r.db.AutoMigrate(&model.Repost{})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+	"time"
+)
+
+// VisibleTo 过滤出 viewerID 在列表中能看到的文章:
+// 公开和加密的文章, 自己的所有文章, 以及已关注作者的仅粉丝可见文章; 不公开列出(unlisted)的文章只能通过链接访问
+func VisibleTo(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(post.visibility IN ? OR post.author_id = ? OR "+
+			"(post.visibility = ? AND post.author_id IN (SELECT followed_id FROM follow WHERE user_id = ? AND deleted_at IS NULL)))",
+			[]model.PostVisibility{model.VisibilityPublic, model.VisibilityPassword}, viewerID,
+			model.VisibilityFollowers, viewerID)
+	}
+}
+
+// ArchiveFilter 按作者、标签或分类筛选文章
+func ArchiveFilter(filter model.ArchiveFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.AuthorID != 0 {
+			db = db.Where("post.author_id = ?", filter.AuthorID)
+		}
+		if filter.TagID != 0 {
+			db = db.Where("post.id IN (SELECT post_id FROM tag_posts WHERE tag_id = ?)", filter.TagID)
+		}
+		if filter.CategoryID != 0 {
+			db = db.Where("post.id IN (SELECT post_id FROM category_posts WHERE category_id = ?)", filter.CategoryID)
+		}
+		return db
+	}
+}
+
+// CreatedBefore 按创建时间和ID降序的游标分页, 只返回 cursor 之后的记录. 同一时间创建的记录按ID区分, 不会重复或遗漏
+func CreatedBefore(table string, cursor *model.Cursor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Order(table + ".created_at desc").Order(table + ".id desc")
+		if cursor == nil || cursor.At == nil {
+			return db
+		}
+		return db.Where("("+table+".created_at < ? OR ("+table+".created_at = ? AND "+table+".id < ?))",
+			*cursor.At, *cursor.At, cursor.ID)
+	}
+}
+
+// IDAfter 按ID升序的游标分页, 只返回 cursor 之后的记录
+func IDAfter(table string, cursor *model.Cursor) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Order(table + ".id")
+		if cursor == nil {
+			return db
+		}
+		return db.Where(table+".id > ?", cursor.ID)
+	}
+}
+
+// cursorPage 查询时多取一条记录, 有多余的记录时去掉并返回最后一条记录的游标, 否则说明没有下一页
+func cursorPage[T any](items []T, limit int, cursorOf func(item *T) model.Cursor) ([]T, *model.Cursor) {
+	if len(items) <= limit {
+		return items, nil
+	}
+	items = items[:limit]
+	next := cursorOf(&items[limit-1])
+	return items, &next
+}
+
+// createdCursor 按创建时间排序的列表的游标
+func createdCursor(createdAt time.Time, id uint) model.Cursor {
+	return model.Cursor{At: &createdAt, ID: id}
+}
+
+// PostQuery 将文章列表的筛选条件转换为查询条件. 标签和分类使用子查询, 同时筛选多个时不会产生重复的文章
+func PostQuery(query *model.PostQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, id := range query.TagIDs {
+			db = db.Where("post.id IN (SELECT post_id FROM tag_posts WHERE tag_id = ?)", id)
+		}
+		for _, name := range query.TagNames {
+			db = db.Where("post.id IN (SELECT tag_posts.post_id FROM tag_posts JOIN tag ON tag.id = tag_posts.tag_id WHERE tag.name = ?)", name)
+		}
+		for _, id := range query.CategoryIDs {
+			db = db.Where("post.id IN (SELECT post_id FROM category_posts WHERE category_id = ?)", id)
+		}
+		for _, name := range query.CategoryNames {
+			db = db.Where("post.id IN (SELECT category_posts.post_id FROM category_posts JOIN category ON category.id = category_posts.category_id WHERE category.name = ?)", name)
+		}
+		if query.AuthorID != 0 {
+			db = db.Where("post.author_id = ?", query.AuthorID)
+		}
+		if query.State != "" {
+			db = db.Where("post.state = ?", query.State)
+		}
+		if query.From != nil {
+			db = db.Where("post.created_at >= ?", *query.From)
+		}
+		if query.To != nil {
+			db = db.Where("post.created_at < ?", *query.To)
+		}
+		if query.Original != nil {
+			db = db.Where("post.original = ?", *query.Original)
+		}
+		return db
+	}
+}
+
+// postSortColumns 排序字段对应的列或表达式, 只有白名单中的字段可以用于排序
+var postSortColumns = map[model.PostSortKey]string{
+	model.SortCreatedAt:    "post.created_at",
+	model.SortUpdatedAt:    "post.updated_at",
+	model.SortViewCount:    "post.view_count",
+	model.SortTitle:        "post.title",
+	model.SortLikeCount:    postLikeCountSQL,
+	model.SortCommentCount: "(SELECT count(*) FROM comment WHERE comment.post_id = post.id AND comment.deleted_at IS NULL)",
+}
+
+// PostOrder 按白名单中的字段排序, 最后按ID降序保证分页结果稳定
+func PostOrder(sorts []model.PostSort) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(sorts) == 0 {
+			sorts = []model.PostSort{{Key: model.SortCreatedAt, Desc: true}}
+		}
+		for _, sort := range sorts {
+			column, ok := postSortColumns[sort.Key]
+			if !ok {
+				continue
+			}
+			if sort.Desc {
+				column += " DESC"
+			}
+			db = db.Order(column)
+		}
+		return db.Order("post.id DESC")
+	}
+}
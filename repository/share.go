@@ -3,11 +3,21 @@ package repository
 import (
 	"gorm.io/gorm"
 	"inkgo/model"
+	"time"
 )
 
 type ShareRepository interface {
 	Create(share *model.Share) (*model.Share, error)
 	GetShareByID(id string) (*model.Share, error)
+	// GetShareByCode 根据短链接码获取分享
+	GetShareByCode(code string) (*model.Share, error)
+	// FindShare 获取用户对某篇文章已生成的分享
+	FindShare(authorID, postID uint) (*model.Share, error)
+	// RecordClick 记录一次短链接点击
+	RecordClick(click *model.ShareClick) error
+	// GetStats 统计文章自 since 以来的分享和点击数据
+	GetStats(postID uint, since time.Time) (*model.ShareStats, error)
+	Migrate() error
 }
 
 type shareRepository struct {
@@ -22,11 +32,7 @@ func NewShareRepository(db *gorm.DB) ShareRepository {
 
 // 创建一个分享
 func (s *shareRepository) Create(share *model.Share) (*model.Share, error) {
-	shares := model.Share{
-		AuthorID: share.AuthorID,
-		PostID:   share.PostID,
-	}
-	if err := s.db.Create(shares).Error; err != nil {
+	if err := s.db.Create(share).Error; err != nil {
 		return nil, err
 	}
 	return share, nil
@@ -35,12 +41,77 @@ func (s *shareRepository) Create(share *model.Share) (*model.Share, error) {
 // 获取分享
 func (s *shareRepository) GetShareByID(shareID string) (*model.Share, error) {
 	share := new(model.Share)
-	err := s.db.Preload(model.AuthorAssociation).Preload(model.PostAssociation).Preload("Post.Author").
+	err := s.db.Preload(model.AuthorAssociation).Preload("Post").Preload("Post.Author").
 		First(&share, shareID).Error
 	return share, err
 }
 
+func (s *shareRepository) GetShareByCode(code string) (*model.Share, error) {
+	share := new(model.Share)
+	if err := s.db.Where("code = ?", code).First(share).Error; err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *shareRepository) FindShare(authorID, postID uint) (*model.Share, error) {
+	share := new(model.Share)
+	if err := s.db.Where("author_id = ? AND post_id = ?", authorID, postID).First(share).Error; err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *shareRepository) RecordClick(click *model.ShareClick) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(click).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Share{}).Where("id = ?", click.ShareID).
+			UpdateColumn("click_count", gorm.Expr("click_count + 1")).Error
+	})
+}
+
+func (s *shareRepository) GetStats(postID uint, since time.Time) (*model.ShareStats, error) {
+	stats := &model.ShareStats{
+		PostID:    postID,
+		Referrers: make([]model.ShareReferrerStat, 0),
+		Daily:     make([]model.ShareDailyStat, 0),
+	}
+	// 分享时间保存为秒级时间戳
+	if err := s.db.Model(&model.Share{}).Where("post_id = ? AND created_at >= ?", postID, since.Unix()).
+		Count(&stats.ShareCount).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&model.ShareClick{}).Where("post_id = ? AND clicked_at >= ?", postID, since).
+		Count(&stats.ClickCount).Error; err != nil {
+		return nil, err
+	}
+
+	// 按来源统计, 取点击最多的前 20 个
+	if err := s.db.Model(&model.ShareClick{}).
+		Select("referrer, count(*) AS clicks").
+		Where("post_id = ? AND clicked_at >= ?", postID, since).
+		Group("referrer").
+		Order("clicks desc").
+		Limit(20).
+		Scan(&stats.Referrers).Error; err != nil {
+		return nil, err
+	}
+
+	// 按天统计
+	if err := s.db.Model(&model.ShareClick{}).
+		Select("DATE_FORMAT(clicked_at, '%Y-%m-%d') AS date, count(*) AS clicks").
+		Where("post_id = ? AND clicked_at >= ?", postID, since).
+		Group("date").
+		Order("date").
+		Scan(&stats.Daily).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // automatically create the table if it does not exist
 func (s *shareRepository) Migrate() error {
-	return s.db.AutoMigrate(&model.Share{})
+	return s.db.AutoMigrate(&model.Share{}, &model.ShareClick{})
 }
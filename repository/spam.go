@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"inkgo/model"
+	"strings"
+)
+
+type SpamRepository interface {
+	// CountLabels 统计训练样本中垃圾评论和正常评论的数量
+	CountLabels() (spam, ham int64, err error)
+	// ListFeatures 获取所有特征的出现次数
+	ListFeatures() ([]model.SpamFeature, error)
+	// Label 标记评论并更新评论状态. 评论之前被标记为另一类时先撤销原来的训练, 同一类时不重复训练
+	Label(label *model.SpamLabel, status model.CommentStatus) error
+	Migrate() error
+}
+
+type spamRepository struct {
+	db *gorm.DB
+}
+
+func NewSpamRepository(db *gorm.DB) SpamRepository {
+	return &spamRepository{
+		db: db,
+	}
+}
+
+func (s *spamRepository) CountLabels() (int64, int64, error) {
+	type result struct {
+		Spam  bool
+		Count int64
+	}
+	results := make([]result, 0, 2)
+	if err := s.db.Model(&model.SpamLabel{}).Select("spam, count(*) AS count").Group("spam").Scan(&results).Error; err != nil {
+		return 0, 0, err
+	}
+	var spam, ham int64
+	for _, r := range results {
+		if r.Spam {
+			spam = r.Count
+		} else {
+			ham = r.Count
+		}
+	}
+	return spam, ham, nil
+}
+
+func (s *spamRepository) ListFeatures() ([]model.SpamFeature, error) {
+	features := make([]model.SpamFeature, 0)
+	if err := s.db.Where("spam > 0 OR ham > 0").Find(&features).Error; err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+func (s *spamRepository) Label(label *model.SpamLabel, status model.CommentStatus) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Comment{}).Where("id = ?", label.CommentID).Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		previous := new(model.SpamLabel)
+		err := tx.First(previous, label.CommentID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if previous.Spam == label.Spam {
+				return nil
+			}
+			if err := trainFeatures(tx, previous.Features, previous.Spam, -1); err != nil {
+				return err
+			}
+		}
+		if err := trainFeatures(tx, label.Features, label.Spam, 1); err != nil {
+			return err
+		}
+		return tx.Save(label).Error
+	})
+}
+
+// trainFeatures 将样本的每个特征计入或移出对应类别的次数
+func trainFeatures(tx *gorm.DB, features string, spam bool, delta int) error {
+	column := "ham"
+	if spam {
+		column = "spam"
+	}
+	for _, feature := range strings.Split(features, "\n") {
+		if feature == "" {
+			continue
+		}
+		row := &model.SpamFeature{Feature: feature}
+		if spam {
+			row.Spam = int64(max(delta, 0))
+		} else {
+			row.Ham = int64(max(delta, 0))
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "feature"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{column: gorm.Expr(column+" + ?", delta)}),
+		}).Create(row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *spamRepository) Migrate() error {
+	return s.db.AutoMigrate(&model.SpamLabel{}, &model.SpamFeature{})
+}
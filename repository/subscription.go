@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"inkgo/model"
+	"time"
+)
+
+type SubscriptionRepository interface {
+	// Subscribe 订阅回复通知, 重复订阅不会报错
+	Subscribe(subscription *model.Subscription) error
+	// Unsubscribe 取消订阅并丢弃尚未发送的通知, target 为 all 时取消用户的全部订阅
+	Unsubscribe(userID uint, target model.SubscriptionTarget, targetID uint) error
+	// List 获取用户的订阅, 最新的在前
+	List(userID uint) ([]model.Subscription, error)
+	// Match 获取文章 postID 或评论 commentID 的订阅
+	Match(postID uint, commentID *uint) ([]model.Subscription, error)
+	// Enqueue 保存待发送的回复通知, 同一用户的同一条回复只保存一次
+	Enqueue(emails []model.ReplyEmail) error
+	// ListPending 获取最早的 limit 条待发送通知, 包含收件人. 回复已删除或不再公开的通知不返回
+	ListPending(limit int) ([]model.ReplyEmail, error)
+	// MarkSent 将通知标记为已发送
+	MarkSent(ids []uint) error
+	Migrate() error
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{
+		db: db,
+	}
+}
+
+func (s *subscriptionRepository) Subscribe(subscription *model.Subscription) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(subscription).Error
+}
+
+func (s *subscriptionRepository) Unsubscribe(userID uint, target model.SubscriptionTarget, targetID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		subscriptions := tx.Where("user_id = ?", userID)
+		emails := tx.Where("user_id = ? AND sent_at IS NULL", userID)
+		if target != model.SubscriptionAll {
+			subscriptions = subscriptions.Where("target_type = ? AND target_id = ?", target, targetID)
+			emails = emails.Where("target_type = ? AND target_id = ?", target, targetID)
+		}
+		if err := subscriptions.Delete(&model.Subscription{}).Error; err != nil {
+			return err
+		}
+		return emails.Delete(&model.ReplyEmail{}).Error
+	})
+}
+
+func (s *subscriptionRepository) List(userID uint) ([]model.Subscription, error) {
+	subscriptions := make([]model.Subscription, 0)
+	err := s.db.Where("user_id = ?", userID).Order("id DESC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (s *subscriptionRepository) Match(postID uint, commentID *uint) ([]model.Subscription, error) {
+	subscriptions := make([]model.Subscription, 0)
+	db := s.db.Where("target_type = ? AND target_id = ?", model.SubscriptionPost, postID)
+	if commentID != nil {
+		db = db.Or("target_type = ? AND target_id = ?", model.SubscriptionComment, *commentID)
+	}
+	err := db.Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (s *subscriptionRepository) Enqueue(emails []model.ReplyEmail) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return s.db.Omit("User").Clauses(clause.OnConflict{DoNothing: true}).Create(&emails).Error
+}
+
+func (s *subscriptionRepository) ListPending(limit int) ([]model.ReplyEmail, error) {
+	emails := make([]model.ReplyEmail, 0)
+	err := s.db.Preload("User").
+		Joins("JOIN comment ON comment.id = reply_email.comment_id AND comment.deleted_at IS NULL AND comment.status = ?", model.CommentApproved).
+		Where("reply_email.sent_at IS NULL").Order("reply_email.id").Limit(limit).Find(&emails).Error
+	return emails, err
+}
+
+func (s *subscriptionRepository) MarkSent(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.Model(&model.ReplyEmail{}).Where("id IN ?", ids).Update("sent_at", time.Now()).Error
+}
+
+// 自动创建表结构到db
+func (s *subscriptionRepository) Migrate() error {
+	return s.db.AutoMigrate(&model.Subscription{}, &model.ReplyEmail{})
+}
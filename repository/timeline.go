@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"inkgo/database"
+	"strconv"
+	"time"
+)
+
+// timelineSentinel 放在列表末尾, 标记时间线已经构建过, 区分空时间线和未构建的时间线
+const timelineSentinel = "0"
+
+// TimelineRepository 在 Redis 列表中缓存每个用户首页时间线的文章ID, 最新的在前
+type TimelineRepository interface {
+	// Push 将文章推送到多个用户的时间线头部, 只推送已构建的时间线, 每条时间线最多保留 size 篇
+	Push(ctx context.Context, userIDs []uint, postID uint, size int) error
+	// Range 获取用户时间线中的文章ID, 时间线未构建或已过期时 ok 为 false
+	Range(ctx context.Context, userID uint) (ids []uint, ok bool, err error)
+	// Replace 整体替换用户的时间线
+	Replace(ctx context.Context, userID uint, ids []uint, expiration time.Duration) error
+	// Invalidate 删除用户的时间线, 下次读取时重新构建
+	Invalidate(ctx context.Context, userID uint) error
+}
+
+type timelineRepository struct {
+	rdb *database.RedisDB
+}
+
+func NewTimelineRepository(rdb *database.RedisDB) TimelineRepository {
+	return &timelineRepository{
+		rdb: rdb,
+	}
+}
+
+func timelineKey(userID uint) string {
+	return fmt.Sprintf("timeline:%d", userID)
+}
+
+func (t *timelineRepository) Push(ctx context.Context, userIDs []uint, postID uint, size int) error {
+	if !t.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	member := strconv.FormatUint(uint64(postID), 10)
+	_, err := t.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			key := timelineKey(userID)
+			// 文章重新发布时先去掉旧的位置, LPushX 不会创建未构建的时间线
+			pipe.LRem(ctx, key, 0, member)
+			pipe.LPushX(ctx, key, member)
+			// 多保留一个位置给末尾的标记
+			pipe.LTrim(ctx, key, 0, int64(size))
+		}
+		return nil
+	})
+	return err
+}
+
+func (t *timelineRepository) Range(ctx context.Context, userID uint) ([]uint, bool, error) {
+	if !t.rdb.Enable() {
+		return nil, false, database.RedisDisableError
+	}
+	members, err := t.rdb.LRange(ctx, timelineKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(members) == 0 {
+		return nil, false, nil
+	}
+	ids := make([]uint, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseUint(m, 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, true, nil
+}
+
+func (t *timelineRepository) Replace(ctx context.Context, userID uint, ids []uint, expiration time.Duration) error {
+	if !t.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	key := timelineKey(userID)
+	members := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		members = append(members, strconv.FormatUint(uint64(id), 10))
+	}
+	members = append(members, timelineSentinel)
+	_, err := t.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.RPush(ctx, key, members...)
+		pipe.Expire(ctx, key, expiration)
+		return nil
+	})
+	return err
+}
+
+func (t *timelineRepository) Invalidate(ctx context.Context, userID uint) error {
+	if !t.rdb.Enable() {
+		return database.RedisDisableError
+	}
+	return t.rdb.Del(ctx, timelineKey(userID))
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"inkgo/model"
+	"strings"
+	"time"
+)
+
+// TrashFilter 回收站的查询条件
+type TrashFilter struct {
+	OwnerID uint            // 为 0 时不限属主, 仅供管理员使用
+	Type    model.TrashType // 为空时查询所有类型
+	Before  time.Time       // 只查询在此之前删除的内容, 为零值时不限
+}
+
+type TrashRepository interface {
+	// List 按删除时间倒序列出已删除的内容
+	List(filter TrashFilter, page, pageSize int) ([]model.TrashItem, int64, error)
+	// Get 获取一条已删除的内容, 不在回收站中时返回 gorm.ErrRecordNotFound
+	Get(itemType model.TrashType, id uint) (*model.TrashItem, error)
+	// Restore 恢复已删除的内容
+	Restore(itemType model.TrashType, id uint) error
+	// Purge 彻底删除内容及其关联数据
+	Purge(itemType model.TrashType, id uint) error
+}
+
+type trashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{
+		db: db,
+	}
+}
+
+// trashTables 每种内容对应的表、标题和属主字段
+var trashTables = []struct {
+	itemType model.TrashType
+	model    interface{}
+	title    string
+	owner    string
+}{
+	{model.TrashPost, &model.Post{}, "title", "author_id"},
+	{model.TrashComment, &model.Comment{}, "content", "author_id"},
+	{model.TrashFavorite, &model.Favorite{}, "name", "user_id"},
+}
+
+// query 将各个表中已删除的记录合并为一个子查询
+func (t *trashRepository) query(filter TrashFilter) *gorm.DB {
+	subs := make([]interface{}, 0, len(trashTables))
+	for _, table := range trashTables {
+		if filter.Type != "" && filter.Type != table.itemType {
+			continue
+		}
+		db := t.db.Unscoped().Model(table.model).
+			Select("? AS type, id, "+table.title+" AS title, "+table.owner+" AS owner_id, deleted_at", string(table.itemType)).
+			Where("deleted_at IS NOT NULL")
+		if filter.OwnerID != 0 {
+			db = db.Where(table.owner+" = ?", filter.OwnerID)
+		}
+		if !filter.Before.IsZero() {
+			db = db.Where("deleted_at < ?", filter.Before)
+		}
+		subs = append(subs, db)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("? UNION ALL ", len(subs)), " UNION ALL ")
+	return t.db.Table("("+placeholders+") AS trash", subs...)
+}
+
+func (t *trashRepository) List(filter TrashFilter, page, pageSize int) ([]model.TrashItem, int64, error) {
+	items := make([]model.TrashItem, 0)
+	var total int64
+	if err := t.query(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := t.query(filter).
+		Order("deleted_at desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (t *trashRepository) Get(itemType model.TrashType, id uint) (*model.TrashItem, error) {
+	items := make([]model.TrashItem, 0, 1)
+	if err := t.query(TrashFilter{Type: itemType}).Where("id = ?", id).Limit(1).Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &items[0], nil
+}
+
+func (t *trashRepository) Restore(itemType model.TrashType, id uint) error {
+	for _, table := range trashTables {
+		if table.itemType != itemType {
+			continue
+		}
+		result := t.db.Unscoped().Model(table.model).Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (t *trashRepository) Purge(itemType model.TrashType, id uint) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		// Session 使后续的每个查询都从干净的条件开始
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		switch itemType {
+		case model.TrashPost:
+			return purgePost(tx, id)
+		case model.TrashComment:
+			return purgeComment(tx, id)
+		case model.TrashFavorite:
+			return purgeFavorite(tx, id)
+		}
+		return gorm.ErrRecordNotFound
+	})
+}
+
+// purgePost 删除文章及其标签、分类、收藏、表情、评论、分享等关联数据
+func purgePost(tx *gorm.DB, id uint) error {
+	for _, table := range []string{"tag_posts", "category_posts", "favorite_posts"} {
+		if err := tx.Table(table).Where("post_id = ?", id).Delete(nil).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.ReactionPost, id).Delete(&model.Reaction{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.MentionPost, id).Delete(&model.Mention{}).Error; err != nil {
+		return err
+	}
+	for _, m := range []interface{}{&model.Activity{}, &model.ShareClick{}, &model.Share{}, &model.Repost{}} {
+		if err := tx.Where("post_id = ?", id).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+	// 转载了该文章的记录保留, 只去掉站内关联
+	if err := tx.Model(&model.Repost{}).Where("repost_id = ?", id).Update("repost_id", nil).Error; err != nil {
+		return err
+	}
+
+	var commentIDs []uint
+	if err := tx.Model(&model.Comment{}).Where("post_id = ?", id).Pluck("id", &commentIDs).Error; err != nil {
+		return err
+	}
+	if len(commentIDs) > 0 {
+		// 先断开回复关系, 避免删除时违反自关联的外键约束
+		if err := tx.Model(&model.Comment{}).Where("id IN ?", commentIDs).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_type = ? AND target_id IN ?", model.ModerationComment, commentIDs).
+			Delete(&model.ModerationReview{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_type = ? AND target_id IN ?", model.ReactionComment, commentIDs).Delete(&model.Reaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_type = ? AND target_id IN ?", model.MentionComment, commentIDs).Delete(&model.Mention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id IN ?", commentIDs).Delete(&model.CommentRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_type = ? AND target_id IN ?", model.SubscriptionComment, commentIDs).Delete(&model.Subscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id IN ?", commentIDs).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.ModerationPost, id).Delete(&model.ModerationReview{}).Error; err != nil {
+		return err
+	}
+	// 通知和回复邮件中保存了文章和评论的摘要, 一并删除
+	if err := tx.Where("post_id = ?", id).Delete(&model.Notification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("post_id = ?", id).Delete(&model.ReplyEmail{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.SubscriptionPost, id).Delete(&model.Subscription{}).Error; err != nil {
+		return err
+	}
+	return deleteTrashed(tx, &model.Post{}, id)
+}
+
+// purgeComment 删除评论, 其回复改为回复上一级评论; 删除的是顶层评论时, 它的每条直接回复成为新的楼层
+func purgeComment(tx *gorm.DB, id uint) error {
+	comment := new(model.Comment)
+	if err := tx.Where("deleted_at IS NOT NULL").First(comment, id).Error; err != nil {
+		return err
+	}
+	var replyIDs []uint
+	if err := tx.Model(&model.Comment{}).Where("parent_id = ?", id).Pluck("id", &replyIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.Comment{}).Where("parent_id = ?", id).Update("parent_id", comment.ParentID).Error; err != nil {
+		return err
+	}
+	if comment.RootID == nil {
+		for _, replyID := range replyIDs {
+			if err := rerootComments(tx, replyID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.ReactionComment, id).Delete(&model.Reaction{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.MentionComment, id).Delete(&model.Mention{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id = ?", id).Delete(&model.CommentRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.ModerationComment, id).Delete(&model.ModerationReview{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.MentionComment, id).Delete(&model.Notification{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id = ?", id).Delete(&model.ReplyEmail{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("target_type = ? AND target_id = ?", model.SubscriptionComment, id).Delete(&model.Subscription{}).Error; err != nil {
+		return err
+	}
+	return deleteTrashed(tx, &model.Comment{}, id)
+}
+
+// rerootComments 将评论改为顶层评论, 其下所有回复归入该楼层
+func rerootComments(tx *gorm.DB, rootID uint) error {
+	if err := tx.Model(&model.Comment{}).Where("id = ?", rootID).Update("root_id", nil).Error; err != nil {
+		return err
+	}
+	level := []uint{rootID}
+	for len(level) > 0 {
+		var children []uint
+		if err := tx.Model(&model.Comment{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return err
+		}
+		if len(children) == 0 {
+			return nil
+		}
+		if err := tx.Model(&model.Comment{}).Where("id IN ?", children).Update("root_id", rootID).Error; err != nil {
+			return err
+		}
+		level = children
+	}
+	return nil
+}
+
+// purgeFavorite 删除收藏夹及其收藏的文章记录和关注记录
+func purgeFavorite(tx *gorm.DB, id uint) error {
+	if err := tx.Table("favorite_posts").Where("favorite_id = ?", id).Delete(nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("favorite_id = ?", id).Delete(&model.FavoriteFollow{}).Error; err != nil {
+		return err
+	}
+	return deleteTrashed(tx, &model.Favorite{}, id)
+}
+
+// deleteTrashed 彻底删除已在回收站中的记录
+func deleteTrashed(tx *gorm.DB, m interface{}, id uint) error {
+	result := tx.Where("id = ? AND deleted_at IS NOT NULL", id).Delete(m)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
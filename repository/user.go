@@ -204,6 +204,18 @@ func (u *userRepository) List(pageSize int, page int) ([]model.User, int64, erro
 	return users, total, nil
 }
 
+// ListByCursor 按游标分页获取用户列表
+func (u *userRepository) ListByCursor(cursor *model.Cursor, limit int) ([]model.User, *model.Cursor, error) {
+	users := make([]model.User, 0, limit+1)
+	if err := u.db.Scopes(IDAfter("user", cursor)).Limit(limit + 1).Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	users, next := cursorPage(users, limit, func(user *model.User) model.Cursor {
+		return model.Cursor{ID: user.ID}
+	})
+	return users, next, nil
+}
+
 // Create 用于创建新用户
 func (u *userRepository) Create(user *model.User) (*model.User, error) {
 	userCreateField := []string{"user_name", "email", "password", "mobile", "avatar"}
@@ -39,6 +39,7 @@ type Server struct {
 	logger      *zap.Logger
 	repository  repository.Repository
 	controllers []controller.Controller
+	jobs        []service.Job
 	enforcer    *casbin.Enforcer
 }
 
@@ -82,10 +83,51 @@ func New(conf *config.Config, logger *zap.Logger) (*Server, error) {
 	jwtService := authentication.NewJWT(&conf.JWTConfig, tokenService)
 	oauthManager := oauth.NewOAuthManager(conf.OAuthConfigs)
 	authContoller := controller.NewAuthController(userService, jwtService, oauthManager, authService)
+
+	// notification, block, timeline, follow, mention
+	notificationService := service.NewNotificationService(repository.Notification())
+	notificationController := controller.NewNotificationController(notificationService)
+	blockService := service.NewBlockService(repository.Block(), repository.User())
+	blockController := controller.NewBlockController(blockService)
+	timelineService := service.NewTimelineService(conf.Timeline, repository.Timeline(), repository.Post(), repository.Follow(), repository.Block(), repository.Like())
+	timelineController := controller.NewTimelineController(timelineService)
+	followService := service.NewFollowService(repository.Follow(), repository.User(), repository.Block(), repository.Tag(), repository.Category(), timelineService)
+	followController := controller.NewFollowController(followService)
+	mentionService := service.NewMentionService(repository.Mention(), repository.User(), repository.Block(), notificationService)
+
+	// moderation
+	relatedService := service.NewRelatedService(conf.Related, repository.Post(), repository.Related())
+	moderationService := service.NewModerationService(conf.Moderation, repository.Moderation(), relatedService)
+	moderationController := controller.NewModerationController(moderationService)
+
 	// Post
-	PostService := service.NewPostService(repository.Post())
+	PostService := service.NewPostService(repository.Post(), repository.Like(), repository.Rank(), repository.Follow(), repository.Token(), relatedService, moderationService, mentionService, timelineService)
 	PostController := controller.NewPostController(PostService)
 
+	// repost
+	repostService := service.NewRepostService(repository.Repost(), repository.Post())
+	repostController := controller.NewRepostController(repostService)
+
+	// share
+	shareService := service.NewShareService(conf.Share, repository.Share(), repository.Post(), PostService)
+	shareController := controller.NewShareController(shareService)
+
+	// trash
+	trashService := service.NewTrashService(conf.Trash, repository.Trash(), relatedService)
+	trashController := controller.NewTrashController(trashService)
+
+	// archive
+	archiveService := service.NewArchiveService(repository.Post())
+	archiveController := controller.NewArchiveController(archiveService)
+
+	// export
+	exportService := service.NewExportService(conf.Export, PostService, repository.Post(), repository.Export())
+	exportController := controller.NewExportController(exportService)
+
+	// import
+	importService := service.NewImportService(conf.Import, repository.Import(), repository.User())
+	importController := controller.NewImportController(importService)
+
 	//category
 	categoryService := service.NewCategoryService(repository.Category())
 	categoryController := controller.NewCategoryController(categoryService)
@@ -95,18 +137,39 @@ func New(conf *config.Config, logger *zap.Logger) (*Server, error) {
 	tagController := controller.NewTagController(tagService)
 
 	// favorite
-	favoriteService := service.NewFavoriteService(repository.Favorite())
+	favoriteService := service.NewFavoriteService(repository.Favorite(), PostService)
 	favoriteController := controller.NewFavoriteController(favoriteService)
 
+	// spam
+	spamService := service.NewSpamService(conf.Spam, repository.Spam(), repository.Comment(), moderationService)
+	spamController := controller.NewSpamController(spamService)
+
 	//comment
-	commentService := service.NewCommentService(repository.Comment())
-	commentController := controller.NewCommentController(commentService)
+	captchaService := service.NewCaptchaService(conf.Comment, repository.Captcha())
+	// 退订链接默认使用 JWT 密钥签名
+	if conf.Mail.Secret == "" {
+		conf.Mail.Secret = conf.JWTConfig.Secret
+	}
+	subscriptionService := service.NewSubscriptionService(conf.Mail, repository.Subscription(), repository.Comment(), PostService)
+	subscriptionController := controller.NewSubscriptionController(subscriptionService)
+	commentService := service.NewCommentService(conf.Comment, conf.Mail, repository.Comment(), PostService, moderationService, spamService,
+		mentionService, captchaService, subscriptionService)
+	commentController := controller.NewCommentController(commentService, captchaService)
 
 	// like
-	likeService := service.NewLikeService(repository.Like())
+	likeService := service.NewLikeService(repository.Like(), PostService)
 	likeController := controller.NewLikeController(likeService)
 
-	controllers := []controller.Controller{userController, PostController, categoryController, tagController, authContoller, commentController, likeController, favoriteController}
+	// reaction
+	reactionService := service.NewReactionService(repository.Reaction(), PostService, commentService)
+	reactionController := controller.NewReactionController(reactionService)
+
+	controllers := []controller.Controller{userController, PostController, categoryController, tagController, authContoller, commentController, likeController, favoriteController, repostController, shareController, importController, exportController, archiveController, moderationController, trashController, spamController, reactionController, notificationController, blockController, subscriptionController, followController, timelineController}
+
+	// 后台任务
+	hotRankJob := service.NewHotRankJob(conf.Ranking, repository.Post(), repository.Rank())
+
+	jobs := []service.Job{hotRankJob, exportService, relatedService, moderationService, trashService, spamService, subscriptionService}
 
 	//logger
 	logs := service.NewLoggerService(&conf.Logger)
@@ -132,6 +195,7 @@ func New(conf *config.Config, logger *zap.Logger) (*Server, error) {
 		logger:      logger,
 		repository:  repository,
 		controllers: controllers,
+		jobs:        jobs,
 		enforcer:    ennforcer,
 	}, nil
 }
@@ -169,6 +233,9 @@ func (s *Server) Routers() {
 			// 其他路由需要登录验证
 			router.RegisterRoute(api)
 		}
+		if p, ok := router.(controller.PublicController); ok {
+			p.RegisterPublicRoute(public)
+		}
 		controllers = append(controllers, router.Name())
 	}
 	zap.S().Infof("server enabled controllers: %v", controllers)
@@ -190,6 +257,10 @@ func (s *Server) Run() error {
 		}
 	}()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	s.RunJobs(jobCtx)
+
 	// 平滑关闭进程
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
@@ -203,6 +274,16 @@ func (s *Server) Run() error {
 	return server.Shutdown(ctx)
 }
 
+// RunJobs 启动所有后台任务
+func (s *Server) RunJobs(ctx context.Context) {
+	jobs := make([]string, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		go job.Run(ctx)
+		jobs = append(jobs, job.Name())
+	}
+	zap.S().Infof("server started jobs: %v", jobs)
+}
+
 func (s *Server) Close() {
 	if err := s.repository.Close(); err != nil {
 		zap.S().Warnf("failed to close repository, %v", err)
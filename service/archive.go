@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"inkgo/model"
+	"inkgo/repository"
+	"strconv"
+	"time"
+)
+
+const calendarDateFormat = "2006-01-02"
+
+var ErrArchiveDate = errors.New("年份或月份不正确")
+
+// ArchiveService 按发布日期归档文章, 以及统计每天发布文章数的写作日历
+type ArchiveService interface {
+	// Summary 按年、月统计已发布的文章数
+	Summary(user *model.User, filter model.ArchiveFilter) ([]model.ArchiveYear, error)
+	// ListPosts 列出某年或某月发布的文章, month 为空时列出全年的文章
+	ListPosts(user *model.User, filter model.ArchiveFilter, year, month string, page, pageSize int) ([]model.Post, int64, error)
+	// Calendar 按天统计某一年发布的文章数, year 为空时统计截至今天的最近一年
+	Calendar(user *model.User, filter model.ArchiveFilter, year string) (*model.Calendar, error)
+}
+
+type archiveService struct {
+	postRepository repository.PostRepository
+}
+
+func NewArchiveService(postRepository repository.PostRepository) ArchiveService {
+	return &archiveService{
+		postRepository: postRepository,
+	}
+}
+
+func (a *archiveService) Summary(user *model.User, filter model.ArchiveFilter) ([]model.ArchiveYear, error) {
+	months, err := a.postRepository.CountByMonth(user.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+	// 月份已按时间降序排列, 依次归入所属的年份
+	years := make([]model.ArchiveYear, 0)
+	for _, month := range months {
+		if len(years) == 0 || years[len(years)-1].Year != month.Year {
+			years = append(years, model.ArchiveYear{Year: month.Year, Months: make([]model.ArchiveMonth, 0)})
+		}
+		year := &years[len(years)-1]
+		year.Count += month.Count
+		year.Months = append(year.Months, month)
+	}
+	return years, nil
+}
+
+func (a *archiveService) ListPosts(user *model.User, filter model.ArchiveFilter, year, month string, page, pageSize int) ([]model.Post, int64, error) {
+	y, err := parseArchiveYear(year)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Date(y, time.January, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(1, 0, 0)
+	if month != "" {
+		m, err := strconv.Atoi(month)
+		if err != nil || m < 1 || m > 12 {
+			return nil, 0, ErrArchiveDate
+		}
+		start = time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(0, 1, 0)
+	}
+	return a.postRepository.ListByDateRange(user.ID, filter, start, end, page, pageSize)
+}
+
+func (a *archiveService) Calendar(user *model.User, filter model.ArchiveFilter, year string) (*model.Calendar, error) {
+	var start, end time.Time
+	if year == "" {
+		now := time.Now()
+		end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+		start = end.AddDate(-1, 0, 0)
+	} else {
+		y, err := parseArchiveYear(year)
+		if err != nil {
+			return nil, err
+		}
+		start = time.Date(y, time.January, 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(1, 0, 0)
+	}
+
+	counts, err := a.postRepository.CountByDay(user.ID, filter, start, end)
+	if err != nil {
+		return nil, err
+	}
+	byDate := make(map[string]int64, len(counts))
+	for _, day := range counts {
+		byDate[day.Date] = day.Count
+	}
+	// 没有发布文章的日期补 0, 方便前端直接绘制
+	calendar := &model.Calendar{
+		Start: start.Format(calendarDateFormat),
+		End:   end.AddDate(0, 0, -1).Format(calendarDateFormat),
+		Days:  make([]model.CalendarDay, 0, 366),
+	}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(calendarDateFormat)
+		count := byDate[date]
+		calendar.Days = append(calendar.Days, model.CalendarDay{Date: date, Count: count})
+		calendar.Total += count
+		if count > calendar.Max {
+			calendar.Max = count
+		}
+	}
+	return calendar, nil
+}
+
+func parseArchiveYear(year string) (int, error) {
+	y, err := strconv.Atoi(year)
+	if err != nil || y < 1970 || y > 9999 {
+		return 0, ErrArchiveDate
+	}
+	return y, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"inkgo/model"
+	"inkgo/repository"
+	"strconv"
+)
+
+var (
+	ErrBlockSelf = errors.New("不能拉黑自己")
+	ErrMuteSelf  = errors.New("不能屏蔽自己")
+)
+
+// BlockService 拉黑和屏蔽用户, 被拉黑的用户无法 @ 拉黑者, 被屏蔽的用户的文章不出现在首页时间线中
+type BlockService interface {
+	Block(user *model.User, id string) error
+	Unblock(user *model.User, id string) error
+	// List 分页获取拉黑的用户
+	List(user *model.User, page, pageSize int) ([]model.Block, int64, error)
+	Mute(user *model.User, id string) error
+	Unmute(user *model.User, id string) error
+	// ListMutes 分页获取屏蔽的用户
+	ListMutes(user *model.User, page, pageSize int) ([]model.Mute, int64, error)
+}
+
+type blockService struct {
+	blockRepository repository.BlockRepository
+	userRepository  repository.UserRepository
+}
+
+func NewBlockService(blockRepository repository.BlockRepository, userRepository repository.UserRepository) BlockService {
+	return &blockService{
+		blockRepository: blockRepository,
+		userRepository:  userRepository,
+	}
+}
+
+func (b *blockService) Block(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	if uint(uid) == user.ID {
+		return ErrBlockSelf
+	}
+	if _, err := b.userRepository.GetUserByID(uint(uid)); err != nil {
+		return err
+	}
+	return b.blockRepository.Block(user.ID, uint(uid))
+}
+
+func (b *blockService) Unblock(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	return b.blockRepository.Unblock(user.ID, uint(uid))
+}
+
+func (b *blockService) List(user *model.User, page, pageSize int) ([]model.Block, int64, error) {
+	page, pageSize = followPage(page, pageSize)
+	return b.blockRepository.List(user.ID, page, pageSize)
+}
+
+func (b *blockService) Mute(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	if uint(uid) == user.ID {
+		return ErrMuteSelf
+	}
+	if _, err := b.userRepository.GetUserByID(uint(uid)); err != nil {
+		return err
+	}
+	return b.blockRepository.Mute(user.ID, uint(uid))
+}
+
+func (b *blockService) Unmute(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	return b.blockRepository.Unmute(user.ID, uint(uid))
+}
+
+func (b *blockService) ListMutes(user *model.User, page, pageSize int) ([]model.Mute, int64, error) {
+	page, pageSize = followPage(page, pageSize)
+	return b.blockRepository.ListMutes(user.ID, page, pageSize)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"inkgo/captcha"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"strings"
+	"time"
+)
+
+const (
+	// 验证码的位数和默认有效期, 单位为秒
+	captchaLength            = 5
+	defaultCaptchaExpiration = 300
+	captchaIDLength          = 20
+)
+
+var ErrCaptcha = errors.New("验证码错误或已过期")
+
+// CaptchaService 生成和校验图片验证码, 答案保存在 Redis 中
+type CaptchaService interface {
+	New() (*model.Captcha, error)
+	// Verify 校验验证码, 无论是否正确验证码都会失效
+	Verify(id, answer string) error
+}
+
+type captchaService struct {
+	expiration        time.Duration
+	captchaRepository repository.CaptchaRepository
+}
+
+func NewCaptchaService(conf config.CommentConfig, captchaRepository repository.CaptchaRepository) CaptchaService {
+	if conf.CaptchaExpiration <= 0 {
+		conf.CaptchaExpiration = defaultCaptchaExpiration
+	}
+	return &captchaService{
+		expiration:        time.Duration(conf.CaptchaExpiration) * time.Second,
+		captchaRepository: captchaRepository,
+	}
+}
+
+func (c *captchaService) New() (*model.Captcha, error) {
+	id, err := utils.RandomString(captchaIDLength)
+	if err != nil {
+		return nil, err
+	}
+	answer := captcha.RandomDigits(captchaLength)
+	image, err := captcha.EncodePNG(answer)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.captchaRepository.Save(id, answer, c.expiration); err != nil {
+		return nil, err
+	}
+	return &model.Captcha{
+		ID:    id,
+		Image: "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
+	}, nil
+}
+
+func (c *captchaService) Verify(id, answer string) error {
+	expected, err := c.captchaRepository.Take(id)
+	if err != nil {
+		return err
+	}
+	if expected == "" || expected != strings.TrimSpace(answer) {
+		return ErrCaptcha
+	}
+	return nil
+}
@@ -1,37 +1,552 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+	"html"
+	"inkgo/common/request"
+	"inkgo/config"
 	"inkgo/model"
+	"inkgo/moderation"
 	"inkgo/repository"
+	"inkgo/utils"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 评论的最大层级, 顶层评论为第 1 层
+	maxCommentDepth = 5
+	// 每个楼层默认和最多预先返回的回复数
+	defaultReplyPreview = 3
+	maxReplyPreview     = 10
+	// 默认的评论可编辑时间, 单位为分钟
+	defaultCommentEditWindow = 15
+	// 认领游客评论的确认链接默认的有效期, 单位为分钟
+	defaultClaimExpiration = 60
+)
+
+var (
+	ErrCommentDepth       = errors.New("回复层级过深, 请回复上一级评论")
+	ErrCommentNotOwner    = errors.New("只能编辑自己的评论")
+	ErrCommentEditExpired = errors.New("已超过评论的可编辑时间")
+	ErrGuestComment       = errors.New("本站未开启游客评论")
+	ErrClaimEmail         = errors.New("账号没有绑定邮箱")
+	ErrClaimToken         = errors.New("无效或已过期的认领链接")
+	ErrMailDisabled       = errors.New("本站未开启邮件发送")
 )
 
 type commentService struct {
-	commentRepository repository.CommentRepository
+	editWindow          time.Duration
+	guest               bool
+	claimExpiration     time.Duration
+	mail                config.MailConfig
+	sender              mailSender
+	commentRepository   repository.CommentRepository
+	postService         PostService
+	moderationService   ModerationService
+	spamService         SpamService
+	mentionService      MentionService
+	captchaService      CaptchaService
+	subscriptionService SubscriptionService
 }
 
-func NewCommentService(commentRepository repository.CommentRepository) CommentService {
-	return &commentService{
-		commentRepository: commentRepository,
+func NewCommentService(conf config.CommentConfig, mail config.MailConfig, commentRepository repository.CommentRepository,
+	postService PostService, moderationService ModerationService, spamService SpamService, mentionService MentionService,
+	captchaService CaptchaService, subscriptionService SubscriptionService) CommentService {
+	if conf.EditWindow <= 0 {
+		conf.EditWindow = defaultCommentEditWindow
+	}
+	if conf.ClaimExpiration <= 0 {
+		conf.ClaimExpiration = defaultClaimExpiration
+	}
+	c := &commentService{
+		editWindow:          time.Duration(conf.EditWindow) * time.Minute,
+		guest:               conf.Guest,
+		claimExpiration:     time.Duration(conf.ClaimExpiration) * time.Minute,
+		mail:                mail,
+		commentRepository:   commentRepository,
+		postService:         postService,
+		moderationService:   moderationService,
+		spamService:         spamService,
+		mentionService:      mentionService,
+		captchaService:      captchaService,
+		subscriptionService: subscriptionService,
 	}
+	if mail.Enabled {
+		c.sender = newMailSender(mail)
+	}
+	moderationService.OnApprove(model.ModerationComment, c.afterApprove)
+	return c
 }
 
-func (c *commentService) Add(comment *model.Comment, id string, user *model.User) (*model.Comment, error) {
-	cid, err := strconv.Atoi(id)
+// afterApprove 待审核的评论通过审核后通知被 @ 的用户和订阅了回复的用户
+func (c *commentService) afterApprove(id uint) {
+	comment, err := c.commentRepository.GetWithAuthor(id)
+	if err != nil {
+		zap.S().Warnf("failed to load approved comment %d, %v", id, err)
+		return
+	}
+	if comment.Status != model.CommentApproved {
+		return
+	}
+	c.syncMentions(comment)
+	author := &comment.Author
+	if comment.AuthorID == 0 {
+		author = &model.User{UserName: comment.GuestName}
+	}
+	if err := c.subscriptionService.NotifyReply(author, comment); err != nil {
+		zap.S().Warnf("failed to queue reply emails of comment %d, %v", comment.ID, err)
+	}
+}
+
+func (c *commentService) Add(user *model.User, postID string, grant string, comment *model.Comment) (*model.Comment, error) {
+	pid, err := strconv.Atoi(postID)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := c.postService.Authorize(user, uint(pid), grant); err != nil {
+		return nil, err
+	}
+	comment.PostID = uint(pid)
+	comment.ParentID, comment.RootID = nil, nil
+	return c.save(user, comment)
+}
 
-	comment.ID = uint(cid)
+func (c *commentService) Reply(user *model.User, parentID string, grant string, comment *model.Comment) (*model.Comment, error) {
+	// 只能回复审核通过的评论, 与点赞评论相同
+	parent, err := c.Authorize(user, parentID, grant)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := c.depth(parent)
+	if err != nil {
+		return nil, err
+	}
+	if depth >= maxCommentDepth {
+		return nil, ErrCommentDepth
+	}
+	comment.PostID = parent.PostID
+	comment.ParentID = &parent.ID
+	comment.RootID = parent.RootID
+	if comment.RootID == nil {
+		comment.RootID = &parent.ID
+	}
+	return c.save(user, comment)
+}
+
+// depth 沿上级评论计算评论所在的层级, 最多向上查找 maxCommentDepth 层
+func (c *commentService) depth(comment *model.Comment) (int, error) {
+	depth := 1
+	for comment.ParentID != nil && depth < maxCommentDepth {
+		parent, err := c.commentRepository.Get(*comment.ParentID)
+		if err != nil {
+			return 0, err
+		}
+		comment = parent
+		depth++
+	}
+	return depth, nil
+}
+
+// save 检查敏感词并判断是否为垃圾评论后保存评论, 需要审核的评论加入审核队列
+func (c *commentService) save(user *model.User, comment *model.Comment) (*model.Comment, error) {
+	comment.ID = 0
 	comment.AuthorID = user.ID
+	comment.LikeCount = 0
+	comment.EditedAt = nil
+	comment.GuestName, comment.GuestEmail, comment.GuestWebsite = "", "", ""
 
-	return c.commentRepository.Add(comment)
+	status, result, err := c.review(user, &comment.Content)
+	if err != nil {
+		return nil, err
+	}
+	comment.Status = status
+	comment, err = c.commentRepository.Add(comment)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == model.CommentPending {
+		if err := c.moderationService.Hold(model.ModerationComment, comment.ID, user.ID, comment.Content, result); err != nil {
+			zap.S().Warnf("failed to submit moderation review of comment %d, %v", comment.ID, err)
+		}
+	}
+	c.syncMentions(comment)
+	if comment.Status == model.CommentApproved {
+		if err := c.subscriptionService.NotifyReply(user, comment); err != nil {
+			zap.S().Warnf("failed to queue reply emails of comment %d, %v", comment.ID, err)
+		}
+	}
+	return comment, nil
+}
+
+// AddGuest 游客发表顶层评论. 游客评论没有作者, 不论内容如何都先进入人工审核, 判定为垃圾评论时直接隐藏
+func (c *commentService) AddGuest(postID string, grant string, req *request.GuestCommentRequest) (*model.Comment, error) {
+	if !c.guest {
+		return nil, ErrGuestComment
+	}
+	if err := c.captchaService.Verify(req.CaptchaID, req.Captcha); err != nil {
+		return nil, err
+	}
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return nil, err
+	}
+	// 未登录的访客
+	guest := &model.User{}
+	if _, err := c.postService.Authorize(guest, uint(pid), grant); err != nil {
+		return nil, err
+	}
+	comment := req.GetComment()
+	comment.PostID = uint(pid)
+	status, result, err := c.review(guest, &comment.Content)
+	if err != nil {
+		return nil, err
+	}
+	comment.Status = model.CommentPending
+	if status == model.CommentSpam {
+		comment.Status = model.CommentSpam
+	}
+	comment, err = c.commentRepository.Add(comment)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status == model.CommentPending {
+		if err := c.moderationService.Hold(model.ModerationComment, comment.ID, 0, comment.Content, result); err != nil {
+			zap.S().Warnf("failed to submit moderation review of comment %d, %v", comment.ID, err)
+		}
+	}
+	return comment, nil
+}
+
+// RequestClaim 向用户的邮箱发送带签名的确认链接, 证明邮箱属于该用户后才能认领游客评论
+func (c *commentService) RequestClaim(user *model.User) error {
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	if email == "" {
+		return ErrClaimEmail
+	}
+	if c.sender == nil {
+		return ErrMailDisabled
+	}
+	// payload 格式为 claim:用户ID:过期时间:邮箱, 邮箱放在最后, 避免其中的冒号影响解析
+	expires := time.Now().Add(c.claimExpiration).Unix()
+	token := utils.Sign(c.mail.Secret, fmt.Sprintf("claim:%d:%d:%s", user.ID, expires, email))
+	link := c.mail.ClaimURL + "?token=" + url.QueryEscape(token)
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", c.mail.From)
+	m.SetHeader("To", user.Email)
+	m.SetHeader("Subject", "确认认领游客评论")
+	m.SetBody("text/html", fmt.Sprintf(`<p>%s, 请在 %d 分钟内点击下面的链接, 将此邮箱以游客身份发表的评论归到你的账号下:</p><p><a href="%s">确认认领</a></p><p>如果不是你本人的操作, 请忽略这封邮件.</p>`,
+		html.EscapeString(user.UserName), int(c.claimExpiration.Minutes()), html.EscapeString(link)))
+	if err := c.sender.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return nil
+}
+
+// ClaimGuestComments 校验邮件中的确认链接, 将用户注册前用相同邮箱发表的游客评论归到用户名下, 返回认领的评论数
+func (c *commentService) ClaimGuestComments(user *model.User, token string) (int64, error) {
+	payload, ok := utils.VerifySign(c.mail.Secret, token)
+	if !ok {
+		return 0, ErrClaimToken
+	}
+	parts := strings.SplitN(payload, ":", 4)
+	if len(parts) != 4 || parts[0] != "claim" {
+		return 0, ErrClaimToken
+	}
+	userID, err := strconv.Atoi(parts[1])
+	if err != nil || uint(userID) != user.ID {
+		return 0, ErrClaimToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, ErrClaimToken
+	}
+	// 发送链接之后修改过邮箱时链接失效
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	if email == "" || parts[3] != email {
+		return 0, ErrClaimToken
+	}
+	return c.commentRepository.ClaimGuestComments(email, user.ID)
+}
+
+// review 检查敏感词并判断是否为垃圾评论, 返回评论应设置的状态.
+// 判定为垃圾评论时直接隐藏; 疑似垃圾评论时与命中敏感词一样进入人工审核
+func (c *commentService) review(user *model.User, content *string) (model.CommentStatus, *moderation.Result, error) {
+	result, err := c.moderationService.Check(content)
+	if err != nil {
+		return "", nil, err
+	}
+	status := model.CommentApproved
+	if result.Action == moderation.ActionReview {
+		status = model.CommentPending
+	}
+	spamStatus, score := c.spamService.Classify(*content)
+	switch spamStatus {
+	case model.CommentSpam:
+		status = model.CommentSpam
+	case model.CommentPending:
+		status = model.CommentPending
+		result.Action = moderation.ActionReview
+	}
+	if spamStatus != model.CommentApproved {
+		zap.S().Infof("comment of user %d classified as %s, score %.3f", user.ID, spamStatus, score)
+	}
+	return status, result, nil
+}
+
+// syncMentions 保存评论中 @ 到的用户, 待审核和垃圾评论只保存 @ 记录, 不通知被 @ 的用户.
+// 只通知无需授权就能看到文章的用户, 例如仅粉丝可见的文章不通知未关注作者的用户
+func (c *commentService) syncMentions(comment *model.Comment) {
+	var notice *MentionNotice
+	if comment.Status == model.CommentApproved {
+		notice = &MentionNotice{Excerpt: comment.Content, CanView: func(userID uint) bool {
+			_, err := c.postService.Authorize(&model.User{Model: gorm.Model{ID: userID}}, comment.PostID, "")
+			return err == nil
+		}}
+	}
+	mentions, err := c.mentionService.Sync(model.MentionComment, comment.ID, comment.PostID, comment.AuthorID, comment.Content, notice)
+	if err != nil {
+		zap.S().Warnf("failed to save mentions of comment %d, %v", comment.ID, err)
+	}
+	comment.Mentions = mentions
+}
+
+// Edit 作者在发表后 editWindow 内可以编辑自己的评论, 管理员可以随时编辑.
+// 作者修改后的内容重新检查敏感词和垃圾评论; 管理员的修改只替换需要屏蔽的词, 不改变评论状态
+func (c *commentService) Edit(user *model.User, id string, content string) (*model.Comment, error) {
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := c.commentRepository.Get(uint(cid))
+	if err != nil {
+		return nil, err
+	}
+	admin := utils.IsAdmin(user)
+	if !admin {
+		if comment.AuthorID != user.ID {
+			return nil, ErrCommentNotOwner
+		}
+		// 作者看不到已隐藏的评论
+		if comment.Status == model.CommentHidden || comment.Status == model.CommentSpam {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if time.Since(comment.CreatedAt) > c.editWindow {
+			return nil, ErrCommentEditExpired
+		}
+	}
+
+	status := comment.Status
+	var result *moderation.Result
+	if admin {
+		_, err = c.moderationService.Check(&content)
+	} else {
+		status, result, err = c.review(user, &content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content == comment.Content && status == comment.Status {
+		return comment, nil
+	}
+	comment.Content, comment.Status = content, status
+	edited, err := c.commentRepository.Edit(comment, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		if edited.Status == model.CommentPending {
+			err = c.moderationService.Hold(model.ModerationComment, edited.ID, edited.AuthorID, edited.Content, result)
+		} else {
+			err = c.moderationService.Release(model.ModerationComment, edited.ID)
+		}
+		if err != nil {
+			zap.S().Warnf("failed to update moderation review of comment %d, %v", edited.ID, err)
+		}
+	}
+	c.syncMentions(edited)
+	return edited, nil
+}
+
+// ListRevisions 管理员查看评论的编辑历史
+func (c *commentService) ListRevisions(user *model.User, id string) ([]model.CommentRevision, error) {
+	if !utils.IsAdmin(user) {
+		return nil, ErrModerationForbidden
+	}
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.commentRepository.Get(uint(cid)); err != nil {
+		return nil, err
+	}
+	return c.commentRepository.ListRevisions(uint(cid))
 }
 
 func (c *commentService) Delete(id string) error {
-	return c.commentRepository.Delete(id)
+	if err := c.commentRepository.Delete(id); err != nil {
+		return err
+	}
+	if cid, err := strconv.Atoi(id); err == nil {
+		if err := c.moderationService.Release(model.ModerationComment, uint(cid)); err != nil {
+			zap.S().Warnf("failed to release moderation review of comment %d, %v", cid, err)
+		}
+	}
+	return nil
 }
 
 func (c *commentService) List(aid string) ([]model.Comment, error) {
 	return c.commentRepository.List(aid)
 }
+
+func (c *commentService) ListThreads(user *model.User, postID string, grant string, sort model.CommentSort, page, pageSize, replies int) ([]model.Comment, int64, error) {
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := c.postService.Authorize(user, uint(pid), grant); err != nil {
+		return nil, 0, err
+	}
+	if replies < 0 {
+		replies = defaultReplyPreview
+	}
+	if replies > maxReplyPreview {
+		replies = maxReplyPreview
+	}
+	threads, total, err := c.commentRepository.ListThreads(uint(pid), user.ID, sort, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	rootIDs := make([]uint, len(threads))
+	for i := range threads {
+		rootIDs[i] = threads[i].ID
+	}
+	previews, counts, err := c.commentRepository.ListReplyPreviews(rootIDs, user.ID, replies)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range threads {
+		thread := &threads[i]
+		thread.Replies = previews[thread.ID]
+		if thread.Replies == nil {
+			thread.Replies = []model.Comment{}
+		}
+		thread.ReplyCount = counts[thread.ID]
+		// 还有更多回复时, 客户端使用该游标调用加载更多回复的接口; 没有预先返回回复时不需要游标
+		if n := len(thread.Replies); n > 0 && int64(n) < thread.ReplyCount {
+			thread.RepliesCursor = utils.EncodeCursor(&model.Cursor{ID: thread.Replies[n-1].ID})
+		}
+	}
+	if err := c.fillMentions(threads); err != nil {
+		return nil, 0, err
+	}
+	return threads, total, nil
+}
+
+func (c *commentService) ListReplies(user *model.User, rootID string, grant string, cursor *model.Cursor, limit int) ([]model.Comment, *model.Cursor, error) {
+	root, err := c.root(rootID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := c.postService.Authorize(user, root.PostID, grant); err != nil {
+		return nil, nil, err
+	}
+	replies, next, err := c.commentRepository.ListReplies(root.ID, user.ID, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.fillMentions(replies); err != nil {
+		return nil, nil, err
+	}
+	return replies, next, nil
+}
+
+// fillMentions 批量获取评论及其预先返回的回复中 @ 到的用户
+func (c *commentService) fillMentions(comments []model.Comment) error {
+	ids := make([]uint, 0, len(comments))
+	for i := range comments {
+		ids = append(ids, comments[i].ID)
+		for j := range comments[i].Replies {
+			ids = append(ids, comments[i].Replies[j].ID)
+		}
+	}
+	mentions, err := c.mentionService.ListByTargets(model.MentionComment, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].ID]
+		for j := range comments[i].Replies {
+			comments[i].Replies[j].Mentions = mentions[comments[i].Replies[j].ID]
+		}
+	}
+	return nil
+}
+
+// root 获取评论所在楼层的顶层评论
+func (c *commentService) root(id string) (*model.Comment, error) {
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := c.commentRepository.Get(uint(cid))
+	if err != nil {
+		return nil, err
+	}
+	if comment.RootID != nil {
+		return c.commentRepository.Get(*comment.RootID)
+	}
+	return comment, nil
+}
+
+func (c *commentService) Count(user *model.User, postID string, grant string) (int64, error) {
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.postService.Authorize(user, uint(pid), grant); err != nil {
+		return 0, err
+	}
+	return c.commentRepository.CountByPost(uint(pid), user.ID)
+}
+
+func (c *commentService) Like(user *model.User, id string, grant string) error {
+	comment, err := c.Authorize(user, id, grant)
+	if err != nil {
+		return err
+	}
+	return c.commentRepository.Like(comment.ID, user.ID)
+}
+
+func (c *commentService) Unlike(user *model.User, id string) error {
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	return c.commentRepository.Unlike(uint(cid), user.ID)
+}
+
+func (c *commentService) Authorize(user *model.User, id string, grant string) (*model.Comment, error) {
+	cid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := c.commentRepository.Get(uint(cid))
+	if err != nil {
+		return nil, err
+	}
+	if comment.Status != model.CommentApproved {
+		return nil, ErrPostForbidden
+	}
+	if _, err := c.postService.Authorize(user, comment.PostID, grant); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
@@ -1 +1,331 @@
 package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/exporter"
+	"inkgo/model"
+	"inkgo/repository"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExportDir       = "./exports"
+	defaultExportRetention = 72
+	defaultExportInterval  = 30
+	// 单篇导出时下载图片的总超时时间
+	exportImageTimeout = 30 * time.Second
+)
+
+var (
+	ErrExportForbidden = errors.New("无权访问该导出任务")
+	ErrExportNotReady  = errors.New("导出任务尚未完成")
+	ErrExportRunning   = errors.New("已有未完成的导出任务, 请稍后再试")
+	ErrExportEmpty     = errors.New("没有可导出的文章")
+)
+
+// ExportFile 导出生成的文件
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// ExportService 导出文章, 批量导出作为后台任务执行, 因此同时实现了 Job
+type ExportService interface {
+	Job
+	// ExportPost 导出单篇文章, 访问权限与查看文章详情相同
+	ExportPost(user *model.User, id string, grant string, format string) (*ExportFile, error)
+	// ExportAll 为当前用户创建导出全部文章的任务
+	ExportAll(user *model.User, format string) (*model.ExportTask, error)
+	// ListTasks 列出当前用户的导出任务
+	ListTasks(user *model.User) ([]model.ExportTask, error)
+	// GetTask 获取当前用户的导出任务
+	GetTask(user *model.User, id string) (*model.ExportTask, error)
+	// TaskFile 获取已完成的导出任务, 用于下载文件
+	TaskFile(user *model.User, id string) (*model.ExportTask, error)
+}
+
+type exportService struct {
+	conf             config.ExportConfig
+	postService      PostService
+	postRepository   repository.PostRepository
+	exportRepository repository.ExportRepository
+	imageLoader      exporter.ImageLoader
+	notify           chan struct{}
+}
+
+func NewExportService(conf config.ExportConfig, postService PostService, postRepository repository.PostRepository,
+	exportRepository repository.ExportRepository) ExportService {
+	if conf.Dir == "" {
+		conf.Dir = defaultExportDir
+	}
+	if conf.Retention <= 0 {
+		conf.Retention = defaultExportRetention
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultExportInterval
+	}
+	return &exportService{
+		conf:             conf,
+		postService:      postService,
+		postRepository:   postRepository,
+		exportRepository: exportRepository,
+		imageLoader:      exporter.NewHTTPImageLoader(conf.ImageHosts, 10*time.Second),
+		notify:           make(chan struct{}, 1),
+	}
+}
+
+func (e *exportService) ExportPost(user *model.User, id string, grant string, format string) (*ExportFile, error) {
+	f, err := exporter.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	// 导出不算阅读, 校验权限后直接读取文章, 不经过 GetPostByID
+	if _, err := e.postService.Authorize(user, uint(pid), grant); err != nil {
+		return nil, err
+	}
+	post, err := e.postRepository.FindDetailByID(uint(pid))
+	if err != nil {
+		return nil, err
+	}
+	hideUnauthorizedContent(post)
+	book := &exporter.Book{
+		Title:    post.Title,
+		Author:   post.Author.UserName,
+		Articles: []*exporter.Article{newExportArticle(post)},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportImageTimeout)
+	defer cancel()
+	exporter.LoadImages(ctx, book, e.imageLoader)
+
+	var buf bytes.Buffer
+	if err := exporter.Write(&buf, f, book, exporter.Options{FontPath: e.conf.FontPath}); err != nil {
+		return nil, err
+	}
+	return &ExportFile{
+		Name:        exportFileName(post.Title, f),
+		ContentType: f.ContentType(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+func (e *exportService) ExportAll(user *model.User, format string) (*model.ExportTask, error) {
+	f, err := exporter.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := e.exportRepository.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if task.Status == model.ExportPending || task.Status == model.ExportRunning {
+			return nil, ErrExportRunning
+		}
+	}
+	task, err := e.exportRepository.Create(&model.ExportTask{
+		UserID:   user.ID,
+		Format:   string(f),
+		Status:   model.ExportPending,
+		FileName: exportFileName(fmt.Sprintf("%s-posts-%s", user.UserName, time.Now().Format("20060102")), f),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 通知后台任务立即处理, 已有通知未处理时不再重复发送
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+	return task, nil
+}
+
+func (e *exportService) ListTasks(user *model.User) ([]model.ExportTask, error) {
+	return e.exportRepository.ListByUser(user.ID)
+}
+
+func (e *exportService) GetTask(user *model.User, id string) (*model.ExportTask, error) {
+	tid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	task, err := e.exportRepository.Get(uint(tid))
+	if err != nil {
+		return nil, err
+	}
+	if task.UserID != user.ID {
+		return nil, ErrExportForbidden
+	}
+	return task, nil
+}
+
+func (e *exportService) TaskFile(user *model.User, id string) (*model.ExportTask, error) {
+	task, err := e.GetTask(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status != model.ExportDone {
+		return nil, ErrExportNotReady
+	}
+	return task, nil
+}
+
+func (e *exportService) Name() string {
+	return "export"
+}
+
+func (e *exportService) Run(ctx context.Context) {
+	// 上次退出时未完成的任务重新处理
+	if err := e.exportRepository.ResetRunning(); err != nil {
+		zap.S().Warnf("failed to reset running export tasks, %v", err)
+	}
+	ticker := time.NewTicker(time.Duration(e.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		e.processPending(ctx)
+		e.cleanup()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-e.notify:
+		}
+	}
+}
+
+// processPending 依次处理所有待处理的任务
+func (e *exportService) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		task, err := e.exportRepository.Claim()
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				zap.S().Warnf("failed to claim export task, %v", err)
+			}
+			return
+		}
+		if err := e.process(ctx, task); err != nil {
+			zap.S().Warnf("export task %d failed, %v", task.ID, err)
+			task.Status = model.ExportFailed
+			task.Error = err.Error()
+		} else {
+			task.Status = model.ExportDone
+		}
+		now := time.Now()
+		task.FinishedAt = &now
+		if err := e.exportRepository.Save(task); err != nil {
+			zap.S().Warnf("failed to save export task %d, %v", task.ID, err)
+		}
+	}
+}
+
+func (e *exportService) process(ctx context.Context, task *model.ExportTask) error {
+	posts, err := e.postRepository.ListByAuthor(task.UserID)
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return ErrExportEmpty
+	}
+	book := &exporter.Book{
+		Title:    fmt.Sprintf("%s 的文章", posts[0].Author.UserName),
+		Author:   posts[0].Author.UserName,
+		Articles: make([]*exporter.Article, 0, len(posts)),
+	}
+	for i := range posts {
+		hideUnauthorizedContent(&posts[i])
+		book.Articles = append(book.Articles, newExportArticle(&posts[i]))
+	}
+	exporter.LoadImages(ctx, book, e.imageLoader)
+
+	if err := os.MkdirAll(e.conf.Dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(e.conf.Dir, fmt.Sprintf("export-%d-%d.%s", task.UserID, task.ID, task.Format))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = exporter.Write(file, exporter.Format(task.Format), book, exporter.Options{FontPath: e.conf.FontPath})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	task.FilePath, task.Size, task.PostCount = path, info.Size(), len(posts)
+	return nil
+}
+
+// cleanup 删除超过保留时间的导出文件和任务记录
+func (e *exportService) cleanup() {
+	tasks, err := e.exportRepository.ListFinishedBefore(time.Now().Add(-time.Duration(e.conf.Retention) * time.Hour))
+	if err != nil {
+		zap.S().Warnf("failed to list expired export tasks, %v", err)
+		return
+	}
+	for _, task := range tasks {
+		if task.FilePath != "" {
+			if err := os.Remove(task.FilePath); err != nil && !os.IsNotExist(err) {
+				zap.S().Warnf("failed to remove export file %s, %v", task.FilePath, err)
+				continue
+			}
+		}
+		if err := e.exportRepository.Delete(task.ID); err != nil {
+			zap.S().Warnf("failed to delete export task %d, %v", task.ID, err)
+		}
+	}
+}
+
+// newExportArticle 将文章转换为导出内容, 正文按 Markdown 解析
+func newExportArticle(post *model.Post) *exporter.Article {
+	article := &exporter.Article{
+		Title:   post.Title,
+		Author:  post.Author.UserName,
+		Date:    post.CreatedAt,
+		Updated: post.UpdatedAt,
+		Blocks:  exporter.ParseMarkdown(post.Content),
+	}
+	for _, tag := range post.Tags {
+		article.Tags = append(article.Tags, tag.Name)
+	}
+	for _, category := range post.Categories {
+		article.Categories = append(article.Categories, category.Name)
+	}
+	if post.Repost != nil {
+		article.Source = post.Repost.Url
+	}
+	return article
+}
+
+// exportFileName 去掉文件名中不能使用的字符
+func exportFileName(title string, format exporter.Format) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "export"
+	}
+	return name + "." + string(format)
+}
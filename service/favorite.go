@@ -1,11 +1,22 @@
 package service
 
 import (
+	"errors"
+	"gorm.io/gorm"
 	"inkgo/model"
 	"inkgo/repository"
 	"strconv"
 )
 
+// 一次最多批量收藏或排序的文章数
+const favoriteBatchMaxPosts = 200
+
+var (
+	ErrFavoriteNotFound = repository.ErrFavoriteNotFound
+	ErrFavoriteBatch    = errors.New("文章列表为空或超过 200 篇")
+	ErrFavoriteFollow   = errors.New("只能关注他人的公开收藏夹")
+)
+
 type FavoriteService interface {
 	CreateFavorite(uid string, favorite *model.Favorite) (*model.Favorite, error)
 	DeleteFavorite(uid string, id string) error
@@ -13,25 +24,58 @@ type FavoriteService interface {
 	GetFavoriteByID(uid, fid string) (*model.Favorite, error)
 	// GetUserFavorites 获取某个用户创建的所有收藏夹列表
 	GetUserFavorites(userID string, page, pageSize int) ([]model.Favorite, int64, error)
+	GetUserFavoritesByCursor(userID string, cursor *model.Cursor, limit int) ([]model.Favorite, *model.Cursor, error)
 
-	AddPostToFavorite(uid string, pid string, fid string) error
+	AddPostToFavorite(uid string, pid string, fid string, note string) error
 	RemovePostFromFavorite(uid string, pid string, fid string) error
 	IsPostInFavorite(uid string, fid, pid string) (bool, error)
 
 	ListPostsInFavorite(uid string, fid string) ([]model.Post, error) // 列出收藏夹中的文章
 	CountPostsInFavorite(uid string, fid string) (int64, error)       // 获取收藏夹中的文章数量
+
+	// BatchAddPosts 按顺序批量收藏文章, 返回新加入的数量
+	BatchAddPosts(user *model.User, fid string, postIDs []uint) (int64, error)
+	// Clear 清空收藏夹, 返回移除的数量
+	Clear(user *model.User, fid string) (int64, error)
+	// UpdateNote 修改收藏的备注
+	UpdateNote(user *model.User, fid, pid string, note string) error
+	// Reorder 按 postIDs 的顺序排列收藏夹中的文章
+	Reorder(user *model.User, fid string, postIDs []uint) error
+	// ListItems 分页列出收藏夹中的文章及备注和收藏时间, 公开的收藏夹所有人可读
+	ListItems(user *model.User, fid string, page, pageSize int) ([]model.FavoritePost, int64, error)
+	// ListFavoritesOfPost 自己的和公开的收藏夹中收藏了该文章的收藏夹
+	ListFavoritesOfPost(user *model.User, pid string) ([]model.Favorite, error)
+
+	// Follow 关注他人的公开收藏夹, 之后加入的文章会出现在动态中
+	Follow(user *model.User, fid string) error
+	Unfollow(user *model.User, fid string) error
+	// ListFollowed 分页获取关注的收藏夹
+	ListFollowed(user *model.User, page, pageSize int) ([]model.FavoriteFollow, int64, error)
+	// ListFollowedActivity 分页获取关注的收藏夹中新加入的文章
+	ListFollowedActivity(user *model.User, page, pageSize int) ([]model.FavoriteActivity, int64, error)
 }
 
 type favoriteService struct {
 	favoriteRepository repository.FavoriteRepository
+	postService        PostService
 }
 
-func NewFavoriteService(favoriteRepository repository.FavoriteRepository) FavoriteService {
+func NewFavoriteService(favoriteRepository repository.FavoriteRepository, postService PostService) FavoriteService {
 	return &favoriteService{
 		favoriteRepository: favoriteRepository,
+		postService:        postService,
 	}
 }
 
+// canFavorite 只能收藏自己可以看到的文章, 加密文章可以收藏, 列表中不返回正文
+func (f *favoriteService) canFavorite(userID uint, postID uint) error {
+	_, err := f.postService.Authorize(&model.User{Model: gorm.Model{ID: userID}}, postID, "")
+	if err != nil && !errors.Is(err, ErrPostLocked) {
+		return err
+	}
+	return nil
+}
+
 func (f *favoriteService) CreateFavorite(uid string, favorite *model.Favorite) (*model.Favorite, error) {
 	uidInt, err := strconv.Atoi(uid)
 	if err != nil {
@@ -86,7 +130,16 @@ func (f *favoriteService) GetUserFavorites(uid string, page int, pageSize int) (
 	return favorites, total, nil
 }
 
-func (f *favoriteService) AddPostToFavorite(uid, pid string, fid string) error {
+// GetUserFavoritesByCursor 按游标获取用户的收藏夹
+func (f *favoriteService) GetUserFavoritesByCursor(uid string, cursor *model.Cursor, limit int) ([]model.Favorite, *model.Cursor, error) {
+	uidInt, err := strconv.Atoi(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.favoriteRepository.GetUserFavoritesByCursor(uint(uidInt), cursor, limit)
+}
+
+func (f *favoriteService) AddPostToFavorite(uid, pid string, fid string, note string) error {
 	pidInt, err := strconv.Atoi(pid)
 	if err != nil {
 		return err
@@ -99,7 +152,10 @@ func (f *favoriteService) AddPostToFavorite(uid, pid string, fid string) error {
 	if err != nil {
 		return err
 	}
-	return f.favoriteRepository.AddPostToFavorite(uint(uidInt), uint(pidInt), uint(fidInt))
+	if err := f.canFavorite(uint(uidInt), uint(pidInt)); err != nil {
+		return err
+	}
+	return f.favoriteRepository.AddPostToFavorite(uint(uidInt), uint(pidInt), uint(fidInt), note)
 }
 
 func (f *favoriteService) RemovePostFromFavorite(uid, pid string, fid string) error {
@@ -165,3 +221,111 @@ func (f *favoriteService) CountPostsInFavorite(uid, fid string) (int64, error) {
 	}
 	return count, nil
 }
+
+func (f *favoriteService) BatchAddPosts(user *model.User, fid string, postIDs []uint) (int64, error) {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return 0, err
+	}
+	if len(postIDs) == 0 || len(postIDs) > favoriteBatchMaxPosts {
+		return 0, ErrFavoriteBatch
+	}
+	// 跳过不存在和无权查看的文章
+	allowed := make([]uint, 0, len(postIDs))
+	for _, id := range postIDs {
+		err := f.canFavorite(user.ID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrPostForbidden) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		allowed = append(allowed, id)
+	}
+	if len(allowed) == 0 {
+		return 0, nil
+	}
+	return f.favoriteRepository.BatchAddPostsToFavorite(user.ID, uint(fidInt), allowed)
+}
+
+func (f *favoriteService) Clear(user *model.User, fid string) (int64, error) {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return 0, err
+	}
+	return f.favoriteRepository.RemoveAllPostsFromFavorite(user.ID, uint(fidInt))
+}
+
+func (f *favoriteService) UpdateNote(user *model.User, fid, pid string, note string) error {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return err
+	}
+	pidInt, err := strconv.Atoi(pid)
+	if err != nil {
+		return err
+	}
+	return f.favoriteRepository.UpdatePostNote(user.ID, uint(fidInt), uint(pidInt), note)
+}
+
+func (f *favoriteService) Reorder(user *model.User, fid string, postIDs []uint) error {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return err
+	}
+	if len(postIDs) == 0 || len(postIDs) > favoriteBatchMaxPosts {
+		return ErrFavoriteBatch
+	}
+	return f.favoriteRepository.ReorderPosts(user.ID, uint(fidInt), postIDs)
+}
+
+func (f *favoriteService) ListItems(user *model.User, fid string, page, pageSize int) ([]model.FavoritePost, int64, error) {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, pageSize = followPage(page, pageSize)
+	return f.favoriteRepository.ListItems(user.ID, uint(fidInt), page, pageSize)
+}
+
+func (f *favoriteService) ListFavoritesOfPost(user *model.User, pid string) ([]model.Favorite, error) {
+	pidInt, err := strconv.Atoi(pid)
+	if err != nil {
+		return nil, err
+	}
+	return f.favoriteRepository.ListFavoritesOfPost(user.ID, uint(pidInt))
+}
+
+func (f *favoriteService) Follow(user *model.User, fid string) error {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return err
+	}
+	favorite, err := f.favoriteRepository.GetFavoriteByID(user.ID, uint(fidInt))
+	if err != nil {
+		return err
+	}
+	// 自己的收藏夹不需要关注, 私有收藏夹在上面已经查不到
+	if favorite.UserID == user.ID {
+		return ErrFavoriteFollow
+	}
+	return f.favoriteRepository.FollowFavorite(user.ID, favorite.ID)
+}
+
+func (f *favoriteService) Unfollow(user *model.User, fid string) error {
+	fidInt, err := strconv.Atoi(fid)
+	if err != nil {
+		return err
+	}
+	return f.favoriteRepository.UnfollowFavorite(user.ID, uint(fidInt))
+}
+
+func (f *favoriteService) ListFollowed(user *model.User, page, pageSize int) ([]model.FavoriteFollow, int64, error) {
+	page, pageSize = followPage(page, pageSize)
+	return f.favoriteRepository.ListFollowedFavorites(user.ID, page, pageSize)
+}
+
+func (f *favoriteService) ListFollowedActivity(user *model.User, page, pageSize int) ([]model.FavoriteActivity, int64, error) {
+	page, pageSize = followPage(page, pageSize)
+	return f.favoriteRepository.ListFollowedActivity(user.ID, page, pageSize)
+}
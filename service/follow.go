@@ -0,0 +1,218 @@
+package service
+
+import (
+	"errors"
+	"inkgo/model"
+	"inkgo/repository"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultFollowSuggestions = 10
+	maxFollowSuggestions     = 50
+	// 共同关注比点赞过文章更能说明兴趣相近, 排序时权重更高
+	mutualSuggestionWeight = 2
+)
+
+var (
+	ErrFollowSelf    = errors.New("不能关注自己")
+	ErrFollowBlocked = errors.New("对方已将你拉黑, 无法关注")
+	ErrFollowTarget  = errors.New("无效的关注类型, 可选值为 tag, category")
+)
+
+// FollowService 关注和取消关注用户, 以及推荐关注
+type FollowService interface {
+	Follow(user *model.User, id string) error
+	Unfollow(user *model.User, id string) error
+	// ListFollowing 分页获取用户 id 关注的人
+	ListFollowing(id string, page, pageSize int) ([]model.FollowUser, int64, error)
+	// ListFollowers 分页获取用户 id 的粉丝
+	ListFollowers(id string, page, pageSize int) ([]model.FollowUser, int64, error)
+	// ListFollowingByCursor 和 ListFollowersByCursor 按游标分页, 返回下一页的游标, 没有下一页时为 nil
+	ListFollowingByCursor(id string, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error)
+	ListFollowersByCursor(id string, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error)
+	// Suggest 根据关注的人所关注的用户, 以及点赞过的文章的作者推荐关注
+	Suggest(user *model.User, limit int) ([]model.FollowSuggestion, error)
+	// FollowTopic 关注标签或分类, 其下的新文章出现在首页时间线中
+	FollowTopic(user *model.User, target model.FollowTarget, id string) error
+	// UnfollowTopic 取消关注标签或分类
+	UnfollowTopic(user *model.User, target model.FollowTarget, id string) error
+	// ListTopics 获取用户关注的标签和分类
+	ListTopics(user *model.User) ([]model.TopicFollow, error)
+}
+
+type followService struct {
+	followRepository   repository.FollowRepository
+	userRepository     repository.UserRepository
+	blockRepository    repository.BlockRepository
+	tagRepository      repository.TagRepository
+	categoryRepository repository.CategoryRepository
+	timelineService    TimelineService
+}
+
+func NewFollowService(followRepository repository.FollowRepository, userRepository repository.UserRepository,
+	blockRepository repository.BlockRepository, tagRepository repository.TagRepository,
+	categoryRepository repository.CategoryRepository, timelineService TimelineService) FollowService {
+	return &followService{
+		followRepository:   followRepository,
+		userRepository:     userRepository,
+		blockRepository:    blockRepository,
+		tagRepository:      tagRepository,
+		categoryRepository: categoryRepository,
+		timelineService:    timelineService,
+	}
+}
+
+func (f *followService) Follow(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	if uint(uid) == user.ID {
+		return ErrFollowSelf
+	}
+	if _, err := f.userRepository.GetUserByID(uint(uid)); err != nil {
+		return err
+	}
+	blockers, err := f.blockRepository.ListBlockers(user.ID, []uint{uint(uid)})
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return ErrFollowBlocked
+	}
+	if err := f.followRepository.Follow(user.ID, uint(uid)); err != nil {
+		return err
+	}
+	f.timelineService.Invalidate(user.ID)
+	return nil
+}
+
+func (f *followService) Unfollow(user *model.User, id string) error {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	if err := f.followRepository.Unfollow(user.ID, uint(uid)); err != nil {
+		return err
+	}
+	f.timelineService.Invalidate(user.ID)
+	return nil
+}
+
+func (f *followService) ListFollowing(id string, page, pageSize int) ([]model.FollowUser, int64, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, pageSize = followPage(page, pageSize)
+	return f.followRepository.ListFollowing(uint(uid), page, pageSize)
+}
+
+func (f *followService) ListFollowers(id string, page, pageSize int) ([]model.FollowUser, int64, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, pageSize = followPage(page, pageSize)
+	return f.followRepository.ListFollowers(uint(uid), page, pageSize)
+}
+
+func (f *followService) ListFollowingByCursor(id string, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.followRepository.GetFollowedListByCursor(uint(uid), cursor, limit)
+}
+
+func (f *followService) ListFollowersByCursor(id string, cursor *model.Cursor, limit int) ([]model.FollowUser, *model.Cursor, error) {
+	uid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f.followRepository.GetFollowerListByCursor(uint(uid), cursor, limit)
+}
+
+func followPage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
+
+func (f *followService) Suggest(user *model.User, limit int) ([]model.FollowSuggestion, error) {
+	if limit <= 0 || limit > maxFollowSuggestions {
+		limit = defaultFollowSuggestions
+	}
+	friends, err := f.followRepository.ListFriendsOfFriends(user.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := f.followRepository.ListLikedAuthors(user.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 合并两种来源, 同一用户累加
+	index := make(map[uint]int, len(friends)+len(authors))
+	suggestions := make([]model.FollowSuggestion, 0, len(friends)+len(authors))
+	for _, candidate := range append(friends, authors...) {
+		if i, ok := index[candidate.ID]; ok {
+			suggestions[i].Mutuals += candidate.Mutuals
+			suggestions[i].Likes += candidate.Likes
+			continue
+		}
+		index[candidate.ID] = len(suggestions)
+		suggestions = append(suggestions, candidate)
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestionScore(&suggestions[i]) > suggestionScore(&suggestions[j])
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+func suggestionScore(s *model.FollowSuggestion) int64 {
+	return s.Mutuals*mutualSuggestionWeight + s.Likes
+}
+
+func (f *followService) FollowTopic(user *model.User, target model.FollowTarget, id string) error {
+	tid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	switch target {
+	case model.FollowTag:
+		_, err = f.tagRepository.Get(uint(tid))
+	case model.FollowCategory:
+		_, err = f.categoryRepository.Get(uint(tid))
+	default:
+		return ErrFollowTarget
+	}
+	if err != nil {
+		return err
+	}
+	return f.followRepository.FollowTopic(user.ID, target, uint(tid))
+}
+
+func (f *followService) UnfollowTopic(user *model.User, target model.FollowTarget, id string) error {
+	if target != model.FollowTag && target != model.FollowCategory {
+		return ErrFollowTarget
+	}
+	tid, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	return f.followRepository.UnfollowTopic(user.ID, target, uint(tid))
+}
+
+func (f *followService) ListTopics(user *model.User) ([]model.TopicFollow, error) {
+	return f.followRepository.ListTopics(user.ID)
+}
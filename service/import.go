@@ -0,0 +1,256 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/importer"
+	"inkgo/model"
+	"inkgo/repository"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// 文章标题的最大长度, 与 post.title 列一致
+const importTitleMaxLength = 100
+
+// 默认的上传和解压大小上限, 单位为 MB
+const (
+	defaultImportMaxUploadSize   = 32
+	defaultImportMaxUnpackedSize = 128
+)
+
+var ErrImportForbidden = errors.New("只有管理员可以导入文章")
+
+// 导入条目的处理结果
+const (
+	ImportActionCreate = "create" // 新建文章
+	ImportActionSkip   = "skip"   // 跳过
+)
+
+// ImportItem 单篇文章的导入计划或结果
+type ImportItem struct {
+	Source     string          `json:"source"`
+	Title      string          `json:"title"`
+	Author     string          `json:"author"`    // 原作者用户名
+	AuthorID   uint            `json:"author_id"` // 导入后归属的用户
+	Date       time.Time       `json:"date"`
+	State      model.PostState `json:"state"`
+	Tags       []string        `json:"tags"`
+	Categories []string        `json:"categories"`
+	Action     string          `json:"action"`
+	Reason     string          `json:"reason,omitempty"`  // 跳过原因或其它提示
+	PostID     uint            `json:"post_id,omitempty"` // 实际导入后的文章ID
+}
+
+// ImportReport 导入报告, dry-run 时只列出将要发生的变更
+type ImportReport struct {
+	DryRun         bool         `json:"dry_run"`
+	Total          int          `json:"total"`
+	Created        int          `json:"created"`
+	Skipped        int          `json:"skipped"`
+	NewTags        []string     `json:"new_tags"`        // 将要新建的标签
+	NewCategories  []string     `json:"new_categories"`  // 将要新建的分类
+	UnknownAuthors []string     `json:"unknown_authors"` // 站内找不到的原作者, 其文章归属导入者
+	Items          []ImportItem `json:"items"`
+}
+
+type ImportService interface {
+	// Import 导入解析好的文章, dryRun 为 true 时不写入数据库, 只返回差异报告
+	Import(user *model.User, docs []*importer.Document, dryRun bool) (*ImportReport, error)
+	// MaxUploadSize 一次上传的文件总大小上限, 单位为字节
+	MaxUploadSize() int64
+	// UnpackLimit 一次上传的压缩包解压后的大小上限
+	UnpackLimit() *importer.Limit
+}
+
+type importService struct {
+	conf             config.ImportConfig
+	importRepository repository.ImportRepository
+	userRepository   repository.UserRepository
+}
+
+func NewImportService(conf config.ImportConfig, importRepository repository.ImportRepository, userRepository repository.UserRepository) ImportService {
+	if conf.MaxUploadSize <= 0 {
+		conf.MaxUploadSize = defaultImportMaxUploadSize
+	}
+	if conf.MaxUnpackedSize <= 0 {
+		conf.MaxUnpackedSize = defaultImportMaxUnpackedSize
+	}
+	return &importService{
+		conf:             conf,
+		importRepository: importRepository,
+		userRepository:   userRepository,
+	}
+}
+
+func (i *importService) MaxUploadSize() int64 {
+	return i.conf.MaxUploadSize << 20
+}
+
+func (i *importService) UnpackLimit() *importer.Limit {
+	return importer.NewLimit(i.conf.MaxUnpackedSize << 20)
+}
+
+func (i *importService) Import(user *model.User, docs []*importer.Document, dryRun bool) (*ImportReport, error) {
+	if user == nil || user.Role != model.RoleAdmin {
+		return nil, ErrImportForbidden
+	}
+
+	report := &ImportReport{DryRun: dryRun, Total: len(docs), Items: make([]ImportItem, 0, len(docs))}
+	authors := map[string]uint{user.UserName: user.ID}
+	unknown := map[string]bool{}
+	seen := map[string]bool{} // 同一批次中的重复文章
+	var tagNames, categoryNames []string
+	var posts []*model.Post
+	var created []int // posts 对应的 report.Items 下标
+
+	for _, doc := range docs {
+		item := ImportItem{
+			Source:     doc.Source,
+			Title:      doc.Title,
+			Author:     doc.Author,
+			Date:       doc.Date,
+			State:      model.PostPublished,
+			Tags:       doc.Tags,
+			Categories: doc.Categories,
+			Action:     ImportActionCreate,
+		}
+		if doc.Draft {
+			item.State = model.PostDraft
+		}
+
+		authorID, err := i.resolveAuthor(authors, doc.Author)
+		if err != nil {
+			return nil, err
+		}
+		if authorID == 0 {
+			authorID = user.ID
+			if doc.Author != "" && !unknown[doc.Author] {
+				unknown[doc.Author] = true
+				report.UnknownAuthors = append(report.UnknownAuthors, doc.Author)
+			}
+			if doc.Author != "" {
+				item.Reason = fmt.Sprintf("作者 %s 不存在, 归属到 %s", doc.Author, user.UserName)
+			}
+		}
+		item.AuthorID = authorID
+
+		key := fmt.Sprintf("%d:%s", authorID, doc.Title)
+		switch {
+		case doc.Title == "":
+			item.Action, item.Reason = ImportActionSkip, "缺少标题"
+		case utf8.RuneCountInString(doc.Title) > importTitleMaxLength:
+			item.Action, item.Reason = ImportActionSkip, fmt.Sprintf("标题超过 %d 个字符", importTitleMaxLength)
+		case seen[key]:
+			item.Action, item.Reason = ImportActionSkip, "与本次导入中的文章重复"
+		default:
+			exists, err := i.importRepository.PostExists(authorID, doc.Title)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				item.Action, item.Reason = ImportActionSkip, "作者已有同名文章"
+			}
+		}
+		seen[key] = true
+
+		if item.Action == ImportActionSkip {
+			report.Skipped++
+			report.Items = append(report.Items, item)
+			continue
+		}
+		report.Created++
+		tagNames = append(tagNames, doc.Tags...)
+		categoryNames = append(categoryNames, doc.Categories...)
+		posts = append(posts, newImportedPost(doc, authorID, item.State))
+		created = append(created, len(report.Items))
+		report.Items = append(report.Items, item)
+	}
+
+	var err error
+	if report.NewTags, err = i.missingNames(tagNames, i.importRepository.ExistingTags); err != nil {
+		return nil, err
+	}
+	if report.NewCategories, err = i.missingNames(categoryNames, i.importRepository.ExistingCategories); err != nil {
+		return nil, err
+	}
+	if dryRun || len(posts) == 0 {
+		return report, nil
+	}
+
+	if err := i.importRepository.Import(posts); err != nil {
+		return nil, err
+	}
+	for idx, post := range posts {
+		report.Items[created[idx]].PostID = post.ID
+	}
+	return report, nil
+}
+
+// resolveAuthor 按用户名查找原作者, 不存在时返回 0
+func (i *importService) resolveAuthor(authors map[string]uint, name string) (uint, error) {
+	if name == "" {
+		return 0, nil
+	}
+	if id, ok := authors[name]; ok {
+		return id, nil
+	}
+	author, err := i.userRepository.FindByUserName(name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if author != nil {
+		authors[name] = author.ID
+	} else {
+		authors[name] = 0
+	}
+	return authors[name], nil
+}
+
+// missingNames 返回 names 中数据库里还不存在的名称, 已去重并排序
+func (i *importService) missingNames(names []string, existing func([]string) (map[string]bool, error)) ([]string, error) {
+	unique := make(map[string]bool, len(names))
+	for _, name := range names {
+		unique[name] = true
+	}
+	list := make([]string, 0, len(unique))
+	for name := range unique {
+		list = append(list, name)
+	}
+	found, err := existing(list)
+	if err != nil {
+		return nil, err
+	}
+	missing := make([]string, 0)
+	for _, name := range list {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+func newImportedPost(doc *importer.Document, authorID uint, state model.PostState) *model.Post {
+	post := &model.Post{
+		Title:      doc.Title,
+		Content:    doc.Content,
+		AuthorID:   authorID,
+		Original:   true,
+		State:      state,
+		Visibility: model.VisibilityPublic,
+		Version:    1,
+	}
+	post.CreatedAt = doc.Date
+	post.UpdatedAt = doc.Updated
+	for _, name := range doc.Tags {
+		post.Tags = append(post.Tags, model.Tag{Name: name})
+	}
+	for _, name := range doc.Categories {
+		post.Categories = append(post.Categories, model.Category{Name: name})
+	}
+	return post
+}
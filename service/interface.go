@@ -1,7 +1,9 @@
 package service
 
 import (
+	"inkgo/common/request"
 	"inkgo/model"
+	"time"
 )
 
 type UserService interface {
@@ -18,6 +20,8 @@ type UserService interface {
 	FindByEmail(email string) (*model.User, error)
 
 	List(pageSize int, page int) ([]model.User, int64, error)
+	// ListByCursor 按游标获取用户列表, 返回下一页的游标
+	ListByCursor(cursor *model.Cursor, limit int) ([]model.User, *model.Cursor, error)
 	// 创建用户（注册）
 	Create(user *model.User) (*model.User, error)
 	Update(user *model.User) (*model.User, error)
@@ -36,23 +40,42 @@ type UserService interface {
 }
 
 type PostService interface {
-	GetPostByID(id string) (*model.Post, error)
-	GetPostByName(name string) (*model.Post, error)
-	HasPublished(page, pageSize int) ([]model.Post, int64, error)
+	GetPostByID(user *model.User, id string, grant string) (*model.Post, error)
+	// GetSharedPost 免登录查看公开或不公开列出的已发布文章, 供分享短链接跳转
+	GetSharedPost(id string) (*model.Post, error)
+	GetPostByName(user *model.User, name string) (*model.Post, error)
+	// Unlock 用密码解锁加密文章, 返回访问授权及其过期时间
+	Unlock(user *model.User, id string, password string) (string, time.Time, error)
+	HasPublished(user *model.User, page, pageSize int) ([]model.Post, int64, error)
 	ListDrafts(page, pageSize int) ([]model.Post, int64, error)
+	// Query 按标签、分类、作者、状态、时间等条件筛选和排序文章
+	Query(user *model.User, query *model.PostQuery, page, pageSize int) ([]model.Post, int64, error)
+	// HasPublishedByCursor 和 ListDraftsByCursor 按游标分页, 返回下一页的游标, 没有下一页时为 nil
+	HasPublishedByCursor(user *model.User, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
+	ListDraftsByCursor(cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
 	Create(*model.User, *model.Post) (*model.Post, error)
 	Get(user *model.User, id string) (*model.Post, error)
-	Update(id string, post *model.Post) (*model.Post, error)
+	// Update 作者或管理员更新文章, 版本冲突时返回 ErrPostConflict 和最新的文章
+	Update(user *model.User, id string, post *model.Post, version uint) (*model.Post, error)
+	// Autosave 自动保存草稿, 版本规则与 Update 相同
+	Autosave(user *model.User, id string, post *model.Post, version uint) (*model.Post, error)
 	UpdateStatus(id string, state model.PostState) (*model.Post, error)
 	Delete(id string) error
-	SortByViewCountDesc(page int, pageSize int) ([]model.Post, int64, error)
-	ListHotPosts(limit int) ([]model.Post, error)
-	ListRecentPosts(limit int) ([]model.Post, error)
+	SortByViewCountDesc(user *model.User, page int, pageSize int) ([]model.Post, int64, error)
+	ListHotPosts(user *model.User, limit int) ([]model.Post, error)
+	ListRecentPosts(user *model.User, limit int) ([]model.Post, error)
+	// Authorize 校验 user 是否可以查看文章, 无权查看时返回 ErrPostForbidden 或 ErrPostLocked
+	Authorize(user *model.User, id uint, grant string) (*model.Post, error)
+	// ListRelated 获取文章的相关文章, 访问权限与文章详情相同
+	ListRelated(user *model.User, id string, grant string, limit int) ([]model.Post, error)
+	// Bulk 批量修改或删除文章, 返回每篇文章的处理结果
+	Bulk(user *model.User, req *model.BulkRequest) (*model.BulkReport, error)
 }
 
 type LikeService interface {
-	LikePost(user *model.User, pid string) error
-	UnLikePost(user *model.User, pid string) error
+	// LikePost 点赞文章, grant 为解锁加密文章后得到的访问授权
+	LikePost(user *model.User, pid string, grant string) error
+	UnLikePost(user *model.User, pid string, grant string) error
 	CountLikes(id string) (int64, error)
 	IsLiked(user *model.User, id string) (bool, error)
 }
@@ -75,7 +98,30 @@ type CategoryService interface {
 }
 
 type CommentService interface {
-	Add(comment *model.Comment, id string, user *model.User) (*model.Comment, error)
+	// Add 发表顶层评论, postID 为文章ID
+	Add(user *model.User, postID string, grant string, comment *model.Comment) (*model.Comment, error)
+	// AddGuest 游客发表顶层评论, 需要开启游客评论并通过验证码校验
+	AddGuest(postID string, grant string, req *request.GuestCommentRequest) (*model.Comment, error)
+	// RequestClaim 向用户的邮箱发送认领游客评论的确认链接
+	RequestClaim(user *model.User) error
+	// ClaimGuestComments 通过确认链接中的 token 认领用户注册前用相同邮箱发表的游客评论
+	ClaimGuestComments(user *model.User, token string) (int64, error)
+	// Reply 回复评论, 超过最大层级时返回 ErrCommentDepth
+	Reply(user *model.User, parentID string, grant string, comment *model.Comment) (*model.Comment, error)
 	Delete(id string) error
 	List(aid string) ([]model.Comment, error)
+	// ListThreads 分页获取文章的顶层评论, 每条附带最早的 replies 条回复
+	ListThreads(user *model.User, postID string, grant string, sort model.CommentSort, page, pageSize, replies int) ([]model.Comment, int64, error)
+	// ListReplies 加载楼层中更多的回复
+	ListReplies(user *model.User, rootID string, grant string, cursor *model.Cursor, limit int) ([]model.Comment, *model.Cursor, error)
+	// Count 统计文章的评论数, 包括回复
+	Count(user *model.User, postID string, grant string) (int64, error)
+	Like(user *model.User, id string, grant string) error
+	Unlike(user *model.User, id string) error
+	// Edit 编辑评论, 作者超过可编辑时间后返回 ErrCommentEditExpired
+	Edit(user *model.User, id string, content string) (*model.Comment, error)
+	// ListRevisions 管理员查看评论的编辑历史
+	ListRevisions(user *model.User, id string) ([]model.CommentRevision, error)
+	// Authorize 获取 user 可以看到的评论, 供表情等依附于评论的功能使用
+	Authorize(user *model.User, id string, grant string) (*model.Comment, error)
 }
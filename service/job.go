@@ -0,0 +1,9 @@
+package service
+
+import "context"
+
+// Job 后台任务, 随服务启动, ctx 取消时退出
+type Job interface {
+	Name() string
+	Run(ctx context.Context)
+}
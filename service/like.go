@@ -8,30 +8,36 @@ import (
 
 type likeService struct {
 	likeRepository repository.LikeRepository
-	//postRepository repository.PostRepository
+	postService    PostService
 }
 
-func NewLikeService(likeRepository repository.LikeRepository) LikeService {
+func NewLikeService(likeRepository repository.LikeRepository, postService PostService) LikeService {
 	return &likeService{
 		likeRepository: likeRepository,
+		postService:    postService,
 	}
 }
 
-func (l *likeService) LikePost(user *model.User, id string) error {
+// LikePost 点赞与添加 like 表情相同, 只能点赞自己可以看到的文章
+func (l *likeService) LikePost(user *model.User, id string, grant string) error {
 	pid, err := strconv.Atoi(id)
 	if err != nil {
 		return err
 	}
-
+	if _, err := l.postService.Authorize(user, uint(pid), grant); err != nil {
+		return err
+	}
 	return l.likeRepository.LikePost(uint(pid), user.ID)
 }
 
-func (l *likeService) UnLikePost(user *model.User, id string) error {
+func (l *likeService) UnLikePost(user *model.User, id string, grant string) error {
 	pid, err := strconv.Atoi(id)
 	if err != nil {
 		return err
 	}
-
+	if _, err := l.postService.Authorize(user, uint(pid), grant); err != nil {
+		return err
+	}
 	return l.likeRepository.UnLikePost(uint(pid), user.ID)
 }
 
@@ -54,5 +60,5 @@ func (l *likeService) IsLiked(user *model.User, id string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return l.likeRepository.IsLiked(user.ID, uint(pid))
+	return l.likeRepository.IsLiked(uint(pid), user.ID)
 }
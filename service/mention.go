@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+)
+
+const (
+	// 同一内容中最多 @ 的用户数, 超出的部分忽略
+	maxMentions = 20
+	// 通知中内容摘要的长度
+	mentionExcerptLength = 100
+)
+
+// MentionNotice 通知被 @ 的用户时使用的摘要, 以及判断用户能否看到该内容
+type MentionNotice struct {
+	Excerpt string
+	// CanView 为 false 的用户暂不通知, 内容对其可见后再次同步时才会通知
+	CanView func(userID uint) bool
+}
+
+// MentionService 解析文章和评论中的 @用户名, 并通知被 @ 的用户
+type MentionService interface {
+	// Sync 解析 text 中 @ 到的用户并保存, 忽略不存在的用户、作者自己和拉黑了作者的用户.
+	// notice 不为 nil 时通知尚未通知过的用户, 草稿、待审核等内容传入 nil, 公开后再次同步时才会通知
+	Sync(target model.MentionTarget, targetID, postID, authorID uint, text string, notice *MentionNotice) ([]model.Mention, error)
+	// ListByTargets 批量获取多篇内容中 @ 到的用户
+	ListByTargets(target model.MentionTarget, targetIDs []uint) (map[uint][]model.Mention, error)
+}
+
+type mentionService struct {
+	mentionRepository   repository.MentionRepository
+	userRepository      repository.UserRepository
+	blockRepository     repository.BlockRepository
+	notificationService NotificationService
+}
+
+func NewMentionService(mentionRepository repository.MentionRepository, userRepository repository.UserRepository,
+	blockRepository repository.BlockRepository, notificationService NotificationService) MentionService {
+	return &mentionService{
+		mentionRepository:   mentionRepository,
+		userRepository:      userRepository,
+		blockRepository:     blockRepository,
+		notificationService: notificationService,
+	}
+}
+
+func (m *mentionService) Sync(target model.MentionTarget, targetID, postID, authorID uint, text string, notice *MentionNotice) ([]model.Mention, error) {
+	mentions, err := m.resolve(authorID, utils.ParseMentions(text))
+	if err != nil {
+		return nil, err
+	}
+	mentions, err = m.mentionRepository.Replace(target, targetID, mentions)
+	if err != nil {
+		return nil, err
+	}
+	if notice == nil {
+		return mentions, nil
+	}
+
+	excerpt := utils.Excerpt(notice.Excerpt, mentionExcerptLength)
+	notifications := make([]model.Notification, 0)
+	ids := make([]uint, 0)
+	for _, mention := range mentions {
+		if mention.Notified || (notice.CanView != nil && !notice.CanView(mention.UserID)) {
+			continue
+		}
+		notifications = append(notifications, model.Notification{
+			UserID:     mention.UserID,
+			ActorID:    authorID,
+			Type:       model.NotificationMention,
+			TargetType: string(target),
+			TargetID:   targetID,
+			PostID:     postID,
+			Excerpt:    excerpt,
+		})
+		ids = append(ids, mention.ID)
+	}
+	if err := m.notificationService.Notify(notifications...); err != nil {
+		return nil, err
+	}
+	if err := m.mentionRepository.MarkNotified(ids); err != nil {
+		return nil, err
+	}
+	return mentions, nil
+}
+
+// resolve 按用户名查找被 @ 的用户, 去掉作者自己和拉黑了作者的用户
+func (m *mentionService) resolve(authorID uint, names []string) ([]model.Mention, error) {
+	if len(names) > maxMentions {
+		names = names[:maxMentions]
+	}
+	mentions := make([]model.Mention, 0, len(names))
+	userIDs := make([]uint, 0, len(names))
+	for _, name := range names {
+		user, err := m.userRepository.FindByUserName(name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.ID == authorID {
+			continue
+		}
+		mentions = append(mentions, model.Mention{UserID: user.ID, UserName: user.UserName, AuthorID: authorID})
+		userIDs = append(userIDs, user.ID)
+	}
+	blockers, err := m.blockRepository.ListBlockers(authorID, userIDs)
+	if err != nil || len(blockers) == 0 {
+		return mentions, err
+	}
+	blocked := make(map[uint]bool, len(blockers))
+	for _, id := range blockers {
+		blocked[id] = true
+	}
+	allowed := mentions[:0]
+	for _, mention := range mentions {
+		if !blocked[mention.UserID] {
+			allowed = append(allowed, mention)
+		}
+	}
+	return allowed, nil
+}
+
+func (m *mentionService) ListByTargets(target model.MentionTarget, targetIDs []uint) (map[uint][]model.Mention, error) {
+	return m.mentionRepository.ListByTargets(target, targetIDs)
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/moderation"
+	"inkgo/repository"
+	"inkgo/utils"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultModerationInterval = 30
+	// 审核队列中保存的内容摘要和敏感词的长度
+	moderationExcerptLength = 200
+	moderationWordsLength   = 500
+)
+
+var (
+	ErrContentBlocked      = errors.New("内容包含敏感词, 无法保存")
+	ErrModerationForbidden = errors.New("只有管理员可以审核内容")
+	ErrReviewResolved      = errors.New("该内容已被审核")
+)
+
+// ModerationService 敏感词过滤和人工审核, 同时作为后台任务在敏感词文件修改后自动重新加载
+type ModerationService interface {
+	Job
+	// Check 检查文本, 处理方式为 mask 的词会被直接替换; 命中 block 的词时返回 ErrContentBlocked.
+	// 返回结果的 Action 为 review 时, 调用方应将内容加入审核队列
+	Check(texts ...*string) (*moderation.Result, error)
+	// Hold 将内容加入审核队列
+	Hold(target model.ModerationTarget, targetID, authorID uint, excerpt string, result *moderation.Result) error
+	// Release 内容修改后不再命中需要审核的词, 移出审核队列
+	Release(target model.ModerationTarget, targetID uint) error
+	// Reload 重新加载敏感词列表, 返回词的数量
+	Reload() (int, error)
+	// ListReviews 管理员查看审核队列
+	ListReviews(user *model.User, status string, page, pageSize int) ([]model.ModerationReview, int64, error)
+	// Approve 审核通过, 发布内容
+	Approve(user *model.User, id string) (*model.ModerationReview, error)
+	// Reject 审核不通过, 文章退回草稿, 评论不再显示
+	Reject(user *model.User, id string, reason string) (*model.ModerationReview, error)
+	// OnApprove 注册内容通过审核后执行的操作, 例如发送通知、推送到时间线
+	OnApprove(target model.ModerationTarget, hook func(targetID uint))
+	// Approved 执行内容通过审核后的操作, 审核之外的途径放行内容(例如标记为非垃圾评论)时调用
+	Approved(target model.ModerationTarget, targetID uint)
+}
+
+type moderationService struct {
+	conf                 config.ModerationConfig
+	defaultAction        moderation.Action
+	matcher              atomic.Pointer[moderation.Matcher]
+	mu                   sync.Mutex
+	modTime              time.Time // 已加载的敏感词文件的修改时间
+	moderationRepository repository.ModerationRepository
+	relatedService       RelatedService
+	hooks                map[model.ModerationTarget][]func(targetID uint)
+}
+
+func NewModerationService(conf config.ModerationConfig, moderationRepository repository.ModerationRepository,
+	relatedService RelatedService) ModerationService {
+	if conf.Interval <= 0 {
+		conf.Interval = defaultModerationInterval
+	}
+	action, err := moderation.ParseAction(conf.DefaultAction)
+	if err != nil {
+		action = moderation.ActionReview
+	}
+	m := &moderationService{
+		conf:                 conf,
+		defaultAction:        action,
+		moderationRepository: moderationRepository,
+		relatedService:       relatedService,
+		hooks:                make(map[model.ModerationTarget][]func(targetID uint)),
+	}
+	m.matcher.Store(moderation.NewMatcher(nil))
+	if conf.WordsFile != "" {
+		if _, err := m.Reload(); err != nil {
+			zap.S().Warnf("failed to load sensitive words, %v", err)
+		}
+	}
+	return m
+}
+
+func (m *moderationService) Check(texts ...*string) (*moderation.Result, error) {
+	matcher := m.matcher.Load()
+	result := &moderation.Result{Matches: make([]moderation.Match, 0)}
+	for _, text := range texts {
+		r := matcher.Match(*text)
+		masks := make([]moderation.Match, 0, len(r.Matches))
+		for _, match := range r.Matches {
+			if match.Action == moderation.ActionMask {
+				masks = append(masks, match)
+			}
+		}
+		*text = moderation.Mask(*text, masks)
+		result.Merge(r)
+	}
+	if result.Action == moderation.ActionBlock {
+		blocked := make([]string, 0)
+		for _, match := range result.Matches {
+			if match.Action == moderation.ActionBlock {
+				blocked = append(blocked, match.Word)
+			}
+		}
+		return result, fmt.Errorf("%w: %s", ErrContentBlocked, strings.Join(blocked, ", "))
+	}
+	return result, nil
+}
+
+func (m *moderationService) Hold(target model.ModerationTarget, targetID, authorID uint, excerpt string, result *moderation.Result) error {
+	if runes := []rune(excerpt); len(runes) > moderationExcerptLength {
+		excerpt = string(runes[:moderationExcerptLength])
+	}
+	words := strings.Join(result.Words(), ",")
+	if runes := []rune(words); len(runes) > moderationWordsLength {
+		words = string(runes[:moderationWordsLength])
+	}
+	_, err := m.moderationRepository.Submit(&model.ModerationReview{
+		TargetType: target,
+		TargetID:   targetID,
+		AuthorID:   authorID,
+		Excerpt:    excerpt,
+		Words:      words,
+	})
+	return err
+}
+
+func (m *moderationService) Release(target model.ModerationTarget, targetID uint) error {
+	return m.moderationRepository.Cancel(target, targetID)
+}
+
+func (m *moderationService) Reload() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info, err := os.Stat(m.conf.WordsFile)
+	if err != nil {
+		return 0, err
+	}
+	rules, err := moderation.LoadRules(m.conf.WordsFile, m.defaultAction)
+	if err != nil {
+		return 0, err
+	}
+	matcher := moderation.NewMatcher(rules)
+	m.matcher.Store(matcher)
+	m.modTime = info.ModTime()
+	zap.S().Infof("loaded %d sensitive words from %s", matcher.Len(), m.conf.WordsFile)
+	return matcher.Len(), nil
+}
+
+func (m *moderationService) Name() string {
+	return "moderation"
+}
+
+// Run 定期检查敏感词文件, 修改后重新加载
+func (m *moderationService) Run(ctx context.Context) {
+	if m.conf.WordsFile == "" {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(m.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !m.modified() {
+				continue
+			}
+			if _, err := m.Reload(); err != nil {
+				zap.S().Warnf("failed to reload sensitive words, %v", err)
+			}
+		}
+	}
+}
+
+// modified 敏感词文件是否在上次加载后被修改
+func (m *moderationService) modified() bool {
+	info, err := os.Stat(m.conf.WordsFile)
+	if err != nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !info.ModTime().Equal(m.modTime)
+}
+
+func (m *moderationService) ListReviews(user *model.User, status string, page, pageSize int) ([]model.ModerationReview, int64, error) {
+	if !utils.IsAdmin(user) {
+		return nil, 0, ErrModerationForbidden
+	}
+	return m.moderationRepository.List(model.ModerationStatus(status), page, pageSize)
+}
+
+func (m *moderationService) Approve(user *model.User, id string) (*model.ModerationReview, error) {
+	review, err := m.pendingReview(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.moderationRepository.Approve(review, user.ID); err != nil {
+		return nil, resolvedError(err)
+	}
+	if review.TargetType == model.ModerationPost {
+		m.relatedService.Refresh(review.TargetID)
+	}
+	m.Approved(review.TargetType, review.TargetID)
+	return review, nil
+}
+
+// OnApprove 只在启动时由各个服务注册, 不需要加锁
+func (m *moderationService) OnApprove(target model.ModerationTarget, hook func(targetID uint)) {
+	m.hooks[target] = append(m.hooks[target], hook)
+}
+
+func (m *moderationService) Approved(target model.ModerationTarget, targetID uint) {
+	for _, hook := range m.hooks[target] {
+		hook(targetID)
+	}
+}
+
+func (m *moderationService) Reject(user *model.User, id string, reason string) (*model.ModerationReview, error) {
+	review, err := m.pendingReview(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.moderationRepository.Reject(review, user.ID, reason); err != nil {
+		return nil, resolvedError(err)
+	}
+	return review, nil
+}
+
+func (m *moderationService) pendingReview(user *model.User, id string) (*model.ModerationReview, error) {
+	if !utils.IsAdmin(user) {
+		return nil, ErrModerationForbidden
+	}
+	rid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	review, err := m.moderationRepository.Get(uint(rid))
+	if err != nil {
+		return nil, err
+	}
+	if review.Status != model.ModerationPending {
+		return nil, ErrReviewResolved
+	}
+	return review, nil
+}
+
+// resolvedError 其他管理员已先审核时, 仓库层返回 gorm.ErrRecordNotFound
+func resolvedError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrReviewResolved
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"inkgo/model"
+	"inkgo/repository"
+)
+
+// NotificationService 站内通知
+type NotificationService interface {
+	// Notify 发送通知, 不会通知用户自己触发的事件
+	Notify(notifications ...model.Notification) error
+	// List 分页获取通知, unread 为 true 时只返回未读通知
+	List(user *model.User, unread bool, page, pageSize int) ([]model.Notification, int64, error)
+	// CountUnread 统计未读通知数
+	CountUnread(user *model.User) (int64, error)
+	// MarkRead 标记为已读, ids 为空时标记全部
+	MarkRead(user *model.User, ids []uint) error
+}
+
+type notificationService struct {
+	notificationRepository repository.NotificationRepository
+}
+
+func NewNotificationService(notificationRepository repository.NotificationRepository) NotificationService {
+	return &notificationService{
+		notificationRepository: notificationRepository,
+	}
+}
+
+func (n *notificationService) Notify(notifications ...model.Notification) error {
+	filtered := make([]model.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		if notification.UserID != 0 && notification.UserID != notification.ActorID {
+			filtered = append(filtered, notification)
+		}
+	}
+	return n.notificationRepository.Create(filtered)
+}
+
+func (n *notificationService) List(user *model.User, unread bool, page, pageSize int) ([]model.Notification, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 10
+	}
+	return n.notificationRepository.List(user.ID, unread, page, pageSize)
+}
+
+func (n *notificationService) CountUnread(user *model.User) (int64, error) {
+	return n.notificationRepository.CountUnread(user.ID)
+}
+
+func (n *notificationService) MarkRead(user *model.User, ids []uint) error {
+	return n.notificationRepository.MarkRead(user.ID, ids)
+}
@@ -1,47 +1,359 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"inkgo/model"
+	"inkgo/moderation"
 	"inkgo/repository"
+	"inkgo/utils"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 加密文章解锁后的访问授权有效期
+	postAccessGrantExpiration = 30 * time.Minute
+	// 文章详情中返回的相关文章数量
+	defaultPostRelatedLimit = 5
+	// 一次批量操作最多处理的文章数
+	bulkMaxPosts = 5000
+)
+
+var (
+	ErrPostForbidden = errors.New("无权查看该文章")
+	ErrPostLocked    = errors.New("该文章已加密, 请输入密码后查看")
+	ErrPostPassword  = errors.New("文章密码错误")
+	ErrPostConflict  = errors.New("文章已被他人修改, 请合并后重试")
+	ErrPostNotOwner  = errors.New("只能操作自己的文章")
+	ErrBulkRequest   = errors.New("无效的批量操作")
 )
 
 type postService struct {
-	postRepository repository.PostRepository
-	likeRepository repository.LikeRepository
+	postRepository    repository.PostRepository
+	likeRepository    repository.LikeRepository
+	rankRepository    repository.RankRepository
+	followRepository  repository.FollowRepository
+	tokenRepository   repository.TokenRepository
+	relatedService    RelatedService
+	moderationService ModerationService
+	mentionService    MentionService
+	timelineService   TimelineService
 }
 
-func NewPostService(postRepository repository.PostRepository) PostService {
-	return &postService{
-		postRepository: postRepository,
+func NewPostService(postRepository repository.PostRepository, likeRepository repository.LikeRepository, rankRepository repository.RankRepository,
+	followRepository repository.FollowRepository, tokenRepository repository.TokenRepository,
+	relatedService RelatedService, moderationService ModerationService, mentionService MentionService, timelineService TimelineService) PostService {
+	p := &postService{
+		postRepository:    postRepository,
+		likeRepository:    likeRepository,
+		rankRepository:    rankRepository,
+		followRepository:  followRepository,
+		tokenRepository:   tokenRepository,
+		relatedService:    relatedService,
+		moderationService: moderationService,
+		mentionService:    mentionService,
+		timelineService:   timelineService,
 	}
+	moderationService.OnApprove(model.ModerationPost, p.afterApprove)
+	return p
 }
 
-func (p *postService) GetPostByID(id string) (*model.Post, error) {
+// afterApprove 待审核的文章通过审核后通知被 @ 的用户并推送到粉丝的时间线
+func (p *postService) afterApprove(id uint) {
+	post, err := p.postRepository.FindByID(id)
+	if err != nil {
+		zap.S().Warnf("failed to load approved post %d, %v", id, err)
+		return
+	}
+	if post.State != model.PostPublished {
+		return
+	}
+	p.afterPublish(post)
+}
+
+// GetPostByID 获取文章详情, grant 为解锁加密文章后得到的访问授权
+func (p *postService) GetPostByID(user *model.User, id string, grant string) (*model.Post, error) {
 	aid, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, err
 	}
-	post, err := p.postRepository.GetPostByID(uint(aid))
+	// 先校验可见范围, 无权访问时不增加阅读量
+	post, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkAccess(user, post, grant); err != nil {
+		return nil, err
+	}
+
+	post, err = p.postRepository.GetPostByID(uint(aid))
 	if err != nil {
 		return nil, err
 	}
+	hideUnauthorizedContent(post)
+	if post.UserLiked, err = p.likeRepository.IsLiked(post.ID, user.ID); err != nil {
+		return nil, err
+	}
+	mentions, err := p.mentionService.ListByTargets(model.MentionPost, []uint{post.ID})
+	if err != nil {
+		return nil, err
+	}
+	post.Mentions = mentions[post.ID]
+	// 相关文章获取失败不影响文章详情
+	if post.Related, err = p.listRelated(user, post.ID, defaultPostRelatedLimit); err != nil {
+		zap.S().Debugf("failed to list related posts of %d: %v", post.ID, err)
+	}
 	return post, nil
 }
 
-func (p *postService) GetPostByName(name string) (*model.Post, error) {
-	post, err := p.postRepository.GetPostByName(name)
+func (p *postService) GetSharedPost(id string) (*model.Post, error) {
+	aid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := p.postRepository.FindDetailByID(uint(aid))
+	if err != nil {
+		return nil, err
+	}
+	// 匿名用户只能看到所有人可见的文章
+	if err := p.checkAccess(&model.User{}, post, ""); err != nil {
+		return nil, err
+	}
+	if post.State != model.PostPublished {
+		return nil, ErrPostForbidden
+	}
+	if err := p.postRepository.IncView(post.ID); err != nil {
+		return nil, err
+	}
+	hideUnauthorizedContent(post)
+	// 不返回作者的邮箱和手机号
+	post.Author = model.User{Model: gorm.Model{ID: post.Author.ID}, UserName: post.Author.UserName, Avatar: post.Author.Avatar}
+	return post, nil
+}
+
+// ListRelated 获取文章的相关文章, 访问权限与查看文章详情相同
+func (p *postService) ListRelated(user *model.User, id string, grant string, limit int) ([]model.Post, error) {
+	aid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkAccess(user, post, grant); err != nil {
+		return nil, err
+	}
+	return p.listRelated(user, post.ID, limit)
+}
+
+// Authorize 校验 user 是否可以查看文章, 供评论等依附于文章的功能使用
+func (p *postService) Authorize(user *model.User, id uint, grant string) (*model.Post, error) {
+	post, err := p.postRepository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkAccess(user, post, grant); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (p *postService) listRelated(user *model.User, id uint, limit int) ([]model.Post, error) {
+	if limit <= 0 {
+		limit = defaultPostRelatedLimit
+	}
+	ids, err := p.relatedService.RelatedIDs(id, limit)
+	if err != nil || len(ids) == 0 {
+		return []model.Post{}, err
+	}
+	posts, err := p.postRepository.ListByIDs(user.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		hideUnauthorizedContent(&posts[i])
+	}
+	return posts, nil
+}
+
+// moderate 检查文章的标题和正文, 要发布的文章命中需要审核的词时改为待审核
+func (p *postService) moderate(post *model.Post) (*moderation.Result, error) {
+	result, err := p.moderationService.Check(&post.Title, &post.Content)
+	if err != nil {
+		return nil, err
+	}
+	// 待审核状态只能由审核流程设置
+	if post.State == model.PostPending {
+		post.State = model.PostPublished
+	}
+	if post.State == model.PostPublished && result.Action == moderation.ActionReview {
+		post.State = model.PostPending
+	}
+	return result, nil
+}
+
+// submitReview 待审核的文章加入审核队列, 其它文章移出审核队列. 失败时只记录日志, 不影响文章的保存
+func (p *postService) submitReview(post *model.Post, result *moderation.Result) {
+	var err error
+	if post.State == model.PostPending && result != nil {
+		err = p.moderationService.Hold(model.ModerationPost, post.ID, post.AuthorID, post.Title+"\n"+post.Content, result)
+	} else {
+		err = p.moderationService.Release(model.ModerationPost, post.ID)
+	}
+	if err != nil {
+		zap.S().Warnf("failed to submit moderation review of post %d, %v", post.ID, err)
+	}
+}
+
+// refreshRelated 文章变更后重新计算相关文章, 未发布或不公开的文章会从推荐中移除
+func (p *postService) refreshRelated(post *model.Post) {
+	if post != nil && post.ID != 0 {
+		p.relatedService.Refresh(post.ID)
+	}
+}
+
+// syncMentions 保存文章中 @ 到的用户, 文章发布后只通知能看到文章的用户, 加密文章的通知只包含标题.
+// 失败时只记录日志, 不影响文章的保存
+func (p *postService) syncMentions(post *model.Post) {
+	var notice *MentionNotice
+	if post.State == model.PostPublished && post.Visibility != model.VisibilityPrivate {
+		notice = &MentionNotice{Excerpt: post.Title + "\n" + post.Content, CanView: func(userID uint) bool {
+			err := p.checkAccess(&model.User{Model: gorm.Model{ID: userID}}, post, "")
+			return err == nil || errors.Is(err, ErrPostLocked)
+		}}
+		if post.Visibility == model.VisibilityPassword {
+			notice.Excerpt = post.Title
+		}
+	}
+	mentions, err := p.mentionService.Sync(model.MentionPost, post.ID, post.ID, post.AuthorID, post.Title+"\n"+post.Content, notice)
+	if err != nil {
+		zap.S().Warnf("failed to save mentions of post %d, %v", post.ID, err)
+		return
+	}
+	post.Mentions = mentions
+}
+
+// afterPublish 文章保存或状态变更后同步 @ 提及, 已发布的文章推送到粉丝的时间线
+func (p *postService) afterPublish(post *model.Post) {
+	p.syncMentions(post)
+	p.timelineService.Publish(post)
+}
+
+func (p *postService) GetPostByName(user *model.User, name string) (*model.Post, error) {
+	post, err := p.postRepository.GetPostByName(user.ID, name)
 	if err != nil {
 		return nil, err
 	}
+	// 加密文章可以被搜索到, 但不返回正文
+	if err := p.checkAccess(user, post, ""); err != nil {
+		if !errors.Is(err, ErrPostLocked) {
+			return nil, err
+		}
+		post.Content = ""
+	}
+	hideUnauthorizedContent(post)
 	return post, nil
 }
 
-func (p *postService) HasPublished(page, pageSize int) ([]model.Post, int64, error) {
-	posts, total, err := p.postRepository.ListHasPublished(page, pageSize)
+// Unlock 校验加密文章的密码, 通过后发放一个短期有效的访问授权
+func (p *postService) Unlock(user *model.User, id string, password string) (string, time.Time, error) {
+	aid, err := strconv.Atoi(id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	post, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if post.Visibility != model.VisibilityPassword {
+		return "", time.Time{}, errors.New("该文章未加密")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(post.PasswordHash), []byte(password)); err != nil {
+		return "", time.Time{}, ErrPostPassword
+	}
+
+	grant, err := utils.RandomString(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// 授权和文章、用户绑定, 防止转给他人或用于其它文章
+	if err := p.tokenRepository.SetToken(postAccessGrantKey(grant), grantValue(post.ID, user.ID), postAccessGrantExpiration); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to save post access grant: %w", err)
+	}
+	return grant, time.Now().Add(postAccessGrantExpiration), nil
+}
+
+// checkAccess 校验 user 是否可以查看文章详情
+func (p *postService) checkAccess(user *model.User, post *model.Post, grant string) error {
+	if post.AuthorID == user.ID || utils.IsAdmin(user) {
+		return nil
+	}
+	// 草稿和待审核的文章只有作者和管理员可以查看
+	if post.State == model.PostDraft || post.State == model.PostPending {
+		return ErrPostForbidden
+	}
+	switch post.Visibility {
+	case model.VisibilityPrivate:
+		return ErrPostForbidden
+	case model.VisibilityFollowers:
+		following, err := p.followRepository.IsFollowing(user.ID, post.AuthorID)
+		if err != nil {
+			return err
+		}
+		if !following {
+			return ErrPostForbidden
+		}
+	case model.VisibilityPassword:
+		if grant == "" {
+			return ErrPostLocked
+		}
+		value, err := p.tokenRepository.GetToken(postAccessGrantKey(grant))
+		if err != nil || value != grantValue(post.ID, user.ID) {
+			return ErrPostLocked
+		}
+	}
+	return nil
+}
+
+func postAccessGrantKey(grant string) string {
+	return "post:grant:" + grant
+}
+
+func grantValue(postID, userID uint) string {
+	return fmt.Sprintf("%d:%d", postID, userID)
+}
+
+// preparePassword 设置为加密文章时, 将明文密码转换为 bcrypt 哈希, 可见范围为空时由调用方决定默认值
+func preparePassword(post *model.Post) error {
+	if post.Visibility != "" && !post.Visibility.Valid() {
+		return fmt.Errorf("无效的可见范围: %s", post.Visibility)
+	}
+	if post.Password = strings.TrimSpace(post.Password); post.Password != "" {
+		hashed, err := utils.HashPassword(post.Password)
+		if err != nil {
+			return err
+		}
+		post.PasswordHash = hashed
+		post.Password = ""
+	}
+	return nil
+}
+
+func (p *postService) HasPublished(user *model.User, page, pageSize int) ([]model.Post, int64, error) {
+	posts, total, err := p.postRepository.ListHasPublished(user.ID, page, pageSize)
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := p.fillUserLiked(user, posts); err != nil {
+		return nil, 0, err
+	}
 	return posts, total, nil
 
 }
@@ -54,36 +366,117 @@ func (p *postService) ListDrafts(page, pageSize int) ([]model.Post, int64, error
 	return posts, total, nil
 }
 
+// Query 按条件列出文章. 未指定状态时只列出已发布的文章, 其它状态只有作者本人和管理员可以查询
+func (p *postService) Query(user *model.User, query *model.PostQuery, page, pageSize int) ([]model.Post, int64, error) {
+	if query.State == "" {
+		query.State = model.PostPublished
+	}
+	if query.State != model.PostPublished && !utils.IsAdmin(user) {
+		if query.AuthorID == 0 {
+			query.AuthorID = user.ID
+		}
+		if query.AuthorID != user.ID {
+			return nil, 0, ErrPostForbidden
+		}
+	}
+	posts, total, err := p.postRepository.Query(user.ID, query, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := p.fillUserLiked(user, posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+func (p *postService) HasPublishedByCursor(user *model.User, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	posts, next, err := p.postRepository.ListHasPublishedByCursor(user.ID, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.fillUserLiked(user, posts); err != nil {
+		return nil, nil, err
+	}
+	return posts, next, nil
+}
+
+// fillUserLiked 批量设置当前用户是否点赞过列表中的文章
+func (p *postService) fillUserLiked(user *model.User, posts []model.Post) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	liked, err := p.likeRepository.ListLiked(user.ID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].UserLiked = liked[posts[i].ID]
+	}
+	return nil
+}
+
+func (p *postService) ListDraftsByCursor(cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	return p.postRepository.ListDraftsByCursor(cursor, limit)
+}
+
 func (p *postService) UpdateStatus(id string, state model.PostState) (*model.Post, error) {
 	aid, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, err
 	}
-	post, err := p.postRepository.UpdateStatus(uint(aid), state)
-	if err != nil {
+	var result *moderation.Result
+	var post *model.Post
+	if state == model.PostPublished || state == model.PostPending {
+		// 发布前重新检查, 敏感词列表可能在保存之后更新过, 脱敏后的标题和正文与状态一起保存
+		existing, err := p.postRepository.FindByID(uint(aid))
+		if err != nil {
+			return nil, err
+		}
+		existing.State = model.PostPublished
+		if result, err = p.moderate(existing); err != nil {
+			return nil, err
+		}
+		post, err = p.postRepository.UpdateModerated(existing.ID, existing.Title, existing.Content, existing.State)
+		if err != nil {
+			return nil, err
+		}
+	} else if post, err = p.postRepository.UpdateStatus(uint(aid), state); err != nil {
 		return nil, err
 	}
+	p.submitReview(post, result)
+	p.refreshRelated(post)
+	p.afterPublish(post)
 	return post, nil
 }
 
-func (p *postService) SortByViewCountDesc(page int, pageSize int) ([]model.Post, int64, error) {
-	posts, total, err := p.postRepository.SortByViewCountDesc(page, pageSize)
+func (p *postService) SortByViewCountDesc(user *model.User, page int, pageSize int) ([]model.Post, int64, error) {
+	posts, total, err := p.postRepository.SortByViewCountDesc(user.ID, page, pageSize)
 	if err != nil {
 		return nil, 0, err
 	}
 	return posts, total, nil
 }
 
-func (p *postService) ListHotPosts(limit int) ([]model.Post, error) {
-	posts, err := p.postRepository.ListHotPosts(limit)
+// ListHotPosts 优先从 Redis 热榜读取, 热榜不可用或尚未生成时回退到按阅读量排序
+func (p *postService) ListHotPosts(user *model.User, limit int) ([]model.Post, error) {
+	ids, err := p.rankRepository.ListHotPostIDs(context.Background(), limit)
+	if err != nil {
+		zap.S().Debugf("hot posts rank unavailable, fallback to database: %v", err)
+	}
+	if len(ids) > 0 {
+		return p.postRepository.ListByIDs(user.ID, ids)
+	}
+
+	posts, err := p.postRepository.ListHotPosts(user.ID, limit)
 	if err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
-func (p *postService) ListRecentPosts(limit int) ([]model.Post, error) {
-	posts, err := p.postRepository.ListRecentPosts(limit)
+func (p *postService) ListRecentPosts(user *model.User, limit int) ([]model.Post, error) {
+	posts, err := p.postRepository.ListRecentPosts(user.ID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +484,28 @@ func (p *postService) ListRecentPosts(limit int) ([]model.Post, error) {
 }
 
 func (p *postService) Create(user *model.User, Post *model.Post) (*model.Post, error) {
-	return p.postRepository.Create(user, Post)
+	if Post.Visibility == "" {
+		Post.Visibility = model.VisibilityPublic
+	}
+	if err := preparePassword(Post); err != nil {
+		return nil, err
+	}
+	if Post.Visibility == model.VisibilityPassword && Post.PasswordHash == "" {
+		return nil, errors.New("加密文章必须设置密码")
+	}
+	result, err := p.moderate(Post)
+	if err != nil {
+		return nil, err
+	}
+	Post.Version = 1
+	post, err := p.postRepository.Create(user, Post)
+	if err != nil {
+		return nil, err
+	}
+	p.submitReview(post, result)
+	p.refreshRelated(post)
+	p.afterPublish(post)
+	return post, nil
 }
 
 func (p *postService) Get(user *model.User, id string) (*model.Post, error) {
@@ -99,25 +513,116 @@ func (p *postService) Get(user *model.User, id string) (*model.Post, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := p.postRepository.IncView(uint(aid)); err != nil {
+	// 先校验可见范围, 无权访问时不增加阅读量
+	Post, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
 		return nil, err
 	}
-	Post, err := p.postRepository.GetPostByID(uint(aid))
-	if err != nil {
+	if err := p.checkAccess(user, Post, ""); err != nil {
 		return nil, err
 	}
-	Post.UserLiked, _ = p.likeRepository.IsLiked(uint(aid), user.ID)
+	// GetPostByID 会增加阅读量
+	if Post, err = p.postRepository.GetPostByID(uint(aid)); err != nil {
+		return nil, err
+	}
+	if Post.UserLiked, err = p.likeRepository.IsLiked(Post.ID, user.ID); err != nil {
+		return nil, err
+	}
+	hideUnauthorizedContent(Post)
 
 	return Post, nil
 }
 
-func (p *postService) Update(id string, Post *model.Post) (*model.Post, error) {
+// Update 作者或管理员更新文章, version 与最新版本不一致时返回 ErrPostConflict 和服务端最新的文章.
+// 先校验权限, 冲突时返回的文章只会给有权编辑它的用户
+func (p *postService) Update(user *model.User, id string, Post *model.Post, version uint) (*model.Post, error) {
 	aid, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, err
 	}
-	Post.ID = uint(aid)
-	return p.postRepository.Update(Post)
+	existing, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
+		return nil, err
+	}
+	if existing.AuthorID != user.ID && !utils.IsAdmin(user) {
+		return nil, ErrPostNotOwner
+	}
+	Post.ID = existing.ID
+	// 未传可见范围时保持原值
+	if Post.Visibility == "" {
+		Post.Visibility = existing.Visibility
+	}
+	if err := preparePassword(Post); err != nil {
+		return nil, err
+	}
+	if Post.Visibility == model.VisibilityPassword && Post.PasswordHash == "" {
+		if existing.PasswordHash == "" {
+			return nil, errors.New("加密文章必须设置密码")
+		}
+	}
+	result, err := p.moderationService.Check(&Post.Title, &Post.Content)
+	if err != nil {
+		return nil, err
+	}
+	post, err := conflictError(p.postRepository.Update(Post, version))
+	if err != nil {
+		return post, err
+	}
+	// Update 不修改状态, 已发布的文章命中需要审核的词时撤回, 待审核的文章不再命中时直接发布
+	state := post.State
+	if state == model.PostPublished && result.Action == moderation.ActionReview {
+		state = model.PostPending
+	} else if state == model.PostPending && result.Action != moderation.ActionReview {
+		state = model.PostPublished
+	}
+	if state != post.State {
+		updated, err := p.postRepository.UpdateStatus(post.ID, state)
+		if err != nil {
+			return nil, err
+		}
+		post.State, post.Version = updated.State, updated.Version
+	}
+	p.submitReview(post, result)
+	p.refreshRelated(post)
+	p.afterPublish(post)
+	return post, nil
+}
+
+// Autosave 自动保存作者自己的草稿, 与 Update 遵循相同的版本规则
+func (p *postService) Autosave(user *model.User, id string, Post *model.Post, version uint) (*model.Post, error) {
+	aid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := p.postRepository.FindByID(uint(aid))
+	if err != nil {
+		return nil, err
+	}
+	if existing.AuthorID != user.ID {
+		return nil, ErrPostForbidden
+	}
+	if existing.State != model.PostDraft {
+		return nil, errors.New("只有草稿可以自动保存")
+	}
+	Post.ID = existing.ID
+	// 草稿不会进入审核队列, 这里只拦截禁用词并对需要脱敏的词打码
+	if _, err := p.moderationService.Check(&Post.Title, &Post.Content); err != nil {
+		return nil, err
+	}
+	post, err := conflictError(p.postRepository.Autosave(Post, version))
+	if err != nil {
+		return post, err
+	}
+	p.syncMentions(post)
+	return post, nil
+}
+
+// conflictError 将仓库层的版本冲突转换为 ErrPostConflict, 同时保留最新的文章
+func conflictError(post *model.Post, err error) (*model.Post, error) {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return post, ErrPostConflict
+	}
+	return post, err
 }
 
 func (p *postService) Delete(id string) error {
@@ -125,5 +630,186 @@ func (p *postService) Delete(id string) error {
 	if err != nil {
 		return err
 	}
-	return p.postRepository.Delete(uint(aid))
+	if err := p.postRepository.Delete(uint(aid)); err != nil {
+		return err
+	}
+	if err := p.moderationService.Release(model.ModerationPost, uint(aid)); err != nil {
+		zap.S().Warnf("failed to release moderation review of post %d, %v", aid, err)
+	}
+	p.relatedService.Refresh(uint(aid))
+	return nil
+}
+
+// Bulk 批量操作文章. 普通用户只能操作自己的文章, 按条件选择时只会选中自己的文章; 转移作者只有管理员可以执行.
+// 无权操作或执行失败的文章记录在结果中, 不影响其它文章
+func (p *postService) Bulk(user *model.User, req *model.BulkRequest) (*model.BulkReport, error) {
+	change, err := bulkChange(user, req)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := p.bulkIDs(user, req)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := p.postRepository.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*model.Post, len(posts))
+	for i := range posts {
+		byID[posts[i].ID] = &posts[i]
+	}
+
+	failed := make(map[uint]error)
+	// 发布时每篇文章需要单独检查敏感词, 命中需要审核的词的文章改为待审核, 因此按目标状态分组执行
+	groups := make(map[model.PostState][]uint)
+	results := make(map[uint]*moderation.Result)
+	masked := make(map[uint]*model.Post)
+	for _, id := range ids {
+		post, ok := byID[id]
+		if !ok {
+			failed[id] = errors.New("文章不存在")
+			continue
+		}
+		if post.AuthorID != user.ID && !utils.IsAdmin(user) {
+			failed[id] = ErrPostNotOwner
+			continue
+		}
+		state := change.State
+		if change.Operation == model.BulkSetState && state == model.PostPublished {
+			title, content := post.Title, post.Content
+			post.State = model.PostPublished
+			result, err := p.moderate(post)
+			if err != nil {
+				failed[id] = err
+				continue
+			}
+			state, results[id] = post.State, result
+			if post.Title != title || post.Content != content {
+				masked[id] = post
+			}
+		}
+		groups[state] = append(groups[state], id)
+	}
+	states := make(map[uint]model.PostState)
+	for state, group := range groups {
+		c := change
+		c.State = state
+		c.Masked = masked
+		groupFailed, err := p.postRepository.BulkApply(group, c)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: %v", ErrBulkRequest, err)
+			}
+			return nil, err
+		}
+		for _, id := range group {
+			if err, ok := groupFailed[id]; ok {
+				failed[id] = err
+				continue
+			}
+			states[id] = state
+		}
+	}
+
+	report := &model.BulkReport{Operation: req.Operation, Total: len(ids), Items: make([]model.BulkItemResult, 0, len(ids))}
+	for _, id := range ids {
+		item := model.BulkItemResult{ID: id}
+		if err, ok := failed[id]; ok {
+			item.Error = err.Error()
+			report.Failed++
+		} else {
+			item.Success = true
+			report.Succeeded++
+			p.afterBulk(byID[id], change, states[id], results[id])
+			if change.Operation == model.BulkSetState {
+				item.State = states[id]
+			}
+		}
+		report.Items = append(report.Items, item)
+	}
+	return report, nil
+}
+
+// bulkChange 校验批量操作的参数
+func bulkChange(user *model.User, req *model.BulkRequest) (model.BulkChange, error) {
+	change := model.BulkChange{
+		Operation:   req.Operation,
+		State:       req.State,
+		TagIDs:      req.TagIDs,
+		CategoryIDs: req.CategoryIDs,
+		AuthorID:    req.AuthorID,
+	}
+	switch req.Operation {
+	case model.BulkSetState:
+		// 待审核状态只能由审核流程设置
+		if req.State != model.PostDraft && req.State != model.PostPublished && req.State != model.PostArchived {
+			return change, fmt.Errorf("%w: 不支持的状态 %q", ErrBulkRequest, req.State)
+		}
+	case model.BulkAddTags, model.BulkRemoveTags:
+		if len(req.TagIDs) == 0 {
+			return change, fmt.Errorf("%w: 缺少标签", ErrBulkRequest)
+		}
+	case model.BulkSetCategories:
+		// 分类为空时清空文章的分类
+	case model.BulkTransferAuthor:
+		if !utils.IsAdmin(user) {
+			return change, fmt.Errorf("%w: 只有管理员可以转移作者", ErrPostNotOwner)
+		}
+		if req.AuthorID == 0 {
+			return change, fmt.Errorf("%w: 缺少新作者", ErrBulkRequest)
+		}
+	case model.BulkDelete:
+	default:
+		return change, fmt.Errorf("%w: 不支持的操作 %q", ErrBulkRequest, req.Operation)
+	}
+	return change, nil
+}
+
+// bulkIDs 获取要操作的文章ID, 去掉重复的ID并保持请求中的顺序
+func (p *postService) bulkIDs(user *model.User, req *model.BulkRequest) ([]uint, error) {
+	var ids []uint
+	switch {
+	case len(req.IDs) > 0:
+		seen := make(map[uint]bool, len(req.IDs))
+		for _, id := range req.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	case req.Filter != nil:
+		filter := *req.Filter
+		if !utils.IsAdmin(user) {
+			filter.AuthorID = user.ID
+		}
+		var err error
+		if ids, err = p.postRepository.ListIDsByFilter(filter, bulkMaxPosts+1); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: 需要指定文章ID或筛选条件", ErrBulkRequest)
+	}
+	if len(ids) > bulkMaxPosts {
+		return nil, fmt.Errorf("%w: 一次最多操作 %d 篇文章", ErrBulkRequest, bulkMaxPosts)
+	}
+	return ids, nil
+}
+
+// afterBulk 文章修改成功后更新审核队列和相关文章
+func (p *postService) afterBulk(post *model.Post, change model.BulkChange, state model.PostState, result *moderation.Result) {
+	switch change.Operation {
+	case model.BulkSetState:
+		post.State = state
+		p.submitReview(post, result)
+		p.afterPublish(post)
+	case model.BulkDelete:
+		if err := p.moderationService.Release(model.ModerationPost, post.ID); err != nil {
+			zap.S().Warnf("failed to release moderation review of post %d, %v", post.ID, err)
+		}
+	case model.BulkTransferAuthor:
+		// 作者不影响相关文章
+		return
+	}
+	p.refreshRelated(post)
 }
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	defaultRankGravity  = 1.8
+	defaultRankInterval = 300
+	defaultRankSize     = 100
+)
+
+// hotRankJob 定时重新计算文章的热度得分, 写入 Redis 热榜
+type hotRankJob struct {
+	conf           config.RankingConfig
+	postRepository repository.PostRepository
+	rankRepository repository.RankRepository
+}
+
+func NewHotRankJob(conf config.RankingConfig, postRepository repository.PostRepository, rankRepository repository.RankRepository) Job {
+	if conf.Gravity <= 0 {
+		conf.Gravity = defaultRankGravity
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultRankInterval
+	}
+	if conf.Size <= 0 {
+		conf.Size = defaultRankSize
+	}
+	return &hotRankJob{
+		conf:           conf,
+		postRepository: postRepository,
+		rankRepository: rankRepository,
+	}
+}
+
+func (j *hotRankJob) Name() string {
+	return "hot-rank"
+}
+
+func (j *hotRankJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(j.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := j.Refresh(ctx); err != nil {
+			zap.S().Warnf("failed to refresh hot posts, %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh 计算所有已发布文章的得分, 只保留得分最高的 Size 篇
+func (j *hotRankJob) Refresh(ctx context.Context) error {
+	stats, err := j.postRepository.ListRankStats()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	scores := make(map[uint]float64, len(stats))
+	for _, stat := range stats {
+		scores[stat.ID] = HotScore(j.conf, stat, now)
+	}
+	if len(scores) > j.conf.Size {
+		scores = topScores(scores, j.conf.Size)
+	}
+	return j.rankRepository.ReplaceHotPosts(ctx, scores)
+}
+
+// HotScore 参考 Hacker News 的排序算法: 互动加权和除以 (发布小时数 + 2) 的 Gravity 次方
+func HotScore(conf config.RankingConfig, stat model.PostRankStat, now time.Time) float64 {
+	points := float64(stat.ViewCount)*conf.ViewWeight +
+		float64(stat.LikeCount)*conf.LikeWeight +
+		float64(stat.CommentCount)*conf.CommentWeight +
+		float64(stat.FavoriteCount)*conf.FavoriteWeight
+	hours := now.Sub(stat.CreatedAt).Hours()
+	if hours < 0 {
+		hours = 0
+	}
+	return points / math.Pow(hours+2, conf.Gravity)
+}
+
+// topScores 返回得分最高的 n 项
+func topScores(scores map[uint]float64, n int) map[uint]float64 {
+	type item struct {
+		id    uint
+		score float64
+	}
+	items := make([]item, 0, len(scores))
+	for id, score := range scores {
+		items = append(items, item{id, score})
+	}
+	sort.Slice(items, func(i, k int) bool {
+		return items[i].score > items[k].score
+	})
+	top := make(map[uint]float64, n)
+	for _, it := range items[:n] {
+		top[it.id] = it.score
+	}
+	return top
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"inkgo/model"
+	"inkgo/repository"
+	"strconv"
+)
+
+var ErrReactionKind = errors.New("不支持的表情")
+
+// ReactionService 对文章和评论添加表情, 点赞是 like 表情
+type ReactionService interface {
+	// React 添加表情, kind 可以是表情名称或表情字符, 返回最新的统计
+	React(user *model.User, target model.ReactionTarget, id string, kind string, grant string) ([]model.ReactionCount, error)
+	// Unreact 取消表情, 返回最新的统计
+	Unreact(user *model.User, target model.ReactionTarget, id string, kind string, grant string) ([]model.ReactionCount, error)
+	// List 统计每种表情的数量, 以及 user 添加过哪些表情
+	List(user *model.User, target model.ReactionTarget, id string, grant string) ([]model.ReactionCount, error)
+}
+
+type reactionService struct {
+	reactionRepository repository.ReactionRepository
+	postService        PostService
+	commentService     CommentService
+}
+
+func NewReactionService(reactionRepository repository.ReactionRepository, postService PostService,
+	commentService CommentService) ReactionService {
+	return &reactionService{
+		reactionRepository: reactionRepository,
+		postService:        postService,
+		commentService:     commentService,
+	}
+}
+
+func (r *reactionService) React(user *model.User, target model.ReactionTarget, id string, kind string, grant string) ([]model.ReactionCount, error) {
+	reactionKind, ok := model.ParseReactionKind(kind)
+	if !ok {
+		return nil, ErrReactionKind
+	}
+	targetID, err := r.authorize(user, target, id, grant)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.reactionRepository.React(&model.Reaction{
+		TargetType: target,
+		TargetID:   targetID,
+		UserID:     user.ID,
+		Kind:       reactionKind,
+	}); err != nil {
+		return nil, err
+	}
+	return r.reactionRepository.Count(target, targetID, user.ID)
+}
+
+func (r *reactionService) Unreact(user *model.User, target model.ReactionTarget, id string, kind string, grant string) ([]model.ReactionCount, error) {
+	reactionKind, ok := model.ParseReactionKind(kind)
+	if !ok {
+		return nil, ErrReactionKind
+	}
+	targetID, err := r.authorize(user, target, id, grant)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.reactionRepository.Unreact(target, targetID, user.ID, reactionKind); err != nil {
+		return nil, err
+	}
+	return r.reactionRepository.Count(target, targetID, user.ID)
+}
+
+func (r *reactionService) List(user *model.User, target model.ReactionTarget, id string, grant string) ([]model.ReactionCount, error) {
+	targetID, err := r.authorize(user, target, id, grant)
+	if err != nil {
+		return nil, err
+	}
+	return r.reactionRepository.Count(target, targetID, user.ID)
+}
+
+// authorize 校验 user 是否可以看到文章或评论, 返回其ID
+func (r *reactionService) authorize(user *model.User, target model.ReactionTarget, id string, grant string) (uint, error) {
+	if target == model.ReactionComment {
+		comment, err := r.commentService.Authorize(user, id, grant)
+		if err != nil {
+			return 0, err
+		}
+		return comment.ID, nil
+	}
+	pid, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, err
+	}
+	post, err := r.postService.Authorize(user, uint(pid), grant)
+	if err != nil {
+		return 0, err
+	}
+	return post.ID, nil
+}
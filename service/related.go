@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/database"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	defaultRelatedTagWeight      = 0.3
+	defaultRelatedCategoryWeight = 0.2
+	defaultRelatedTextWeight     = 0.5
+	defaultRelatedMinScore       = 0.05
+	defaultRelatedSize           = 10
+	defaultRelatedInterval       = 3600
+	// 每篇文章只保留权重最高的词, 减少计算量
+	relatedMaxTerms = 64
+	// 标题中的词按出现多次计算
+	relatedTitleBoost = 3
+)
+
+// RelatedService 计算并缓存每篇文章的相关文章, 同时作为后台任务定期全量重算
+type RelatedService interface {
+	Job
+	// RelatedIDs 按相似度降序返回相关文章ID, 缓存不可用时直接计算
+	RelatedIDs(postID uint, limit int) ([]uint, error)
+	// Refresh 文章发布或更新后异步重新计算, 不阻塞调用方
+	Refresh(postID uint)
+}
+
+type relatedService struct {
+	conf              config.RelatedConfig
+	postRepository    repository.PostRepository
+	relatedRepository repository.RelatedRepository
+	queue             chan uint
+	// index 最近一次全量计算的索引, 单篇文章变更时增量更新. 只在 Run 所在的协程中使用
+	index *relatedIndex
+}
+
+func NewRelatedService(conf config.RelatedConfig, postRepository repository.PostRepository, relatedRepository repository.RelatedRepository) RelatedService {
+	if conf.TagWeight == 0 && conf.CategoryWeight == 0 && conf.TextWeight == 0 {
+		conf.TagWeight, conf.CategoryWeight, conf.TextWeight = defaultRelatedTagWeight, defaultRelatedCategoryWeight, defaultRelatedTextWeight
+	}
+	if conf.MinScore <= 0 {
+		conf.MinScore = defaultRelatedMinScore
+	}
+	if conf.Size <= 0 {
+		conf.Size = defaultRelatedSize
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultRelatedInterval
+	}
+	return &relatedService{
+		conf:              conf,
+		postRepository:    postRepository,
+		relatedRepository: relatedRepository,
+		queue:             make(chan uint, 100),
+	}
+}
+
+func (r *relatedService) Name() string {
+	return "related-posts"
+}
+
+func (r *relatedService) Run(ctx context.Context) {
+	r.logError(r.refreshAll(ctx), "failed to refresh related posts")
+	ticker := time.NewTicker(time.Duration(r.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.logError(r.refreshAll(ctx), "failed to refresh related posts")
+		case id := <-r.queue:
+			r.logError(r.refreshPost(ctx, id), fmt.Sprintf("failed to refresh related posts of %d", id))
+		}
+	}
+}
+
+// logError 未启用 Redis 时不记录日志
+func (r *relatedService) logError(err error, msg string) {
+	if err != nil && !errors.Is(err, database.RedisDisableError) {
+		zap.S().Warnf("%s, %v", msg, err)
+	}
+}
+
+func (r *relatedService) Refresh(postID uint) {
+	select {
+	case r.queue <- postID:
+	default:
+		// 队列已满时放弃, 等待下一次全量计算
+	}
+}
+
+// refreshAll 重新计算所有文章的相关文章
+func (r *relatedService) refreshAll(ctx context.Context) error {
+	index, err := r.buildIndex()
+	if err != nil {
+		return err
+	}
+	r.index = index
+	related := make(map[uint]map[uint]float64, len(index.docs))
+	for i, doc := range index.docs {
+		related[doc.id] = r.top(index.scores(i, r.conf), r.conf.Size)
+	}
+	return r.relatedRepository.ReplaceRelated(ctx, related)
+}
+
+// refreshPost 只更新这篇文章在索引中的 TF-IDF 向量, 重新计算它的相关文章, 并把它加入其它相似文章的推荐中.
+// 其它文章的向量沿用上次全量计算时的 IDF, 下一次全量计算时更新
+func (r *relatedService) refreshPost(ctx context.Context, postID uint) error {
+	if r.index == nil {
+		index, err := r.buildIndex()
+		if err != nil {
+			return err
+		}
+		r.index = index
+	}
+	index := r.index
+	post, err := r.postRepository.FindRelatedCorpus(postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 文章已撤回或不再公开
+		index.remove(postID)
+		return r.relatedRepository.DeleteRelated(ctx, postID)
+	}
+	if err != nil {
+		return err
+	}
+	i := index.update(post)
+	scores := r.top(index.scores(i, r.conf), index.size)
+	if err := r.relatedRepository.ReplaceRelated(ctx, map[uint]map[uint]float64{postID: r.top(scores, r.conf.Size)}); err != nil {
+		return err
+	}
+	for id, score := range scores {
+		if err := r.relatedRepository.AddRelated(ctx, id, postID, score, r.conf.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *relatedService) RelatedIDs(postID uint, limit int) ([]uint, error) {
+	ids, err := r.relatedRepository.ListRelatedIDs(context.Background(), postID, limit)
+	if !errors.Is(err, database.RedisDisableError) {
+		return ids, err
+	}
+	// 没有 Redis 时直接计算, 文章较多时会比较慢
+	index, err := r.buildIndex()
+	if err != nil {
+		return nil, err
+	}
+	i, ok := index.byID[postID]
+	if !ok {
+		return []uint{}, nil
+	}
+	return sortedIDs(r.top(index.scores(i, r.conf), limit)), nil
+}
+
+func (r *relatedService) buildIndex() (*relatedIndex, error) {
+	posts, err := r.postRepository.ListRelatedCorpus()
+	if err != nil {
+		return nil, err
+	}
+	return newRelatedIndex(posts), nil
+}
+
+// top 过滤掉低于 MinScore 的文章, 只保留得分最高的 n 篇
+func (r *relatedService) top(scores map[uint]float64, n int) map[uint]float64 {
+	for id, score := range scores {
+		if score < r.conf.MinScore {
+			delete(scores, id)
+		}
+	}
+	if len(scores) > n {
+		return topScores(scores, n)
+	}
+	return scores
+}
+
+// sortedIDs 按得分降序返回ID
+func sortedIDs(scores map[uint]float64) []uint {
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return scores[ids[i]] > scores[ids[j]]
+	})
+	return ids
+}
+
+// relatedIndex 一次计算所用的文章索引, 包含正文词项和标签、分类的倒排表.
+// 增量更新时被移除的文章在 docs 中留下空位, 下标保持不变
+type relatedIndex struct {
+	docs       []relatedDoc
+	byID       map[uint]int
+	terms      map[string][]termWeight // 词 -> 包含该词的文章及权重
+	tags       map[uint][]int          // 标签ID -> 文章下标
+	categories map[uint][]int          // 分类ID -> 文章下标
+	df         map[string]int          // 词 -> 包含该词的文章数
+	size       int                     // 索引中的文章数, 不含空位
+}
+
+type relatedDoc struct {
+	id         uint
+	tags       []uint
+	categories []uint
+	tf         map[string]int // 词频, 移除文章时据此更新文档频率
+	vector     []termWeight   // 归一化后的 TF-IDF 向量
+}
+
+type termWeight struct {
+	term   string
+	doc    int
+	weight float64
+}
+
+func newRelatedIndex(posts []model.Post) *relatedIndex {
+	index := &relatedIndex{
+		docs:       make([]relatedDoc, 0, len(posts)),
+		byID:       make(map[uint]int, len(posts)),
+		terms:      make(map[string][]termWeight),
+		tags:       make(map[uint][]int),
+		categories: make(map[uint][]int),
+		df:         make(map[string]int),
+	}
+	// 先统计所有文章的词频和文档频率, 再计算向量
+	for i := range posts {
+		index.add(&posts[i])
+	}
+	for i := range index.docs {
+		index.setVector(i)
+	}
+	return index
+}
+
+// update 用文章的最新内容替换索引中的旧版本, 返回文章的下标
+func (idx *relatedIndex) update(post *model.Post) int {
+	idx.remove(post.ID)
+	i := idx.add(post)
+	idx.setVector(i)
+	return i
+}
+
+// add 统计文章的词频并加入标签、分类的倒排表, 向量由 setVector 计算
+func (idx *relatedIndex) add(post *model.Post) int {
+	i := len(idx.docs)
+	doc := relatedDoc{id: post.ID, tf: make(map[string]int)}
+	for _, term := range utils.Tokenize(post.Title) {
+		doc.tf[term] += relatedTitleBoost
+	}
+	for _, term := range utils.Tokenize(post.Content) {
+		doc.tf[term]++
+	}
+	for term := range doc.tf {
+		idx.df[term]++
+	}
+	for _, tag := range post.Tags {
+		doc.tags = append(doc.tags, tag.ID)
+		idx.tags[tag.ID] = append(idx.tags[tag.ID], i)
+	}
+	for _, category := range post.Categories {
+		doc.categories = append(doc.categories, category.ID)
+		idx.categories[category.ID] = append(idx.categories[category.ID], i)
+	}
+	idx.docs = append(idx.docs, doc)
+	idx.byID[post.ID] = i
+	idx.size++
+	return i
+}
+
+// remove 从索引和倒排表中移除文章, 文章不在索引中时不做处理
+func (idx *relatedIndex) remove(id uint) {
+	i, ok := idx.byID[id]
+	if !ok {
+		return
+	}
+	doc := idx.docs[i]
+	for term := range doc.tf {
+		if idx.df[term]--; idx.df[term] <= 0 {
+			delete(idx.df, term)
+		}
+	}
+	for _, tw := range doc.vector {
+		idx.terms[tw.term] = slices.DeleteFunc(idx.terms[tw.term], func(other termWeight) bool { return other.doc == i })
+	}
+	for _, tag := range doc.tags {
+		idx.tags[tag] = slices.DeleteFunc(idx.tags[tag], func(j int) bool { return j == i })
+	}
+	for _, category := range doc.categories {
+		idx.categories[category] = slices.DeleteFunc(idx.categories[category], func(j int) bool { return j == i })
+	}
+	idx.docs[i] = relatedDoc{}
+	delete(idx.byID, id)
+	idx.size--
+}
+
+// setVector 按当前的文档频率计算第 i 篇文章归一化后的 TF-IDF 向量, 并加入词的倒排表
+func (idx *relatedIndex) setVector(i int) {
+	tf := idx.docs[i].tf
+	n := float64(idx.size)
+	vector := make([]termWeight, 0, len(tf))
+	for term, count := range tf {
+		idf := math.Log((1+n)/(1+float64(idx.df[term]))) + 1
+		vector = append(vector, termWeight{term: term, doc: i, weight: (1 + math.Log(float64(count))) * idf})
+	}
+	sort.Slice(vector, func(a, b int) bool {
+		return vector[a].weight > vector[b].weight
+	})
+	if len(vector) > relatedMaxTerms {
+		vector = vector[:relatedMaxTerms]
+	}
+	var norm float64
+	for _, tw := range vector {
+		norm += tw.weight * tw.weight
+	}
+	norm = math.Sqrt(norm)
+	for k := range vector {
+		vector[k].weight /= norm
+		idx.terms[vector[k].term] = append(idx.terms[vector[k].term], vector[k])
+	}
+	idx.docs[i].vector = vector
+}
+
+// scores 计算第 i 篇文章与其它文章的相似度, 只返回有交集的文章
+func (idx *relatedIndex) scores(i int, conf config.RelatedConfig) map[uint]float64 {
+	doc := idx.docs[i]
+	text := make(map[int]float64)
+	for _, tw := range doc.vector {
+		for _, other := range idx.terms[tw.term] {
+			if other.doc != i {
+				text[other.doc] += tw.weight * other.weight
+			}
+		}
+	}
+	sharedTags := sharedCount(i, doc.tags, idx.tags)
+	sharedCategories := sharedCount(i, doc.categories, idx.categories)
+
+	scores := make(map[uint]float64)
+	add := func(j int, score float64) {
+		scores[idx.docs[j].id] += score
+	}
+	for j, cosine := range text {
+		add(j, conf.TextWeight*cosine)
+	}
+	for j, shared := range sharedTags {
+		add(j, conf.TagWeight*jaccard(shared, len(doc.tags), len(idx.docs[j].tags)))
+	}
+	for j, shared := range sharedCategories {
+		add(j, conf.CategoryWeight*jaccard(shared, len(doc.categories), len(idx.docs[j].categories)))
+	}
+	return scores
+}
+
+// sharedCount 统计其它文章与第 i 篇文章共有的标签或分类数
+func sharedCount(i int, ids []uint, postings map[uint][]int) map[int]int {
+	shared := make(map[int]int)
+	for _, id := range ids {
+		for _, j := range postings[id] {
+			if j != i {
+				shared[j]++
+			}
+		}
+	}
+	return shared
+}
+
+func jaccard(shared, a, b int) float64 {
+	union := a + b - shared
+	if union <= 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
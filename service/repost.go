@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"strconv"
+)
+
+// 未获授权的转载文章展示的摘要长度
+const repostExcerptLength = 200
+
+var (
+	ErrRepostForbidden   = errors.New("无权处理该转载")
+	ErrRepostUnpublished = errors.New("只能转载已发布的文章")
+)
+
+type RepostService interface {
+	// Repost 转载站内文章, 转载他人文章需要原作者授权
+	Repost(user *model.User, sourceID string, post *model.Post) (*model.Post, error)
+	// SetSource 为自己的文章登记站外来源
+	SetSource(user *model.User, postID string, repost *model.Repost) (*model.Repost, error)
+	// ListRequests 列出当前用户收到的转载授权申请
+	ListRequests(user *model.User, status string, page, pageSize int) ([]model.Repost, int64, error)
+	// Approve 原作者同意转载
+	Approve(user *model.User, id string) (*model.Repost, error)
+	// Reject 原作者拒绝转载
+	Reject(user *model.User, id string) (*model.Repost, error)
+}
+
+type repostService struct {
+	repostRepository repository.RepostRepository
+	postRepository   repository.PostRepository
+}
+
+func NewRepostService(repostRepository repository.RepostRepository, postRepository repository.PostRepository) RepostService {
+	return &repostService{
+		repostRepository: repostRepository,
+		postRepository:   postRepository,
+	}
+}
+
+func (r *repostService) Repost(user *model.User, sourceID string, post *model.Post) (*model.Post, error) {
+	sid, err := strconv.Atoi(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := r.postRepository.FindByID(uint(sid))
+	if err != nil {
+		return nil, err
+	}
+	if source.State != model.PostPublished {
+		return nil, ErrRepostUnpublished
+	}
+	// 转载一篇站内转载文章时, 署名和授权都指向最初的原文
+	if source.Repost != nil && !source.Repost.IsExternal() {
+		if source, err = r.postRepository.FindByID(*source.Repost.RepostID); err != nil {
+			return nil, err
+		}
+	}
+
+	if post.Title == "" {
+		post.Title = source.Title
+	}
+	post.Cover = source.Cover
+	post.AuthorID = user.ID
+	post.Original = false
+	post.State = model.PostPublished
+
+	repost := &model.Repost{
+		Url:            fmt.Sprintf("/api/v1/post/%d", source.ID),
+		SourceTitle:    source.Title,
+		SourceAuthor:   source.Author.UserName,
+		SourceAuthorID: &source.AuthorID,
+		RepostID:       &source.ID,
+		Status:         model.RepostPending,
+	}
+	// 转载自己的文章无需授权
+	if source.AuthorID == user.ID {
+		repost.Status = model.RepostApproved
+		repost.IsAuthorized = true
+	}
+	// 获得授权之前只保存原文的摘要, 授权后再复制全文
+	post.Content = repostContent(source, repost.IsAuthorized)
+	return r.repostRepository.Create(post, repost)
+}
+
+func (r *repostService) SetSource(user *model.User, postID string, repost *model.Repost) (*model.Repost, error) {
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := r.postRepository.FindByID(uint(pid))
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != user.ID {
+		return nil, ErrRepostForbidden
+	}
+	if post.Repost != nil && !post.Repost.IsExternal() {
+		return nil, errors.New("站内转载的文章不能修改来源")
+	}
+
+	// 站外来源由作者自行声明, 不需要站内授权
+	repost.PostID = post.ID
+	repost.RepostID = nil
+	repost.SourceAuthorID = nil
+	repost.Status = model.RepostApproved
+	repost.IsAuthorized = true
+	return r.repostRepository.Save(repost)
+}
+
+func (r *repostService) ListRequests(user *model.User, status string, page, pageSize int) ([]model.Repost, int64, error) {
+	return r.repostRepository.ListRequests(user.ID, model.RepostStatus(status), page, pageSize)
+}
+
+func (r *repostService) Approve(user *model.User, id string) (*model.Repost, error) {
+	return r.review(user, id, model.RepostApproved)
+}
+
+func (r *repostService) Reject(user *model.User, id string) (*model.Repost, error) {
+	return r.review(user, id, model.RepostRejected)
+}
+
+// review 只有原作者或管理员可以处理转载申请
+func (r *repostService) review(user *model.User, id string, status model.RepostStatus) (*model.Repost, error) {
+	rid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	repost, err := r.repostRepository.Get(uint(rid))
+	if err != nil {
+		return nil, err
+	}
+	if repost.SourceAuthorID == nil || (*repost.SourceAuthorID != user.ID && !utils.IsAdmin(user)) {
+		return nil, ErrRepostForbidden
+	}
+	source, err := r.postRepository.FindByID(*repost.RepostID)
+	if err != nil {
+		return nil, err
+	}
+	return r.repostRepository.UpdateStatus(repost.ID, status, repostContent(source, status == model.RepostApproved))
+}
+
+// repostContent 转载文章保存的正文, 获得授权时为原文全文, 否则只有摘要
+func repostContent(source *model.Post, authorized bool) string {
+	if authorized {
+		return source.Content
+	}
+	return utils.Excerpt(source.Content, repostExcerptLength)
+}
+
+// hideUnauthorizedContent 未获原作者授权的转载文章只展示摘要
+func hideUnauthorizedContent(post *model.Post) {
+	if post != nil && post.Repost != nil && !post.Repost.IsAuthorized {
+		post.Content = utils.Excerpt(post.Content, repostExcerptLength)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	shareCodeLength     = 8
+	shareCodeMaxRetries = 3
+)
+
+var ErrShareForbidden = errors.New("只有作者可以查看分享统计")
+
+type ShareService interface {
+	// Share 为文章生成当前用户的分享短链接, 重复分享返回同一个链接
+	Share(user *model.User, postID string) (*model.Share, error)
+	// Resolve 解析短链接, 记录点击并返回跳转地址
+	Resolve(code string, click *model.ShareClick) (string, error)
+	// Stats 作者查看文章最近 days 天的分享统计
+	Stats(user *model.User, postID string, days int) (*model.ShareStats, error)
+}
+
+type shareService struct {
+	conf            config.ShareConfig
+	shareRepository repository.ShareRepository
+	postRepository  repository.PostRepository
+	postService     PostService
+}
+
+func NewShareService(conf config.ShareConfig, shareRepository repository.ShareRepository, postRepository repository.PostRepository,
+	postService PostService) ShareService {
+	return &shareService{
+		conf:            conf,
+		shareRepository: shareRepository,
+		postRepository:  postRepository,
+		postService:     postService,
+	}
+}
+
+func (s *shareService) Share(user *model.User, postID string) (*model.Share, error) {
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return nil, err
+	}
+	// 只能分享自己可以看到的文章, 加密文章的链接打开后仍需输入密码
+	post, err := s.postService.Authorize(user, uint(pid), "")
+	if errors.Is(err, ErrPostLocked) {
+		post, err = s.postRepository.FindByID(uint(pid))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if post.State != model.PostPublished {
+		return nil, errors.New("只能分享已发布的文章")
+	}
+
+	share, err := s.shareRepository.FindShare(user.ID, post.ID)
+	if err == nil {
+		share.URL = s.shortURL(share.Code)
+		return share, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 短链接码冲突的概率很低, 冲突时重新生成
+	for i := 0; i < shareCodeMaxRetries; i++ {
+		code, err := utils.RandomString(shareCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		share, err = s.shareRepository.Create(&model.Share{
+			AuthorID: user.ID,
+			PostID:   post.ID,
+			Code:     code,
+		})
+		if err == nil {
+			share.URL = s.shortURL(share.Code)
+			return share, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("生成短链接失败, 请重试")
+}
+
+func (s *shareService) Resolve(code string, click *model.ShareClick) (string, error) {
+	share, err := s.shareRepository.GetShareByCode(code)
+	if err != nil {
+		return "", err
+	}
+
+	click.ShareID = share.ID
+	click.PostID = share.PostID
+	click.ClickedAt = time.Now()
+	// 统计失败不影响跳转
+	if err := s.shareRepository.RecordClick(click); err != nil {
+		zap.S().Warnf("failed to record share click %s, %v", code, err)
+	}
+	return s.targetURL(share.PostID), nil
+}
+
+func (s *shareService) Stats(user *model.User, postID string, days int) (*model.ShareStats, error) {
+	pid, err := strconv.Atoi(postID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.postRepository.FindByID(uint(pid))
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != user.ID && !utils.IsAdmin(user) {
+		return nil, ErrShareForbidden
+	}
+	if days <= 0 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	return s.shareRepository.GetStats(post.ID, since)
+}
+
+func (s *shareService) shortURL(code string) string {
+	return strings.TrimRight(s.conf.BaseURL, "/") + "/" + code
+}
+
+func (s *shareService) targetURL(postID uint) string {
+	target := s.conf.TargetURL
+	if target == "" {
+		target = "/api/v1/shared/posts/{id}"
+	}
+	return strings.ReplaceAll(target, "{id}", strconv.FormatUint(uint64(postID), 10))
+}
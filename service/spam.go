@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/spam"
+	"inkgo/utils"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSpamHideThreshold   = 0.95
+	defaultSpamReviewThreshold = 0.7
+	defaultSpamMinSamples      = 20
+	defaultSpamInterval        = 600
+)
+
+// SpamService 垃圾评论分类, 从管理员的标记中学习, 同时作为后台任务定期重新加载模型
+type SpamService interface {
+	Job
+	// Classify 计算评论是垃圾评论的概率并给出评论应设置的状态: 超过 HideThreshold 为 spam, 超过 ReviewThreshold 为 pending.
+	// 未启用或样本不足时总是返回 approved
+	Classify(content string) (model.CommentStatus, float64)
+	// Mark 管理员标记评论是否为垃圾评论, 同时训练分类器. 标记为正常的评论会重新显示
+	Mark(user *model.User, commentID string, isSpam bool) (*model.Comment, error)
+	// ListSpam 管理员查看垃圾评论
+	ListSpam(user *model.User, page, pageSize int) ([]model.Comment, int64, error)
+	// Reload 从数据库重新加载模型
+	Reload() error
+}
+
+type spamService struct {
+	conf              config.SpamConfig
+	model             atomic.Pointer[spam.Model]
+	spamRepository    repository.SpamRepository
+	commentRepository repository.CommentRepository
+	moderationService ModerationService
+}
+
+func NewSpamService(conf config.SpamConfig, spamRepository repository.SpamRepository,
+	commentRepository repository.CommentRepository, moderationService ModerationService) SpamService {
+	if conf.HideThreshold <= 0 || conf.HideThreshold > 1 {
+		conf.HideThreshold = defaultSpamHideThreshold
+	}
+	if conf.ReviewThreshold <= 0 || conf.ReviewThreshold > conf.HideThreshold {
+		conf.ReviewThreshold = min(defaultSpamReviewThreshold, conf.HideThreshold)
+	}
+	if conf.MinSamples <= 0 {
+		conf.MinSamples = defaultSpamMinSamples
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultSpamInterval
+	}
+	s := &spamService{
+		conf:              conf,
+		spamRepository:    spamRepository,
+		commentRepository: commentRepository,
+		moderationService: moderationService,
+	}
+	s.model.Store(spam.NewModel(0, 0, nil))
+	return s
+}
+
+func (s *spamService) Name() string {
+	return "spam-classifier"
+}
+
+func (s *spamService) Run(ctx context.Context) {
+	if err := s.Reload(); err != nil {
+		zap.S().Warnf("failed to load spam classifier, %v", err)
+	}
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(); err != nil {
+				zap.S().Warnf("failed to reload spam classifier, %v", err)
+			}
+		}
+	}
+}
+
+func (s *spamService) Reload() error {
+	spamDocs, hamDocs, err := s.spamRepository.CountLabels()
+	if err != nil {
+		return err
+	}
+	rows, err := s.spamRepository.ListFeatures()
+	if err != nil {
+		return err
+	}
+	features := make(map[string]spam.Counts, len(rows))
+	for _, row := range rows {
+		features[row.Feature] = spam.Counts{Spam: row.Spam, Ham: row.Ham}
+	}
+	s.model.Store(spam.NewModel(spamDocs, hamDocs, features))
+	return nil
+}
+
+func (s *spamService) Classify(content string) (model.CommentStatus, float64) {
+	m := s.model.Load()
+	if !s.conf.Enabled || !m.Trained(s.conf.MinSamples) {
+		return model.CommentApproved, 0
+	}
+	score := m.Score(spam.Features(content))
+	switch {
+	case score >= s.conf.HideThreshold:
+		return model.CommentSpam, score
+	case score >= s.conf.ReviewThreshold:
+		return model.CommentPending, score
+	}
+	return model.CommentApproved, score
+}
+
+func (s *spamService) Mark(user *model.User, commentID string, isSpam bool) (*model.Comment, error) {
+	if !utils.IsAdmin(user) {
+		return nil, ErrModerationForbidden
+	}
+	cid, err := strconv.Atoi(commentID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.commentRepository.Get(uint(cid))
+	if err != nil {
+		return nil, err
+	}
+	previous := comment.Status
+	status := model.CommentApproved
+	if isSpam {
+		status = model.CommentSpam
+	}
+	label := &model.SpamLabel{
+		CommentID: comment.ID,
+		Spam:      isSpam,
+		Features:  strings.Join(spam.Features(comment.Content), "\n"),
+		UserID:    user.ID,
+	}
+	if err := s.spamRepository.Label(label, status); err != nil {
+		return nil, err
+	}
+	comment.Status = status
+	// 管理员已经处理过, 不再需要审核
+	if err := s.moderationService.Release(model.ModerationComment, comment.ID); err != nil {
+		zap.S().Warnf("failed to release moderation review of comment %d, %v", comment.ID, err)
+	}
+	if err := s.Reload(); err != nil {
+		zap.S().Warnf("failed to reload spam classifier, %v", err)
+	}
+	// 标记为非垃圾评论后评论公开, 与审核通过一样发送通知
+	if status == model.CommentApproved && previous != model.CommentApproved {
+		s.moderationService.Approved(model.ModerationComment, comment.ID)
+	}
+	return comment, nil
+}
+
+func (s *spamService) ListSpam(user *model.User, page, pageSize int) ([]model.Comment, int64, error) {
+	if !utils.IsAdmin(user) {
+		return nil, 0, ErrModerationForbidden
+	}
+	return s.commentRepository.ListByStatus(model.CommentSpam, page, pageSize)
+}
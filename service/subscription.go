@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/gomail.v2"
+	"html"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMailInterval = 600
+	// 每次最多读取的待发送通知数, 剩余的下次处理
+	replyEmailBatch = 500
+	// 邮件中回复内容的摘要长度
+	replyExcerptLength = 200
+)
+
+var (
+	ErrSubscriptionTarget   = errors.New("无效的订阅类型, 可选值为 post, comment")
+	ErrSubscriptionNotOwner = errors.New("只能订阅自己评论的回复")
+	ErrUnsubscribeToken     = errors.New("无效的退订链接")
+)
+
+// mailSender 发送邮件, 便于替换 gomail.Dialer
+type mailSender interface {
+	DialAndSend(m ...*gomail.Message) error
+}
+
+// SubscriptionService 订阅文章评论和评论回复的邮件通知, 同时作为后台任务定期合并发送通知邮件
+type SubscriptionService interface {
+	Job
+	// Subscribe 订阅文章下的新评论, 或自己评论收到的回复
+	Subscribe(user *model.User, target model.SubscriptionTarget, id string, grant string) error
+	// Unsubscribe 取消订阅
+	Unsubscribe(user *model.User, target model.SubscriptionTarget, id string) error
+	// VerifyUnsubscribeToken 校验退订链接, 不取消订阅
+	VerifyUnsubscribeToken(token string) error
+	// UnsubscribeByToken 通过邮件中的退订链接取消订阅, 无需登录
+	UnsubscribeByToken(token string) error
+	// List 获取用户的订阅
+	List(user *model.User) ([]model.Subscription, error)
+	// NotifyReply 新评论公开后, 为订阅了文章或上级评论的用户加入待发送队列
+	NotifyReply(author *model.User, comment *model.Comment) error
+}
+
+type subscriptionService struct {
+	conf                   config.MailConfig
+	sender                 mailSender
+	subscriptionRepository repository.SubscriptionRepository
+	commentRepository      repository.CommentRepository
+	postService            PostService
+}
+
+func NewSubscriptionService(conf config.MailConfig, subscriptionRepository repository.SubscriptionRepository,
+	commentRepository repository.CommentRepository, postService PostService) SubscriptionService {
+	if conf.Interval <= 0 {
+		conf.Interval = defaultMailInterval
+	}
+	s := &subscriptionService{
+		conf:                   conf,
+		subscriptionRepository: subscriptionRepository,
+		commentRepository:      commentRepository,
+		postService:            postService,
+	}
+	if conf.Enabled {
+		s.sender = newMailSender(conf)
+	}
+	return s
+}
+
+// newMailSender 未配置 SMTP 服务器时根据发件邮箱的域名查找, 找不到时不发送邮件
+func newMailSender(conf config.MailConfig) mailSender {
+	if conf.Host == "" {
+		_, domain := utils.ParseEmail(conf.From)
+		smtpConfig, ok := config.LookupSMTPConfig(domain)
+		if !ok {
+			zap.S().Warnf("unsupported mail provider %q, reply emails disabled", domain)
+			return nil
+		}
+		conf.Host, conf.Port = smtpConfig.Host, smtpConfig.Port
+	}
+	d := gomail.NewDialer(conf.Host, conf.Port, conf.From, conf.AuthCode)
+	d.SSL = conf.Port == 465
+	return d
+}
+
+func (s *subscriptionService) Subscribe(user *model.User, target model.SubscriptionTarget, id string, grant string) error {
+	targetID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	switch target {
+	case model.SubscriptionPost:
+		if _, err := s.postService.Authorize(user, uint(targetID), grant); err != nil {
+			return err
+		}
+	case model.SubscriptionComment:
+		comment, err := s.commentRepository.Get(uint(targetID))
+		if err != nil {
+			return err
+		}
+		if comment.AuthorID != user.ID {
+			return ErrSubscriptionNotOwner
+		}
+	default:
+		return ErrSubscriptionTarget
+	}
+	return s.subscriptionRepository.Subscribe(&model.Subscription{
+		UserID:     user.ID,
+		TargetType: target,
+		TargetID:   uint(targetID),
+	})
+}
+
+func (s *subscriptionService) Unsubscribe(user *model.User, target model.SubscriptionTarget, id string) error {
+	if target != model.SubscriptionPost && target != model.SubscriptionComment {
+		return ErrSubscriptionTarget
+	}
+	targetID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	return s.subscriptionRepository.Unsubscribe(user.ID, target, uint(targetID))
+}
+
+func (s *subscriptionService) VerifyUnsubscribeToken(token string) error {
+	_, _, _, err := s.parseUnsubscribeToken(token)
+	return err
+}
+
+func (s *subscriptionService) UnsubscribeByToken(token string) error {
+	userID, target, targetID, err := s.parseUnsubscribeToken(token)
+	if err != nil {
+		return err
+	}
+	return s.subscriptionRepository.Unsubscribe(userID, target, targetID)
+}
+
+// parseUnsubscribeToken 校验退订链接的签名, 返回其中的用户、订阅类型和订阅对象
+func (s *subscriptionService) parseUnsubscribeToken(token string) (uint, model.SubscriptionTarget, uint, error) {
+	payload, ok := utils.VerifySign(s.conf.Secret, token)
+	if !ok {
+		return 0, "", 0, ErrUnsubscribeToken
+	}
+	// payload 格式为 用户ID:订阅类型:订阅对象ID
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 {
+		return 0, "", 0, ErrUnsubscribeToken
+	}
+	target := model.SubscriptionTarget(parts[1])
+	if target != model.SubscriptionPost && target != model.SubscriptionComment && target != model.SubscriptionAll {
+		return 0, "", 0, ErrUnsubscribeToken
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", 0, ErrUnsubscribeToken
+	}
+	targetID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, "", 0, ErrUnsubscribeToken
+	}
+	return uint(userID), target, uint(targetID), nil
+}
+
+func (s *subscriptionService) List(user *model.User) ([]model.Subscription, error) {
+	return s.subscriptionRepository.List(user.ID)
+}
+
+func (s *subscriptionService) NotifyReply(author *model.User, comment *model.Comment) error {
+	if s.sender == nil {
+		return nil
+	}
+	subscriptions, err := s.subscriptionRepository.Match(comment.PostID, comment.ParentID)
+	if err != nil {
+		return err
+	}
+	excerpt := utils.Excerpt(comment.Content, replyExcerptLength)
+	emails := make([]model.ReplyEmail, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.UserID == author.ID {
+			continue
+		}
+		emails = append(emails, model.ReplyEmail{
+			UserID:     subscription.UserID,
+			CommentID:  comment.ID,
+			PostID:     comment.PostID,
+			TargetType: subscription.TargetType,
+			TargetID:   subscription.TargetID,
+			ActorName:  author.UserName,
+			Excerpt:    excerpt,
+		})
+	}
+	return s.subscriptionRepository.Enqueue(emails)
+}
+
+func (s *subscriptionService) Name() string {
+	return "reply-mail"
+}
+
+func (s *subscriptionService) Run(ctx context.Context) {
+	if s.sender == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(s.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.sendPending(ctx)
+	}
+}
+
+// sendPending 将待发送的通知按收件人合并, 每人发送一封摘要邮件
+func (s *subscriptionService) sendPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		emails, err := s.subscriptionRepository.ListPending(replyEmailBatch)
+		if err != nil {
+			zap.S().Warnf("failed to list pending reply emails, %v", err)
+			return
+		}
+		digests := make(map[uint][]model.ReplyEmail)
+		order := make([]uint, 0)
+		for _, email := range emails {
+			if _, ok := digests[email.UserID]; !ok {
+				order = append(order, email.UserID)
+			}
+			digests[email.UserID] = append(digests[email.UserID], email)
+		}
+		sent := 0
+		for _, userID := range order {
+			digest := digests[userID]
+			ids := make([]uint, len(digest))
+			for i := range digest {
+				ids[i] = digest[i].ID
+			}
+			// 收件人已无权查看的文章下的回复不发送, 与没有邮箱的用户一样直接标记为已发送
+			digest = s.visibleReplies(digest)
+			if len(digest) > 0 && digest[0].User.Email != "" {
+				if err := s.sender.DialAndSend(s.message(digest)); err != nil {
+					zap.S().Warnf("failed to send reply email to user %d, %v", userID, err)
+					continue
+				}
+			}
+			if err := s.subscriptionRepository.MarkSent(ids); err != nil {
+				zap.S().Warnf("failed to mark reply emails of user %d as sent, %v", userID, err)
+				continue
+			}
+			sent++
+		}
+		// 全部失败时等下次再发送, 避免死循环
+		if len(emails) < replyEmailBatch || sent == 0 {
+			return
+		}
+	}
+}
+
+// visibleReplies 去掉收件人已无权查看的文章下的回复. 加密文章在订阅时已验证过密码, 视为可见
+func (s *subscriptionService) visibleReplies(digest []model.ReplyEmail) []model.ReplyEmail {
+	visible := make([]model.ReplyEmail, 0, len(digest))
+	allowed := make(map[uint]bool)
+	for _, email := range digest {
+		ok, checked := allowed[email.PostID]
+		if !checked {
+			_, err := s.postService.Authorize(&email.User, email.PostID, "")
+			ok = err == nil || errors.Is(err, ErrPostLocked)
+			allowed[email.PostID] = ok
+		}
+		if ok {
+			visible = append(visible, email)
+		}
+	}
+	return visible
+}
+
+// message 生成摘要邮件, 每条回复附带退订对应订阅的链接, List-Unsubscribe 头退订全部
+func (s *subscriptionService) message(digest []model.ReplyEmail) *gomail.Message {
+	user := digest[0].User
+	var body strings.Builder
+	fmt.Fprintf(&body, "<p>%s, 你关注的讨论有 %d 条新回复:</p>", html.EscapeString(user.UserName), len(digest))
+	for _, email := range digest {
+		postURL := strings.ReplaceAll(s.conf.PostURL, "{id}", strconv.Itoa(int(email.PostID)))
+		fmt.Fprintf(&body, `<blockquote><p><strong>%s</strong>: %s</p><p><a href="%s">查看</a> · <a href="%s">不再接收此讨论的通知</a></p></blockquote>`,
+			html.EscapeString(email.ActorName), html.EscapeString(email.Excerpt), html.EscapeString(postURL),
+			html.EscapeString(s.unsubscribeURL(user.ID, email.TargetType, email.TargetID)))
+	}
+	unsubscribeAll := s.unsubscribeURL(user.ID, model.SubscriptionAll, 0)
+	fmt.Fprintf(&body, `<p><a href="%s">退订全部回复通知</a></p>`, html.EscapeString(unsubscribeAll))
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.conf.From)
+	m.SetHeader("To", user.Email)
+	m.SetHeader("Subject", fmt.Sprintf("你有 %d 条新回复", len(digest)))
+	m.SetHeader("List-Unsubscribe", "<"+unsubscribeAll+">")
+	m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	m.SetBody("text/html", body.String())
+	return m
+}
+
+// unsubscribeURL 生成带签名的退订链接
+func (s *subscriptionService) unsubscribeURL(userID uint, target model.SubscriptionTarget, targetID uint) string {
+	token := utils.Sign(s.conf.Secret, fmt.Sprintf("%d:%s:%d", userID, target, targetID))
+	return s.conf.UnsubscribeURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"inkgo/config"
+	"inkgo/database"
+	"inkgo/model"
+	"inkgo/repository"
+	"slices"
+	"time"
+)
+
+const (
+	defaultTimelineSize             = 800
+	defaultTimelinePopularFollowers = 1000
+	defaultTimelineExpiration       = 168
+)
+
+// TimelineService 首页时间线. 普通作者发文时推送到粉丝在 Redis 中的时间线(写扩散),
+// 粉丝较多的作者的文章在读取时间线时再查询(读扩散), 关注的标签和分类同样在读取时查询
+type TimelineService interface {
+	// Timeline 按游标获取用户的首页时间线, 不包含屏蔽和拉黑的用户的文章
+	Timeline(user *model.User, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error)
+	// Publish 文章发布后推送到粉丝的时间线, 失败时只记录日志
+	Publish(post *model.Post)
+	// Invalidate 关注关系变化后删除用户的时间线, 下次读取时重建
+	Invalidate(userID uint)
+}
+
+type timelineService struct {
+	conf               config.TimelineConfig
+	timelineRepository repository.TimelineRepository
+	postRepository     repository.PostRepository
+	followRepository   repository.FollowRepository
+	blockRepository    repository.BlockRepository
+	likeRepository     repository.LikeRepository
+}
+
+func NewTimelineService(conf config.TimelineConfig, timelineRepository repository.TimelineRepository, postRepository repository.PostRepository,
+	followRepository repository.FollowRepository, blockRepository repository.BlockRepository, likeRepository repository.LikeRepository) TimelineService {
+	if conf.Size <= 0 {
+		conf.Size = defaultTimelineSize
+	}
+	if conf.PopularFollowers <= 0 {
+		conf.PopularFollowers = defaultTimelinePopularFollowers
+	}
+	if conf.Expiration <= 0 {
+		conf.Expiration = defaultTimelineExpiration
+	}
+	return &timelineService{
+		conf:               conf,
+		timelineRepository: timelineRepository,
+		postRepository:     postRepository,
+		followRepository:   followRepository,
+		blockRepository:    blockRepository,
+		likeRepository:     likeRepository,
+	}
+}
+
+func (t *timelineService) Timeline(user *model.User, cursor *model.Cursor, limit int) ([]model.Post, *model.Cursor, error) {
+	filter, err := t.filter(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, next, err := t.postRepository.ListTimeline(user.ID, filter, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	liked, err := t.likeRepository.ListLiked(user.ID, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range posts {
+		posts[i].UserLiked = liked[posts[i].ID]
+	}
+	return posts, next, nil
+}
+
+// filter 汇总时间线的文章来源: Redis 中推送的文章, 粉丝较多的作者, 关注的标签和分类
+func (t *timelineService) filter(userID uint) (repository.TimelineFilter, error) {
+	var filter repository.TimelineFilter
+	topics, err := t.followRepository.ListTopics(userID)
+	if err != nil {
+		return filter, err
+	}
+	for _, topic := range topics {
+		switch topic.TargetType {
+		case model.FollowTag:
+			filter.TagIDs = append(filter.TagIDs, topic.TargetID)
+		case model.FollowCategory:
+			filter.CategoryIDs = append(filter.CategoryIDs, topic.TargetID)
+		}
+	}
+	if filter.ExcludeAuthorIDs, err = t.blockRepository.ListHiddenAuthors(userID); err != nil {
+		return filter, err
+	}
+
+	ctx := context.Background()
+	ids, ok, err := t.timelineRepository.Range(ctx, userID)
+	if err != nil {
+		t.logError(err, "failed to read timeline")
+		// 没有 Redis 时全部在读取时查询
+		filter.AuthorIDs, err = t.followRepository.ListFollowedIDs(userID)
+		return filter, err
+	}
+	if filter.AuthorIDs, err = t.followRepository.ListPopularFollowed(userID, t.conf.PopularFollowers); err != nil {
+		return filter, err
+	}
+	if !ok {
+		if ids, err = t.rebuild(ctx, userID, filter.AuthorIDs); err != nil {
+			return filter, err
+		}
+	}
+	filter.PostIDs = ids
+	return filter, nil
+}
+
+// rebuild 用普通作者最近的文章重建时间线, 粉丝较多的作者的文章不放入时间线
+func (t *timelineService) rebuild(ctx context.Context, userID uint, popular []uint) ([]uint, error) {
+	followed, err := t.followRepository.ListFollowedIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	authors := slices.DeleteFunc(followed, func(id uint) bool {
+		return slices.Contains(popular, id)
+	})
+	ids, err := t.postRepository.ListRecentIDsByAuthors(authors, t.conf.Size)
+	if err != nil {
+		return nil, err
+	}
+	expiration := time.Duration(t.conf.Expiration) * time.Hour
+	t.logError(t.timelineRepository.Replace(ctx, userID, ids, expiration), "failed to rebuild timeline")
+	return ids, nil
+}
+
+func (t *timelineService) Publish(post *model.Post) {
+	// 仅自己可见和不公开列出的文章不出现在时间线中
+	if post.State != model.PostPublished ||
+		post.Visibility == model.VisibilityPrivate || post.Visibility == model.VisibilityUnlisted {
+		return
+	}
+	count, err := t.followRepository.GetFollowerCount(post.AuthorID)
+	if err != nil {
+		zap.S().Warnf("failed to count followers of user %d, %v", post.AuthorID, err)
+		return
+	}
+	if count == 0 || count >= t.conf.PopularFollowers {
+		return
+	}
+	followers, err := t.followRepository.ListFollowerIDs(post.AuthorID)
+	if err != nil {
+		zap.S().Warnf("failed to list followers of user %d, %v", post.AuthorID, err)
+		return
+	}
+	t.logError(t.timelineRepository.Push(context.Background(), followers, post.ID, t.conf.Size), "failed to push post to timelines")
+}
+
+func (t *timelineService) Invalidate(userID uint) {
+	t.logError(t.timelineRepository.Invalidate(context.Background(), userID), "failed to invalidate timeline")
+}
+
+// logError 未启用 Redis 时不记录日志
+func (t *timelineService) logError(err error, msg string) {
+	if err != nil && !errors.Is(err, database.RedisDisableError) {
+		zap.S().Warnf("%s, %v", msg, err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"inkgo/config"
+	"inkgo/model"
+	"inkgo/repository"
+	"inkgo/utils"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTrashRetention = 30
+	defaultTrashInterval  = 3600
+	// 每次最多彻底删除的过期内容数量, 剩余的下次处理
+	trashPurgeBatch = 100
+	// 回收站中评论内容的显示长度
+	trashTitleLength = 100
+)
+
+var (
+	ErrTrashForbidden = errors.New("无权操作该内容")
+	ErrTrashType      = errors.New("无效的类型, 可选值为 post, comment, favorite")
+	ErrTrashConflict  = errors.New("已存在同名的内容, 无法恢复")
+)
+
+// TrashService 回收站, 同时作为后台任务彻底删除超过保留期的内容
+type TrashService interface {
+	Job
+	// List 列出当前用户删除的内容, 管理员 all 为 true 时列出所有人的
+	List(user *model.User, itemType string, all bool, page, pageSize int) ([]model.TrashItem, int64, error)
+	// Restore 恢复已删除的内容
+	Restore(user *model.User, itemType string, id string) error
+	// Purge 彻底删除内容, 不可恢复
+	Purge(user *model.User, itemType string, id string) error
+}
+
+type trashService struct {
+	conf            config.TrashConfig
+	trashRepository repository.TrashRepository
+	relatedService  RelatedService
+}
+
+func NewTrashService(conf config.TrashConfig, trashRepository repository.TrashRepository, relatedService RelatedService) TrashService {
+	if conf.Retention <= 0 {
+		conf.Retention = defaultTrashRetention
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = defaultTrashInterval
+	}
+	return &trashService{
+		conf:            conf,
+		trashRepository: trashRepository,
+		relatedService:  relatedService,
+	}
+}
+
+func (t *trashService) List(user *model.User, itemType string, all bool, page, pageSize int) ([]model.TrashItem, int64, error) {
+	filter := repository.TrashFilter{OwnerID: user.ID, Type: model.TrashType(itemType)}
+	if filter.Type != "" && !filter.Type.Valid() {
+		return nil, 0, ErrTrashType
+	}
+	if all {
+		if !utils.IsAdmin(user) {
+			return nil, 0, ErrTrashForbidden
+		}
+		filter.OwnerID = 0
+	}
+	items, total, err := t.trashRepository.List(filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		if runes := []rune(items[i].Title); len(runes) > trashTitleLength {
+			items[i].Title = string(runes[:trashTitleLength])
+		}
+		items[i].PurgeAt = items[i].DeletedAt.AddDate(0, 0, t.conf.Retention)
+	}
+	return items, total, nil
+}
+
+func (t *trashService) Restore(user *model.User, itemType string, id string) error {
+	item, err := t.find(user, itemType, id)
+	if err != nil {
+		return err
+	}
+	if err := t.trashRepository.Restore(item.Type, item.ID); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrTrashConflict
+		}
+		return err
+	}
+	if item.Type == model.TrashPost {
+		t.relatedService.Refresh(item.ID)
+	}
+	return nil
+}
+
+func (t *trashService) Purge(user *model.User, itemType string, id string) error {
+	item, err := t.find(user, itemType, id)
+	if err != nil {
+		return err
+	}
+	return t.trashRepository.Purge(item.Type, item.ID)
+}
+
+// find 获取回收站中的内容, 只有属主和管理员可以操作
+func (t *trashService) find(user *model.User, itemType string, id string) (*model.TrashItem, error) {
+	if !model.TrashType(itemType).Valid() {
+		return nil, ErrTrashType
+	}
+	tid, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	item, err := t.trashRepository.Get(model.TrashType(itemType), uint(tid))
+	if err != nil {
+		return nil, err
+	}
+	if item.OwnerID != user.ID && !utils.IsAdmin(user) {
+		return nil, ErrTrashForbidden
+	}
+	return item, nil
+}
+
+func (t *trashService) Name() string {
+	return "trash-purge"
+}
+
+func (t *trashService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(t.conf.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		t.purgeExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired 彻底删除超过保留期的内容
+func (t *trashService) purgeExpired(ctx context.Context) {
+	filter := repository.TrashFilter{Before: time.Now().AddDate(0, 0, -t.conf.Retention)}
+	for ctx.Err() == nil {
+		items, _, err := t.trashRepository.List(filter, 1, trashPurgeBatch)
+		if err != nil {
+			zap.S().Warnf("failed to list expired trash, %v", err)
+			return
+		}
+		purged := 0
+		for _, item := range items {
+			if err := t.trashRepository.Purge(item.Type, item.ID); err != nil {
+				zap.S().Warnf("failed to purge %s %d, %v", item.Type, item.ID, err)
+				continue
+			}
+			purged++
+		}
+		if purged > 0 {
+			zap.S().Infof("purged %d expired items from trash", purged)
+		}
+		// 全部失败时不再重试, 避免死循环
+		if len(items) < trashPurgeBatch || purged == 0 {
+			return
+		}
+	}
+}
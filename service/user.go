@@ -218,6 +218,11 @@ func (u *userService) List(pageSize int, page int) ([]model.User, int64, error)
 	return user, total, nil
 }
 
+// ListByCursor 按游标获取用户列表
+func (u *userService) ListByCursor(cursor *model.Cursor, limit int) ([]model.User, *model.Cursor, error) {
+	return u.userRepository.ListByCursor(cursor, limit)
+}
+
 // GetUserByName 根据用户名获取用户
 func (u *userService) GetUserByName(name string) (*model.User, error) {
 	if name == "" {
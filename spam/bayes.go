@@ -0,0 +1,95 @@
+// Package spam 朴素贝叶斯垃圾评论分类器, 在进程内计算, 不依赖外部服务
+package spam
+
+import (
+	"inkgo/utils"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// 每条评论最多使用的特征数, 避免超长评论主导训练结果
+	maxFeatures = 200
+	// 特征的最大长度, 与数据库中的字段长度一致
+	maxFeatureLength = 64
+)
+
+var linkPattern = regexp.MustCompile(`https?://[^\s)\]>"']+`)
+
+// Counts 一个特征在垃圾评论和正常评论中出现的次数(按评论计, 同一条评论只算一次)
+type Counts struct {
+	Spam int64
+	Ham  int64
+}
+
+// Model 分类器的训练结果, 构建后只读, 可以并发使用
+type Model struct {
+	SpamDocs int64 // 训练过的垃圾评论数
+	HamDocs  int64 // 训练过的正常评论数
+	Features map[string]Counts
+}
+
+func NewModel(spamDocs, hamDocs int64, features map[string]Counts) *Model {
+	if features == nil {
+		features = make(map[string]Counts)
+	}
+	return &Model{SpamDocs: spamDocs, HamDocs: hamDocs, Features: features}
+}
+
+// Trained 垃圾评论和正常评论都至少有 minSamples 条样本时才用于分类
+func (m *Model) Trained(minSamples int64) bool {
+	return m.SpamDocs >= minSamples && m.HamDocs >= minSamples
+}
+
+// Score 返回评论是垃圾评论的概率. 使用拉普拉斯平滑, 在对数空间累加避免下溢
+func (m *Model) Score(features []string) float64 {
+	logOdds := math.Log(float64(m.SpamDocs+1)) - math.Log(float64(m.HamDocs+1))
+	for _, feature := range features {
+		c, ok := m.Features[feature]
+		if !ok {
+			// 没见过的特征不提供信息
+			continue
+		}
+		pSpam := float64(c.Spam+1) / float64(m.SpamDocs+2)
+		pHam := float64(c.Ham+1) / float64(m.HamDocs+2)
+		logOdds += math.Log(pSpam) - math.Log(pHam)
+	}
+	return 1 / (1 + math.Exp(-logOdds))
+}
+
+// Features 提取评论的特征: 词、链接的域名和链接数量, 去重并排序
+func Features(text string) []string {
+	seen := make(map[string]bool)
+	for _, token := range utils.Tokenize(text) {
+		if runes := []rune(token); len(runes) > maxFeatureLength {
+			token = string(runes[:maxFeatureLength])
+		}
+		seen[token] = true
+	}
+	links := linkPattern.FindAllString(text, -1)
+	for _, link := range links {
+		if u, err := url.Parse(link); err == nil && u.Host != "" {
+			host := strings.ToLower(u.Hostname())
+			if len(host) > maxFeatureLength-len("link:") {
+				host = host[:maxFeatureLength-len("link:")]
+			}
+			seen["link:"+host] = true
+		}
+	}
+	// 链接数量按 0, 1, 2, 3+ 分档
+	seen["links:"+strconv.Itoa(min(len(links), 3))] = true
+
+	features := make([]string, 0, len(seen))
+	for feature := range seen {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	if len(features) > maxFeatures {
+		features = features[:maxFeatures]
+	}
+	return features
+}
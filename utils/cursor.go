@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"inkgo/model"
+	"strconv"
+)
+
+const (
+	defaultCursorLimit = 10
+	maxCursorLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// EncodeCursor 将游标编码为不透明的字符串, 没有下一页时返回空字符串
+func EncodeCursor(cursor *model.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标, 空字符串表示第一页
+func DecodeCursor(s string) (*model.Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := new(model.Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// CursorQuery 解析 ?cursor=&limit= 参数. 两个参数都没有时 ok 为 false, 调用方继续使用 page/page_size 分页
+func CursorQuery(c *gin.Context) (cursor *model.Cursor, limit int, ok bool, err error) {
+	value, hasCursor := c.GetQuery("cursor")
+	limitValue, hasLimit := c.GetQuery("limit")
+	if !hasCursor && !hasLimit {
+		return nil, 0, false, nil
+	}
+	if cursor, err = DecodeCursor(value); err != nil {
+		return nil, 0, true, err
+	}
+	limit, _ = strconv.Atoi(limitValue)
+	if limit <= 0 {
+		limit = defaultCursorLimit
+	}
+	if limit > maxCursorLimit {
+		limit = maxCursorLimit
+	}
+	return cursor, limit, true, nil
+}
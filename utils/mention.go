@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 用户名的最大长度, 与 model.User 的 UserName 一致
+const mentionNameLength = 64
+
+// ParseMentions 提取文本中 @ 到的用户名, 按首次出现的顺序去重.
+// @ 前面是字母或数字时不算, 避免把邮箱地址识别为 @; 用户名末尾的 . 视为句号
+func ParseMentions(texts ...string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		runes := []rune(text)
+		for i := 0; i < len(runes); i++ {
+			if runes[i] != '@' || (i > 0 && isMentionRune(runes[i-1])) {
+				continue
+			}
+			j := i + 1
+			for j < len(runes) && isMentionRune(runes[j]) {
+				j++
+			}
+			name := strings.TrimRight(string(runes[i+1:j]), ".")
+			i = j - 1
+			if name == "" || len([]rune(name)) > mentionNameLength || seen[strings.ToLower(name)] {
+				continue
+			}
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
@@ -1,12 +1,16 @@
 package utils
 
 import (
+	crand "crypto/rand"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"math/big"
 	"math/rand"
 	"time"
 )
 
+const base62 = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
 func GenerateCode() string {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	return fmt.Sprintf("%06d", r.Intn(1000000))
@@ -16,3 +20,17 @@ func HashPassword(password string) (string, error) {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(hashed), err
 }
+
+// RandomString 生成长度为 n 的随机字符串, 只包含数字和大小写字母, 可用于短链接或一次性令牌
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(base62)))
+	for i := range b {
+		idx, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = base62[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -2,16 +2,18 @@ package utils
 
 import (
 	"github.com/gin-gonic/gin"
+	"inkgo/model"
 	"net/http"
 )
 
 type Response struct {
-	Code     int         `json:"code"`                //状态码
-	Message  string      `json:"message"`             //提示信息
-	Data     interface{} `json:"data"`                //返回数据
-	Total    *int64      `json:"total,omitempty"`     // 总数（分页时返回）
-	Page     *int        `json:"page,omitempty"`      // 当前页（分页时返回）
-	PageSize *int        `json:"page_size,omitempty"` // 页大小（分页时返回）
+	Code       int         `json:"code"`                  //状态码
+	Message    string      `json:"message"`               //提示信息
+	Data       interface{} `json:"data"`                  //返回数据
+	Total      *int64      `json:"total,omitempty"`       // 总数（分页时返回）
+	Page       *int        `json:"page,omitempty"`        // 当前页（分页时返回）
+	PageSize   *int        `json:"page_size,omitempty"`   // 页大小（分页时返回）
+	NextCursor *string     `json:"next_cursor,omitempty"` // 下一页的游标（游标分页时返回, 没有下一页时为空字符串）
 }
 
 func JSON(c *gin.Context, code int, message string, data interface{}, total *int64, page, pageSize *int) {
@@ -33,6 +35,17 @@ func SuccessWithPage(c *gin.Context, data interface{}, total int64, page, pageSi
 	JSON(c, http.StatusOK, "success", data, &total, &page, &pageSize)
 }
 
+// SuccessWithCursor 游标分页的响应, next 为 nil 表示没有下一页
+func SuccessWithCursor(c *gin.Context, data interface{}, next *model.Cursor) {
+	cursor := EncodeCursor(next)
+	c.JSON(http.StatusOK, Response{
+		Code:       http.StatusOK,
+		Message:    "success",
+		Data:       data,
+		NextCursor: &cursor,
+	})
+}
+
 func Error(c *gin.Context, code int, err error) {
 	if code == 0 {
 		code = http.StatusInternalServerError
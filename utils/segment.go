@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"github.com/go-ego/gse"
+	"go.uber.org/zap"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 正文中的链接和图片地址不参与分词
+var linkPattern = regexp.MustCompile(`https?://[^\s)\]>"']+`)
+
+// 常见的无意义词, 包括中文的虚词和单字
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true, "are": true, "was": true,
+	"from": true, "not": true, "but": true, "you": true, "can": true, "have": true, "has": true, "will": true,
+	"我们": true, "你们": true, "他们": true, "一个": true, "这个": true, "那个": true, "可以": true, "没有": true,
+	"就是": true, "什么": true, "因为": true, "所以": true, "但是": true, "如果": true, "已经": true, "进行": true,
+	"这样": true, "这些": true, "那些": true, "还是": true, "以及": true, "或者": true, "然后": true, "时候": true,
+	"自己": true, "需要": true, "通过": true, "使用": true, "如何": true, "为了": true, "其中": true, "之后": true,
+	"的": true, "了": true, "是": true, "在": true, "和": true, "也": true, "就": true, "都": true, "而": true,
+	"及": true, "与": true, "着": true, "或": true, "一": true, "不": true, "有": true, "这": true, "那": true,
+	"我": true, "你": true, "他": true, "她": true, "它": true, "把": true, "被": true, "给": true, "从": true,
+}
+
+var (
+	segmenter     gse.Segmenter
+	segmenterOnce sync.Once
+)
+
+// loadSegmenter 加载内置的简体中文词典, 词典较大, 第一次分词时才加载
+func loadSegmenter() {
+	segmenter.SkipLog = true
+	if err := segmenter.LoadDictEmbed("zh_s"); err != nil {
+		// 词典加载失败时仍可按 HMM 切分, 只是准确率较低
+		zap.S().Warnf("failed to load segment dictionary, %v", err)
+	}
+}
+
+// Tokenize 将文本切分为词, 用于计算文本相似度.
+// 中文使用 gse 按词典和 HMM 分词, 西文按单词切分并转为小写, 去掉标点和无意义词
+func Tokenize(text string) []string {
+	segmenterOnce.Do(loadSegmenter)
+	text = linkPattern.ReplaceAllString(text, " ")
+	words := segmenter.Cut(text, true)
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if isTerm(word) && !stopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// isTerm 只保留由字母、数字组成的词, 西文单词至少两个字符, 中文允许单字
+func isTerm(word string) bool {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return false
+	}
+	for _, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return len(runes) >= 2 || unicode.Is(unicode.Han, runes[0])
+}
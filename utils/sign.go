@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// Sign 用 HMAC-SHA256 对 payload 签名, 返回可放在 URL 中的令牌
+func Sign(secret, payload string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature(secret, payload)
+}
+
+// VerifySign 校验 Sign 生成的令牌, 返回其中的 payload
+func VerifySign(secret, token string) (string, bool) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, string(payload)))) {
+		return "", false
+	}
+	return string(payload), true
+}
+
+func signature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,10 @@
+package utils
+
+// Excerpt 截取文本的前 n 个字符作为摘要, 超出部分以省略号代替
+func Excerpt(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}